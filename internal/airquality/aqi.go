@@ -0,0 +1,73 @@
+package airquality
+
+// aqiBreakpoint maps a concentration range (µg/m³) onto a US AQI range
+type aqiBreakpoint struct {
+	concentrationLow, concentrationHigh float64
+	aqiLow, aqiHigh                     float64
+}
+
+// aqiBreakpoints are the US EPA breakpoints WAQI reports its per-pollutant
+// indices on
+var aqiBreakpoints = map[string][]aqiBreakpoint{
+	PollutantPM25: {
+		{0, 12.0, 0, 50},
+		{12.1, 35.4, 51, 100},
+		{35.5, 55.4, 101, 150},
+		{55.5, 150.4, 151, 200},
+		{150.5, 250.4, 201, 300},
+		{250.5, 350.4, 301, 400},
+		{350.5, 500.4, 401, 500},
+	},
+	PollutantPM10: {
+		{0, 54, 0, 50},
+		{55, 154, 51, 100},
+		{155, 254, 101, 150},
+		{255, 354, 151, 200},
+		{355, 424, 201, 300},
+		{425, 504, 301, 400},
+		{505, 604, 401, 500},
+	},
+}
+
+// AQIToConcentration converts a US AQI sub-index of the pollutant into a
+// concentration in µg/m³. ok is false for pollutants without breakpoints.
+func AQIToConcentration(pollutant string, aqi float64) (float64, bool) {
+	breakpoints, ok := aqiBreakpoints[pollutant]
+	if !ok {
+		return 0, false
+	}
+	if aqi <= 0 {
+		return 0, true
+	}
+
+	bp := breakpoints[len(breakpoints)-1]
+	for _, candidate := range breakpoints {
+		if aqi <= candidate.aqiHigh {
+			bp = candidate
+			break
+		}
+	}
+	// Values above the scale follow the slope of the last band
+	return bp.concentrationLow + (aqi-bp.aqiLow)*(bp.concentrationHigh-bp.concentrationLow)/(bp.aqiHigh-bp.aqiLow), true
+}
+
+// ConcentrationToAQI converts a concentration in µg/m³ into the pollutant's
+// US AQI sub-index. ok is false for pollutants without breakpoints.
+func ConcentrationToAQI(pollutant string, concentration float64) (float64, bool) {
+	breakpoints, ok := aqiBreakpoints[pollutant]
+	if !ok {
+		return 0, false
+	}
+	if concentration <= 0 {
+		return 0, true
+	}
+
+	bp := breakpoints[len(breakpoints)-1]
+	for _, candidate := range breakpoints {
+		if concentration <= candidate.concentrationHigh {
+			bp = candidate
+			break
+		}
+	}
+	return bp.aqiLow + (concentration-bp.concentrationLow)*(bp.aqiHigh-bp.aqiLow)/(bp.concentrationHigh-bp.concentrationLow), true
+}
@@ -0,0 +1,45 @@
+package airquality
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAQIConversion(t *testing.T) {
+	tests := []struct {
+		name          string
+		pollutant     string
+		aqi           float64
+		concentration float64
+	}{
+		{"pm25 zero", PollutantPM25, 0, 0},
+		{"pm25 good", PollutantPM25, 50, 12},
+		{"pm25 moderate", PollutantPM25, 100, 35.4},
+		{"pm25 unhealthy", PollutantPM25, 175.5, 103},
+		{"pm25 hazardous", PollutantPM25, 500, 500.4},
+		{"pm10 moderate", PollutantPM10, 100, 154},
+		{"pm10 very unhealthy", PollutantPM10, 250.5, 389.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			concentration, ok := AQIToConcentration(tt.pollutant, tt.aqi)
+			if !ok || math.Abs(concentration-tt.concentration) > 0.05 {
+				t.Errorf("AQIToConcentration(%g) = %g, %v, want %g", tt.aqi, concentration, ok, tt.concentration)
+			}
+			aqi, ok := ConcentrationToAQI(tt.pollutant, concentration)
+			if !ok || math.Abs(aqi-tt.aqi) > 1e-9 {
+				t.Errorf("round trip of AQI %g gave %g, %v", tt.aqi, aqi, ok)
+			}
+		})
+	}
+}
+
+func TestAQIConversionWithoutBreakpoints(t *testing.T) {
+	if _, ok := AQIToConcentration("o3", 50); ok {
+		t.Error("AQIToConcentration accepted a pollutant without breakpoints")
+	}
+	if _, ok := ConcentrationToAQI("o3", 50); ok {
+		t.Error("ConcentrationToAQI accepted a pollutant without breakpoints")
+	}
+}
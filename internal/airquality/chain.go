@@ -0,0 +1,281 @@
+package airquality
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/clean-route/go-backend/internal/cache"
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/geo"
+	"github.com/clean-route/go-backend/internal/history"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/observations"
+	"github.com/clean-route/go-backend/internal/workers"
+)
+
+// RegionChain is the provider priority order used inside a region
+type RegionChain struct {
+	ID string `json:"id" yaml:"id"`
+	// Polygon is a ring of [longitude, latitude] points
+	Polygon   [][2]float64 `json:"polygon" yaml:"polygon"`
+	Providers []string     `json:"providers" yaml:"providers"`
+}
+
+// regionsFile is the on-disk format of AIR_QUALITY_REGIONS_FILE
+type regionsFile struct {
+	Regions []RegionChain `json:"regions" yaml:"regions"`
+}
+
+type regionProviders struct {
+	chain     RegionChain
+	providers []Provider
+}
+
+var (
+	mu           sync.RWMutex
+	defaultChain []Provider
+	regions      []regionProviders
+	// lookups caches provider readings by geohash cell and time bucket
+	lookups *cache.Cache
+	// inflight coalesces identical provider lookups
+	inflight workers.Group
+)
+
+// Init builds the providers and fallback chains from configuration:
+// AIR_QUALITY_PROVIDERS is the default chain and AIR_QUALITY_REGIONS_FILE
+// optionally overrides it inside regions
+func Init() error {
+	available := map[string]Provider{
+		models.ProviderWAQI:            NewWAQIProvider(config.AppConfig.WAQIAPIKey),
+		models.ProviderSensorCommunity: NewSensorCommunityProvider(config.AppConfig.MaxStationDistanceKm),
+		models.ProviderObservations:    NewObservationsProvider(config.AppConfig.MaxStationDistanceKm),
+	}
+	if key := config.AppConfig.OpenAQAPIKey; key != "" {
+		available[models.ProviderOpenAQ] = NewOpenAQProvider(key, config.AppConfig.MaxStationDistanceKm)
+	}
+	if key := config.AppConfig.CPCBAPIKey; key != "" {
+		available[models.ProviderCPCB] = NewCPCBProvider(key)
+	}
+
+	resolve := func(names []string) ([]Provider, error) {
+		var providers []Provider
+		for _, name := range names {
+			provider, ok := available[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("air-quality provider %q is unknown or missing its API key", name)
+			}
+			providers = append(providers, provider)
+		}
+		if len(providers) == 0 {
+			return nil, fmt.Errorf("air-quality provider chain is empty")
+		}
+		return providers, nil
+	}
+
+	chain, err := resolve(config.AppConfig.AirQualityProviders)
+	if err != nil {
+		return err
+	}
+
+	var loaded []regionProviders
+	if path := config.AppConfig.AirQualityRegionsFile; path != "" {
+		file, err := loadRegionsFile(path)
+		if err != nil {
+			return err
+		}
+		for _, region := range file.Regions {
+			if len(region.Polygon) < 3 {
+				return fmt.Errorf("air-quality region %q polygon needs at least 3 points", region.ID)
+			}
+			providers, err := resolve(region.Providers)
+			if err != nil {
+				return fmt.Errorf("air-quality region %q: %w", region.ID, err)
+			}
+			loaded = append(loaded, regionProviders{chain: region, providers: providers})
+		}
+	}
+
+	mu.Lock()
+	defaultChain = chain
+	regions = loaded
+	lookups = cache.New("air_quality", config.AppConfig.AirQualityCachePrecision, config.AppConfig.AirQualityCacheTTL, config.AppConfig.CacheMaxEntries)
+	mu.Unlock()
+
+	logger.Info("Air-quality providers configured",
+		"default_chain", config.AppConfig.AirQualityProviders,
+		"regions", len(loaded),
+	)
+	return nil
+}
+
+func loadRegionsFile(path string) (regionsFile, error) {
+	var file regionsFile
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return file, fmt.Errorf("error reading air-quality regions %s: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return file, fmt.Errorf("error parsing air-quality regions %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// chainFor returns the providers to try, in order, at the location
+func chainFor(location [2]float64) []Provider {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, region := range regions {
+		if geo.RingContains(region.chain.Polygon, location) {
+			return region.providers
+		}
+	}
+	return defaultChain
+}
+
+// Nearest returns the reading of the pollutant nearest to the location
+// ([longitude, latitude]) from the first provider in the location's chain
+// whose station is within MAX_STATION_AGE_MINUTES and MAX_STATION_DISTANCE_KM.
+// If no provider has such a station, the first reading found is returned.
+// Nearby community sensor readings are blended in (see blendObservations).
+// Every reading fetched from a provider is recorded in the reading history.
+// failed lists the providers that returned an error.
+func Nearest(location [2]float64, pollutant string) (Reading, []string, error) {
+	var failed []string
+	var fallback *Reading
+	now := time.Now()
+
+	for _, provider := range chainFor(location) {
+		candidate, err := cachedNearest(provider, location, pollutant)
+		if err != nil {
+			logger.Warn("Air-quality provider failed, trying next",
+				"provider", provider.Name(),
+				"error", err.Error(),
+				"location", location,
+			)
+			failed = append(failed, provider.Name())
+			continue
+		}
+
+		if withinLimits(candidate, location, now) {
+			return blendObservations(candidate, location), failed, nil
+		}
+		if fallback == nil {
+			fallback = &candidate
+		}
+	}
+
+	if fallback != nil {
+		return blendObservations(*fallback, location), failed, nil
+	}
+	if config.AppConfig.ObservationBlendWeight > 0 {
+		// Community sensors stand in when no provider answers
+		if nearby := observations.Near(location, pollutant, config.AppConfig.MaxStationDistanceKm, config.AppConfig.MaxStationAge); len(nearby) > 0 {
+			return observationReading(nearby[0]), failed, nil
+		}
+	}
+	return Reading{}, failed, fmt.Errorf("no air-quality provider has a %s reading near %v", pollutant, location)
+}
+
+// cachedNearest asks a provider for its nearest reading, answering from the
+// lookup cache, or the reading history, when a point in the same geohash cell
+// was looked up recently. Identical lookups in flight share one call, and
+// calls to each provider are capped by its worker limit. Community sensors
+// are local and never cached.
+func cachedNearest(provider Provider, location [2]float64, pollutant string) (Reading, error) {
+	mu.RLock()
+	c := lookups
+	mu.RUnlock()
+
+	if provider.Name() == models.ProviderObservations {
+		return provider.Nearest(location, pollutant)
+	}
+
+	key := c.Key(provider.Name()+"|"+pollutant, location, time.Now())
+	var reading Reading
+	if c.Get(key, &reading) {
+		return reading, nil
+	}
+	// Lookups evicted from the cache or made before a restart are answered
+	// from the reading history while they are as fresh as the cache keeps them
+	cell := c.Cell(provider.Name()+"|"+pollutant, location)
+	if record, ok := history.Recent(history.KindAirQuality, cell, c.TTL()); ok && json.Unmarshal(record.Payload, &reading) == nil {
+		c.Set(key, reading)
+		return reading, nil
+	}
+	if key == "" {
+		key = fmt.Sprintf("%s|%s|%f,%f", provider.Name(), pollutant, location[0], location[1])
+	}
+
+	value, err, _ := inflight.Do(key, func() (interface{}, error) {
+		var reading Reading
+		var err error
+		workers.Provider(provider.Name()).Do(func() {
+			reading, err = provider.Nearest(location, pollutant)
+		})
+		if err == nil {
+			c.Set(key, reading)
+			recordReading(reading, location, cell)
+		}
+		return reading, err
+	})
+	if err != nil {
+		return Reading{}, err
+	}
+	return value.(Reading), nil
+}
+
+// recordReading stores a reading fetched from a provider in the reading
+// history, with the reading itself as the payload answering repeats of the
+// lookup in cell
+func recordReading(reading Reading, location [2]float64, cell string) {
+	payload, err := json.Marshal(reading)
+	if err != nil {
+		return
+	}
+	if reading.HasLocation() {
+		location = reading.Location
+	}
+	history.Add(history.Record{
+		Kind:        history.KindAirQuality,
+		Source:      reading.Source,
+		StationID:   reading.StationID,
+		StationName: reading.StationName,
+		Location:    location,
+		Timestamp:   reading.Timestamp,
+		Values:      map[string]float64{reading.Pollutant: reading.Concentration},
+		LookupKey:   cell,
+		Payload:     payload,
+	})
+}
+
+// withinLimits reports whether the reading is recent and close enough to the
+// location. Unknown ages and station locations are accepted.
+func withinLimits(reading Reading, location [2]float64, now time.Time) bool {
+	if limit := config.AppConfig.MaxStationAge; limit > 0 && !reading.Timestamp.IsZero() && now.Sub(reading.Timestamp) > limit {
+		return false
+	}
+	if limit := config.AppConfig.MaxStationDistanceKm; limit > 0 && reading.HasLocation() {
+		km := geo.HaversineDistance(location[1], location[0], reading.Location[1], reading.Location[0]) / 1000
+		if km > limit {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package airquality
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/cache"
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/history"
+)
+
+// countingProvider answers every lookup with the same reading
+type countingProvider struct {
+	calls   int
+	reading Reading
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) Nearest(location [2]float64, pollutant string) (Reading, error) {
+	p.calls++
+	return p.reading, nil
+}
+
+func TestCachedNearestAnswersRepeatsFromHistory(t *testing.T) {
+	config.AppConfig = &config.Config{
+		HistoryDir:       t.TempDir(),
+		HistoryRetention: 24 * time.Hour,
+	}
+	if err := history.Init(); err != nil {
+		t.Fatalf("history.Init: %v", err)
+	}
+	defer history.Close()
+
+	provider := &countingProvider{reading: Reading{
+		Pollutant:     PollutantPM25,
+		Concentration: 42,
+		Source:        "counting",
+		StationID:     "@1",
+		Location:      [2]float64{77.59, 12.97},
+		Timestamp:     time.Now().UTC().Add(-10 * time.Minute),
+		Forecast:      []DailyForecast{{Day: "2024-01-15", Avg: 40, Min: 30, Max: 50}},
+	}}
+	lookups = cache.New("air_quality", 6, 15*time.Minute, 100)
+	if _, err := cachedNearest(provider, [2]float64{77.5901, 12.9701}, PollutantPM25); err != nil {
+		t.Fatalf("cachedNearest: %v", err)
+	}
+
+	// A restart empties the cache and writes the queued history
+	if err := history.Init(); err != nil {
+		t.Fatalf("history.Init: %v", err)
+	}
+	lookups = cache.New("air_quality", 6, 15*time.Minute, 100)
+
+	reading, err := cachedNearest(provider, [2]float64{77.5902, 12.9702}, PollutantPM25)
+	if err != nil {
+		t.Fatalf("cachedNearest: %v", err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times, want once", provider.calls)
+	}
+	if reading.Concentration != 42 || len(reading.Forecast) != 1 {
+		t.Errorf("reading %+v, want the recorded reading with its forecast", reading)
+	}
+}
@@ -0,0 +1,156 @@
+package airquality
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/geo"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+const (
+	// data.gov.in resource of CPCB's real-time air quality index feed
+	cpcbBaseUrl = "https://api.data.gov.in/resource/3b01bcb8-0b14-4abf-b6f2-c1bfd384ba69?"
+	// CPCB stations report hourly; the whole country is fetched at once and
+	// reused for this long
+	cpcbCacheTTL = 15 * time.Minute
+)
+
+// cpcbPollutantIDs are the CPCB pollutant ids of the supported pollutants
+var cpcbPollutantIDs = map[string]string{
+	PollutantPM10: "PM10",
+	PollutantPM25: "PM2.5",
+}
+
+// cpcbTimezone is India Standard Time, in which CPCB reports its readings
+var cpcbTimezone = time.FixedZone("+05:30", 5*3600+30*60)
+
+type cpcbRecord struct {
+	State      string `json:"state"`
+	City       string `json:"city"`
+	Station    string `json:"station"`
+	LastUpdate string `json:"last_update"`
+	Latitude   string `json:"latitude"`
+	Longitude  string `json:"longitude"`
+	// Older versions of the resource name the average avg_value
+	PollutantAvg string `json:"pollutant_avg"`
+	AvgValue     string `json:"avg_value"`
+}
+
+type cpcbResponse struct {
+	Records []cpcbRecord `json:"records"`
+}
+
+type cpcbCache struct {
+	fetchedAt time.Time
+	records   []cpcbRecord
+}
+
+// CPCBProvider reads the nearest station of India's Central Pollution
+// Control Board real-time feed, published on data.gov.in
+type CPCBProvider struct {
+	apiKey string
+
+	mu    sync.Mutex
+	cache map[string]cpcbCache
+}
+
+// NewCPCBProvider creates a provider using a data.gov.in API key
+func NewCPCBProvider(apiKey string) *CPCBProvider {
+	return &CPCBProvider{apiKey: apiKey, cache: map[string]cpcbCache{}}
+}
+
+// Name returns the provider name
+func (p *CPCBProvider) Name() string {
+	return models.ProviderCPCB
+}
+
+// Nearest returns the latest reading of the nearest CPCB station
+func (p *CPCBProvider) Nearest(location [2]float64, pollutant string) (Reading, error) {
+	pollutantID, ok := cpcbPollutantIDs[pollutant]
+	if !ok {
+		return Reading{}, ErrUnsupportedPollutant
+	}
+
+	records, err := p.records(pollutantID)
+	if err != nil {
+		logger.Error("Failed to fetch CPCB stations",
+			"error", err.Error(),
+			"location", location,
+		)
+		return Reading{}, err
+	}
+
+	var reading Reading
+	nearest := math.Inf(1)
+	for _, record := range records {
+		lat, latErr := strconv.ParseFloat(record.Latitude, 64)
+		lon, lonErr := strconv.ParseFloat(record.Longitude, 64)
+		value := record.PollutantAvg
+		if value == "" {
+			value = record.AvgValue
+		}
+		// Stations without a current value report "NA"
+		concentration, valueErr := strconv.ParseFloat(value, 64)
+		if latErr != nil || lonErr != nil || valueErr != nil {
+			continue
+		}
+
+		distance := geo.HaversineDistance(location[1], location[0], lat, lon)
+		if distance >= nearest {
+			continue
+		}
+		nearest = distance
+		reading = Reading{
+			Pollutant:     pollutant,
+			Concentration: concentration,
+			Unit:          UnitMicrogramsPerCubicMeter,
+			Location:      [2]float64{lon, lat},
+			Source:        p.Name(),
+			StationID:     record.Station,
+			StationName:   record.Station,
+		}
+		if readAt, err := time.ParseInLocation("02-01-2006 15:04:05", record.LastUpdate, cpcbTimezone); err == nil {
+			reading.Timestamp = readAt
+		}
+	}
+	if reading.Source == "" {
+		return Reading{}, ErrNoStation
+	}
+	return reading, nil
+}
+
+// records returns every station's record for the pollutant, refreshing the
+// cached copy when it is older than cpcbCacheTTL
+func (p *CPCBProvider) records(pollutantID string) ([]cpcbRecord, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.cache[pollutantID]; ok && time.Since(cached.fetchedAt) < cpcbCacheTTL {
+		return cached.records, nil
+	}
+
+	params := url.Values{}
+	params.Add("api-key", p.apiKey)
+	params.Add("format", "json")
+	params.Add("limit", "4000")
+	params.Add("filters[pollutant_id]", pollutantID)
+
+	req, err := http.NewRequest(http.MethodGet, cpcbBaseUrl+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response cpcbResponse
+	if err := getJSON(p.Name(), req, &response); err != nil {
+		return nil, err
+	}
+
+	p.cache[pollutantID] = cpcbCache{fetchedAt: time.Now(), records: response.Records}
+	return response.Records, nil
+}
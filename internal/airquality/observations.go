@@ -0,0 +1,95 @@
+package airquality
+
+import (
+	"math"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/geo"
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/observations"
+)
+
+// Blended readings treat sensors closer than this as this far away (km), so
+// a sensor next to the route point does not take all the weight
+const blend_min_distance_km = 0.5
+
+// ObservationsProvider reads the community sensor readings ingested through
+// the observations API
+type ObservationsProvider struct {
+	radiusKm float64
+}
+
+// NewObservationsProvider creates a provider using sensors within radiusKm
+func NewObservationsProvider(radiusKm float64) *ObservationsProvider {
+	return &ObservationsProvider{radiusKm: radiusKm}
+}
+
+// Name returns the provider name
+func (p *ObservationsProvider) Name() string {
+	return models.ProviderObservations
+}
+
+// Nearest returns the latest calibrated reading of the nearest sensor
+func (p *ObservationsProvider) Nearest(location [2]float64, pollutant string) (Reading, error) {
+	nearby := observations.Near(location, pollutant, p.radiusKm, config.AppConfig.MaxStationAge)
+	if len(nearby) == 0 {
+		return Reading{}, ErrNoStation
+	}
+	return observationReading(nearby[0]), nil
+}
+
+func observationReading(sensor observations.Nearby) Reading {
+	return Reading{
+		Pollutant:     sensor.Pollutant,
+		Concentration: sensor.Calibrated,
+		Unit:          UnitMicrogramsPerCubicMeter,
+		Timestamp:     sensor.Timestamp,
+		Location:      sensor.Location,
+		Source:        models.ProviderObservations,
+		StationID:     sensor.SensorID,
+		StationName:   sensor.Sensor.Name,
+	}
+}
+
+// blendObservations combines a station reading with the community sensors
+// near the location by inverse-distance weighting. Sensors are weighted by
+// OBSERVATION_BLEND_WEIGHT relative to the station. The reading is returned
+// unchanged when there are no sensors nearby.
+func blendObservations(reading Reading, location [2]float64) Reading {
+	weight := config.AppConfig.ObservationBlendWeight
+	if weight <= 0 || reading.Source == models.ProviderObservations {
+		return reading
+	}
+
+	nearby := observations.Near(location, reading.Pollutant, config.AppConfig.MaxStationDistanceKm, config.AppConfig.MaxStationAge)
+	if len(nearby) == 0 {
+		return reading
+	}
+
+	// A station of unknown location counts as being at the limit distance
+	stationKm := config.AppConfig.MaxStationDistanceKm
+	if reading.HasLocation() {
+		stationKm = geo.HaversineDistance(location[1], location[0], reading.Location[1], reading.Location[0]) / 1000
+	}
+	stationWeight := inverseDistanceSquared(stationKm)
+
+	total := stationWeight * reading.Concentration
+	weights := stationWeight
+	for _, sensor := range nearby {
+		sensorWeight := weight * inverseDistanceSquared(sensor.DistanceKm)
+		total += sensorWeight * sensor.Calibrated
+		weights += sensorWeight
+	}
+
+	blended := reading
+	blended.Concentration = total / weights
+	blended.Source = models.ProviderBlended
+	blended.BlendedSources = []string{reading.Source, models.ProviderObservations}
+	blended.Sensors = len(nearby)
+	return blended
+}
+
+func inverseDistanceSquared(km float64) float64 {
+	km = math.Max(km, blend_min_distance_km)
+	return 1 / (km * km)
+}
@@ -0,0 +1,158 @@
+package airquality
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/geo"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+const (
+	openAQBaseUrl = "https://api.openaq.org/v3"
+	// OpenAQ limits location searches to a 25 km radius
+	openAQMaxRadius = 25000
+)
+
+// openAQParameterIDs are the OpenAQ parameter ids of the supported pollutants
+var openAQParameterIDs = map[string]int{
+	PollutantPM10: 1,
+	PollutantPM25: 2,
+}
+
+type openAQCoordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type openAQLocations struct {
+	Results []struct {
+		ID          int               `json:"id"`
+		Name        string            `json:"name"`
+		Coordinates openAQCoordinates `json:"coordinates"`
+		Sensors     []struct {
+			ID        int `json:"id"`
+			Parameter struct {
+				Name  string `json:"name"`
+				Units string `json:"units"`
+			} `json:"parameter"`
+		} `json:"sensors"`
+	} `json:"results"`
+}
+
+type openAQSensors struct {
+	Results []struct {
+		Latest *struct {
+			Datetime struct {
+				UTC   string `json:"utc"`
+				Local string `json:"local"`
+			} `json:"datetime"`
+			Value float64 `json:"value"`
+		} `json:"latest"`
+	} `json:"results"`
+}
+
+// OpenAQProvider reads the latest measurement of the nearest OpenAQ v3
+// location with a sensor for the pollutant
+type OpenAQProvider struct {
+	apiKey string
+	radius float64 // meters
+}
+
+// NewOpenAQProvider creates a provider using the OpenAQ API key, searching
+// for locations within radiusKm (at most 25 km)
+func NewOpenAQProvider(apiKey string, radiusKm float64) *OpenAQProvider {
+	radius := radiusKm * 1000
+	if radius <= 0 || radius > openAQMaxRadius {
+		radius = openAQMaxRadius
+	}
+	return &OpenAQProvider{apiKey: apiKey, radius: radius}
+}
+
+// Name returns the provider name
+func (p *OpenAQProvider) Name() string {
+	return models.ProviderOpenAQ
+}
+
+// Nearest returns the latest reading of the nearest OpenAQ location
+func (p *OpenAQProvider) Nearest(location [2]float64, pollutant string) (Reading, error) {
+	parameterID, ok := openAQParameterIDs[pollutant]
+	if !ok {
+		return Reading{}, ErrUnsupportedPollutant
+	}
+
+	params := url.Values{}
+	params.Add("coordinates", fmt.Sprintf("%f,%f", location[1], location[0]))
+	params.Add("radius", strconv.Itoa(int(p.radius)))
+	params.Add("parameters_id", strconv.Itoa(parameterID))
+	params.Add("limit", "25")
+
+	var locations openAQLocations
+	if err := p.get(openAQBaseUrl+"/locations?"+params.Encode(), &locations); err != nil {
+		logger.Error("Failed to fetch OpenAQ locations",
+			"error", err.Error(),
+			"location", location,
+		)
+		return Reading{}, err
+	}
+
+	// Locations are not ordered by distance
+	reading := Reading{Pollutant: pollutant, Unit: UnitMicrogramsPerCubicMeter, Source: p.Name()}
+	sensorID := 0
+	nearest := math.Inf(1)
+	for _, candidate := range locations.Results {
+		for _, sensor := range candidate.Sensors {
+			if sensor.Parameter.Name != pollutant || sensor.Parameter.Units != UnitMicrogramsPerCubicMeter {
+				continue
+			}
+			distance := geo.HaversineDistance(location[1], location[0], candidate.Coordinates.Latitude, candidate.Coordinates.Longitude)
+			if distance < nearest {
+				nearest = distance
+				sensorID = sensor.ID
+				reading.StationID = strconv.Itoa(candidate.ID)
+				reading.StationName = candidate.Name
+				reading.Location = [2]float64{candidate.Coordinates.Longitude, candidate.Coordinates.Latitude}
+			}
+		}
+	}
+	if sensorID == 0 {
+		return Reading{}, ErrNoStation
+	}
+
+	var sensors openAQSensors
+	if err := p.get(fmt.Sprintf("%s/sensors/%d", openAQBaseUrl, sensorID), &sensors); err != nil {
+		logger.Error("Failed to fetch OpenAQ sensor",
+			"error", err.Error(),
+			"sensor_id", sensorID,
+		)
+		return Reading{}, err
+	}
+	if len(sensors.Results) == 0 || sensors.Results[0].Latest == nil {
+		return Reading{}, ErrNoStation
+	}
+
+	latest := sensors.Results[0].Latest
+	reading.Concentration = latest.Value
+	// The local time keeps the station's UTC offset
+	for _, value := range []string{latest.Datetime.Local, latest.Datetime.UTC} {
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			reading.Timestamp = parsed
+			break
+		}
+	}
+	return reading, nil
+}
+
+func (p *OpenAQProvider) get(requestUrl string, target interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", p.apiKey)
+	return getJSON(p.Name(), req, target)
+}
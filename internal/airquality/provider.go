@@ -0,0 +1,42 @@
+package airquality
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/clean-route/go-backend/internal/outbound"
+)
+
+// Provider is a source of air-quality station readings
+type Provider interface {
+	// Name identifies the provider in configuration and data quality reports
+	Name() string
+	// Nearest returns the latest reading of the pollutant at the station
+	// nearest to the location ([longitude, latitude])
+	Nearest(location [2]float64, pollutant string) (Reading, error)
+}
+
+// getJSON fetches a URL through the provider's outbound client and decodes
+// its JSON body into target
+func getJSON(provider string, req *http.Request, target interface{}) error {
+	resp, err := outbound.For(provider).Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status code: %d", req.URL.Host, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading %s response: %w", req.URL.Host, err)
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("error unmarshaling %s JSON: %w", req.URL.Host, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package airquality
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	PollutantPM25 = "pm25"
+	PollutantPM10 = "pm10"
+
+	// UnitMicrogramsPerCubicMeter is the unit every reading is normalised to
+	UnitMicrogramsPerCubicMeter = "µg/m³"
+)
+
+var (
+	// ErrUnsupportedPollutant is returned by providers that do not report
+	// the requested pollutant
+	ErrUnsupportedPollutant = errors.New("pollutant not supported by provider")
+	// ErrNoStation is returned when no station near the location reports
+	// the pollutant
+	ErrNoStation = errors.New("no station with a reading near location")
+)
+
+// Reading is the latest concentration of a pollutant measured at a station,
+// normalised across providers
+type Reading struct {
+	Pollutant     string  `json:"pollutant"`
+	Concentration float64 `json:"concentration"`
+	Unit          string  `json:"unit"`
+	// Timestamp is when the reading was taken, in the station's time zone
+	// where the provider reports it. It is zero when unknown.
+	Timestamp time.Time `json:"timestamp"`
+	// Location is the station's [longitude, latitude], zero when unknown
+	Location    [2]float64 `json:"location"`
+	Source      string     `json:"source"`
+	StationID   string     `json:"station_id,omitempty"`
+	StationName string     `json:"station_name,omitempty"`
+
+	// Forecast holds the provider's daily forecasts of the pollutant, if any
+	Forecast []DailyForecast `json:"forecast,omitempty"`
+
+	// BlendedSources lists the providers combined into a blended reading
+	BlendedSources []string `json:"blended_sources,omitempty"`
+	// Sensors is how many community sensors were blended in
+	Sensors int `json:"sensors,omitempty"`
+}
+
+// HasLocation reports whether the provider reported where the station is
+func (r Reading) HasLocation() bool {
+	return r.Location != [2]float64{}
+}
+
+// DailyForecast is a forecast of a pollutant's concentration over one day.
+// Day is formatted 2006-01-02 in the station's time zone.
+type DailyForecast struct {
+	Day string  `json:"day"`
+	Avg float64 `json:"avg"`
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
@@ -0,0 +1,133 @@
+package airquality
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/geo"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+const (
+	sensorCommunityBaseUrl = "https://data.sensor.community/airrohr/v1/filter/area="
+	// Area queries return every recent measurement, so keep them small
+	sensorCommunityMaxRadiusKm = 10
+)
+
+// sensorCommunityValueTypes are the Sensor.Community value types of the
+// supported pollutants
+var sensorCommunityValueTypes = map[string]string{
+	PollutantPM10: "P1",
+	PollutantPM25: "P2",
+}
+
+type sensorCommunityMeasurement struct {
+	Timestamp string `json:"timestamp"`
+	Location  struct {
+		ID        int    `json:"id"`
+		Latitude  string `json:"latitude"`
+		Longitude string `json:"longitude"`
+	} `json:"location"`
+	Sensor struct {
+		ID         int `json:"id"`
+		SensorType struct {
+			Name string `json:"name"`
+		} `json:"sensor_type"`
+	} `json:"sensor"`
+	SensorDataValues []struct {
+		ValueType string `json:"value_type"`
+		Value     string `json:"value"`
+	} `json:"sensordatavalues"`
+}
+
+// SensorCommunityProvider reads the latest measurement of the nearest
+// Sensor.Community (formerly luftdaten.info) low-cost particulate sensor
+type SensorCommunityProvider struct {
+	radiusKm float64
+}
+
+// NewSensorCommunityProvider creates a provider searching for sensors within
+// radiusKm (at most 10 km)
+func NewSensorCommunityProvider(radiusKm float64) *SensorCommunityProvider {
+	if radiusKm <= 0 || radiusKm > sensorCommunityMaxRadiusKm {
+		radiusKm = sensorCommunityMaxRadiusKm
+	}
+	return &SensorCommunityProvider{radiusKm: radiusKm}
+}
+
+// Name returns the provider name
+func (p *SensorCommunityProvider) Name() string {
+	return models.ProviderSensorCommunity
+}
+
+// Nearest returns the latest reading of the nearest sensor
+func (p *SensorCommunityProvider) Nearest(location [2]float64, pollutant string) (Reading, error) {
+	valueType, ok := sensorCommunityValueTypes[pollutant]
+	if !ok {
+		return Reading{}, ErrUnsupportedPollutant
+	}
+
+	requestUrl := sensorCommunityBaseUrl + fmt.Sprintf("%f,%f,%g", location[1], location[0], p.radiusKm)
+	req, err := http.NewRequest(http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return Reading{}, err
+	}
+
+	var measurements []sensorCommunityMeasurement
+	if err := getJSON(p.Name(), req, &measurements); err != nil {
+		logger.Error("Failed to fetch Sensor.Community measurements",
+			"error", err.Error(),
+			"location", location,
+		)
+		return Reading{}, err
+	}
+
+	// The feed holds several recent measurements per sensor; keep the latest
+	// of the nearest sensor
+	var reading Reading
+	nearest := math.Inf(1)
+	for _, measurement := range measurements {
+		lat, latErr := strconv.ParseFloat(measurement.Location.Latitude, 64)
+		lon, lonErr := strconv.ParseFloat(measurement.Location.Longitude, 64)
+		// Timestamps are UTC
+		readAt, timeErr := time.Parse("2006-01-02 15:04:05", measurement.Timestamp)
+		if latErr != nil || lonErr != nil || timeErr != nil {
+			continue
+		}
+
+		for _, value := range measurement.SensorDataValues {
+			if value.ValueType != valueType {
+				continue
+			}
+			concentration, err := strconv.ParseFloat(value.Value, 64)
+			if err != nil {
+				continue
+			}
+
+			distance := geo.HaversineDistance(location[1], location[0], lat, lon)
+			stationID := strconv.Itoa(measurement.Sensor.ID)
+			sameSensor := stationID == reading.StationID
+			if distance < nearest && !sameSensor || sameSensor && readAt.After(reading.Timestamp) {
+				nearest = distance
+				reading = Reading{
+					Pollutant:     pollutant,
+					Concentration: concentration,
+					Unit:          UnitMicrogramsPerCubicMeter,
+					Timestamp:     readAt,
+					Location:      [2]float64{lon, lat},
+					Source:        p.Name(),
+					StationID:     stationID,
+					StationName:   measurement.Sensor.SensorType.Name,
+				}
+			}
+		}
+	}
+	if reading.Source == "" {
+		return Reading{}, ErrNoStation
+	}
+	return reading, nil
+}
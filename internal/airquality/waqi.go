@@ -0,0 +1,128 @@
+package airquality
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+	waqi "github.com/clean-route/go-backend/internal/models/waqi"
+)
+
+// WAQIProvider reads the World Air Quality Index feed of the station nearest
+// to a location. WAQI reports US AQI sub-indices, which are converted back to
+// concentrations.
+type WAQIProvider struct {
+	token string
+}
+
+// NewWAQIProvider creates a provider using the WAQI API token
+func NewWAQIProvider(token string) *WAQIProvider {
+	return &WAQIProvider{token: token}
+}
+
+// Name returns the provider name
+func (p *WAQIProvider) Name() string {
+	return models.ProviderWAQI
+}
+
+// Nearest returns the reading and daily forecast of the nearest WAQI station
+func (p *WAQIProvider) Nearest(location [2]float64, pollutant string) (Reading, error) {
+	if _, ok := aqiBreakpoints[pollutant]; !ok {
+		return Reading{}, ErrUnsupportedPollutant
+	}
+
+	baseUrl := "https://api.waqi.info/feed/geo:" + fmt.Sprintf("%f;%f/?", location[1], location[0])
+	params := url.Values{}
+	params.Add("token", p.token)
+
+	req, err := http.NewRequest(http.MethodGet, baseUrl+params.Encode(), nil)
+	if err != nil {
+		return Reading{}, err
+	}
+
+	var response waqi.APIResponse
+	if err := getJSON(p.Name(), req, &response); err != nil {
+		logger.Error("Failed to fetch WAQI station",
+			"error", err.Error(),
+			"location", location,
+		)
+		return Reading{}, err
+	}
+	if response.Status != "ok" {
+		logger.Error("WAQI API returned non-OK status",
+			"status", response.Status,
+			"location", location,
+		)
+		return Reading{}, errors.New("WAQI response is not 'OK' but: " + response.Status)
+	}
+
+	station := response.Data
+	index, ok := station.IAQI[pollutant]
+	if !ok {
+		return Reading{}, ErrNoStation
+	}
+	concentration, _ := AQIToConcentration(pollutant, index.V)
+
+	reading := Reading{
+		Pollutant:     pollutant,
+		Concentration: concentration,
+		Unit:          UnitMicrogramsPerCubicMeter,
+		Source:        p.Name(),
+		StationID:     strconv.Itoa(station.IDX),
+		StationName:   station.City.Name,
+	}
+	if readAt, ok := waqiReadingTime(station.Time); ok {
+		reading.Timestamp = readAt
+	}
+	// WAQI reports the station as [latitude, longitude]
+	if len(station.City.Geo) == 2 {
+		reading.Location = [2]float64{station.City.Geo[1], station.City.Geo[0]}
+	}
+
+	var daily []waqi.DailyData
+	switch pollutant {
+	case PollutantPM25:
+		daily = station.Forecast.Daily.PM25
+	case PollutantPM10:
+		daily = station.Forecast.Daily.PM10
+	}
+	for _, day := range daily {
+		avg, _ := AQIToConcentration(pollutant, float64(day.Avg))
+		low, _ := AQIToConcentration(pollutant, float64(day.Min))
+		high, _ := AQIToConcentration(pollutant, float64(day.Max))
+		reading.Forecast = append(reading.Forecast, DailyForecast{Day: day.Day, Avg: avg, Min: low, Max: high})
+	}
+
+	return reading, nil
+}
+
+// waqiReadingTime returns when a WAQI reading was taken. WAQI reports the
+// station's local time in "s" with its UTC offset in "tz"; "iso" carries both.
+func waqiReadingTime(t waqi.Time) (time.Time, bool) {
+	if t.Iso != "" {
+		if parsed, err := time.Parse(time.RFC3339, t.Iso); err == nil {
+			return parsed, true
+		}
+	}
+	if t.S != "" {
+		if parsed, err := time.ParseInLocation("2006-01-02 15:04:05", t.S, waqiLocation(t.Tz)); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// waqiLocation parses WAQI's "+05:30" style offsets
+func waqiLocation(tz string) *time.Location {
+	offset, err := time.Parse("-07:00", tz)
+	if err != nil {
+		return time.UTC
+	}
+	_, seconds := offset.Zone()
+	return time.FixedZone(tz, seconds)
+}
@@ -0,0 +1,64 @@
+// Package background loads gridded pollutant concentration fields, such as
+// CAMS or WRF-Chem model output, and answers point queries with bilinear
+// interpolation in space and linear interpolation in time. The fields fill in
+// where station coverage is sparse.
+package background
+
+import (
+	"math"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/raster"
+)
+
+// Grid is one time step of a regular latitude/longitude concentration field
+// in µg/m³. Cell (0, 0) is centred on (Lon0, Lat0); steps may be negative.
+type Grid struct {
+	Pollutant string
+	// Time is the valid time of the field; zero for a field without time
+	Time    time.Time
+	Lon0    float64
+	LonStep float64
+	Lat0    float64
+	LatStep float64
+	Width   int
+	Height  int
+	// Values holds the cells row by row (latitude-major); NaN marks cells
+	// without data
+	Values []float32
+	Source string
+}
+
+// global reports whether the grid spans all longitudes, in which case
+// interpolation wraps across the antimeridian
+func (g *Grid) global() bool {
+	return math.Abs(float64(g.Width)*g.LonStep) >= 359.99
+}
+
+// At returns the interpolated concentration at a point, or false if the point
+// is outside the grid or the surrounding cells have no data
+func (g *Grid) At(lon, lat float64) (float64, bool) {
+	if g.Width == 0 || g.Height == 0 || g.LonStep == 0 || g.LatStep == 0 {
+		return 0, false
+	}
+
+	col := (lon - g.Lon0) / g.LonStep
+	row := (lat - g.Lat0) / g.LatStep
+	width := g.Width
+	if g.global() {
+		col = math.Mod(col, float64(g.Width))
+		if col < 0 {
+			col += float64(g.Width)
+		}
+		// one extra column joins the last cell to the first
+		width++
+	}
+
+	return raster.Bilinear(col, row, width, g.Height, func(x, y int) (float64, bool) {
+		value := float64(g.Values[y*g.Width+x%g.Width])
+		if math.IsNaN(value) {
+			return 0, false
+		}
+		return value, true
+	})
+}
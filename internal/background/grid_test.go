@@ -0,0 +1,56 @@
+package background
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGridAt(t *testing.T) {
+	nan := float32(math.NaN())
+	// Cell centres at longitudes -135, -45, 45 and 135 and latitudes -45, 0
+	// and 45
+	global := &Grid{
+		Lon0: -135, LonStep: 90, Lat0: -45, LatStep: 45, Width: 4, Height: 3,
+		Values: []float32{
+			10, 20, 30, 40,
+			50, 60, 70, 80,
+			90, nan, 110, 120,
+		},
+	}
+	// A 1° regional grid over south India, north row first
+	regional := &Grid{
+		Lon0: 77, LonStep: 1, Lat0: 13, LatStep: -1, Width: 2, Height: 2,
+		Values: []float32{10, 20, 30, 40},
+	}
+
+	tests := []struct {
+		name     string
+		grid     *Grid
+		lon, lat float64
+		want     float64
+		wantOK   bool
+	}{
+		{"cell centre", global, -45, 0, 60, true},
+		{"between cells", global, 0, 0, 65, true},
+		{"antimeridian from the east", global, 180, 0, 65, true},
+		{"antimeridian from the west", global, -180, 0, 65, true},
+		{"wrapped past 180", global, 270, 0, 55, true},
+		{"west of the first centre", global, -157.5, -45, 17.5, true},
+		{"around a cell without data", global, 0, 45, 110, true},
+		{"cell without data", global, -45, 45, 0, false},
+		{"beyond the last row", global, 0, 60, 0, false},
+		{"regional interpolation", regional, 77.5, 12.5, 25, true},
+		{"regional east edge", regional, 78, 13, 20, true},
+		{"outside a regional grid", regional, 79, 12.5, 0, false},
+		{"empty grid", &Grid{}, 0, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.grid.At(tt.lon, tt.lat)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("At(%g, %g) = %g, %v, want %g, %v", tt.lon, tt.lat, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package background
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+)
+
+// step holds the grids valid at one time, finest resolution first so that
+// nested regional domains take precedence over global fields
+type step struct {
+	time  time.Time
+	grids []*Grid
+}
+
+func (s step) at(lon, lat float64) (float64, bool) {
+	for _, grid := range s.grids {
+		if value, ok := grid.At(lon, lat); ok {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// indexedFile is a loaded grid file, reloaded when its size or modification
+// time changes
+type indexedFile struct {
+	modTime time.Time
+	size    int64
+	grids   []*Grid
+}
+
+var (
+	mu sync.RWMutex
+
+	files = map[string]indexedFile{}
+	// steps holds the timed grids of each pollutant sorted by time
+	steps = map[string][]step{}
+	// static holds the grids of each pollutant without a valid time, used as
+	// a climatology when no timed grid is close enough
+	static = map[string]step{}
+
+	rescanOnce sync.Once
+)
+
+// Init loads the grids in BACKGROUND_GRID_DIR and rescans the directory every
+// BACKGROUND_RESCAN_MINUTES so that new model runs are picked up. It fails
+// when any grid file in the directory cannot be read, such as NetCDF-4 or
+// curvilinear files; later rescans log those files and index the rest.
+func Init() error {
+	dir := config.AppConfig.BackgroundGridDir
+	if dir == "" {
+		logger.Info("No background concentration grids configured")
+		return nil
+	}
+
+	if err := scan(dir); err != nil {
+		return err
+	}
+
+	if interval := config.AppConfig.BackgroundRescanInterval; interval > 0 {
+		rescanOnce.Do(func() {
+			go func() {
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for range ticker.C {
+					if err := scan(dir); err != nil {
+						logger.Error("Failed to rescan background grids",
+							"directory", dir,
+							"error", err.Error(),
+						)
+					}
+				}
+			}()
+		})
+	}
+	return nil
+}
+
+// scan loads new and changed grid files and rebuilds the index
+func scan(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error reading background grid directory %s: %w", dir, err)
+	}
+
+	mu.RLock()
+	previous := files
+	mu.RUnlock()
+
+	current := map[string]indexedFile{}
+	var changed bool
+	var unreadable []error
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if file, ok := previous[path]; ok && file.modTime.Equal(info.ModTime()) && file.size == info.Size() {
+			current[path] = file
+			continue
+		}
+
+		grids, err := loadFile(path)
+		if err != nil {
+			unreadable = append(unreadable, fmt.Errorf("%s: %w", entry.Name(), err))
+		}
+		if grids == nil && err == nil {
+			continue
+		}
+		current[path] = indexedFile{modTime: info.ModTime(), size: info.Size(), grids: grids}
+		changed = true
+	}
+	if !changed && len(current) == len(previous) {
+		return nil
+	}
+
+	timed := map[string]map[time.Time][]*Grid{}
+	untimed := map[string]step{}
+	var count int
+	for _, file := range current {
+		for _, grid := range file.grids {
+			count++
+			if grid.Time.IsZero() {
+				s := untimed[grid.Pollutant]
+				s.grids = append(s.grids, grid)
+				untimed[grid.Pollutant] = s
+				continue
+			}
+			if timed[grid.Pollutant] == nil {
+				timed[grid.Pollutant] = map[time.Time][]*Grid{}
+			}
+			timed[grid.Pollutant][grid.Time] = append(timed[grid.Pollutant][grid.Time], grid)
+		}
+	}
+
+	index := map[string][]step{}
+	for pollutant, byTime := range timed {
+		for at, grids := range byTime {
+			sortFinestFirst(grids)
+			index[pollutant] = append(index[pollutant], step{time: at, grids: grids})
+		}
+		sort.Slice(index[pollutant], func(i, j int) bool {
+			return index[pollutant][i].time.Before(index[pollutant][j].time)
+		})
+	}
+	for _, s := range untimed {
+		sortFinestFirst(s.grids)
+	}
+
+	mu.Lock()
+	files = current
+	steps = index
+	static = untimed
+	mu.Unlock()
+
+	logger.Info("Background concentration grids indexed",
+		"directory", dir,
+		"files", len(current),
+		"grids", count,
+	)
+	return unreadableError(dir, unreadable)
+}
+
+// unreadableError reports the files of a scan that could not be loaded. The
+// readable files are indexed regardless; unreadable ones are retried once
+// they change.
+func unreadableError(dir string, unreadable []error) error {
+	if len(unreadable) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d background grid files in %s could not be read: %w", len(unreadable), dir, errors.Join(unreadable...))
+}
+
+func sortFinestFirst(grids []*Grid) {
+	sort.SliceStable(grids, func(i, j int) bool {
+		return cellArea(grids[i]) < cellArea(grids[j])
+	})
+}
+
+func cellArea(grid *Grid) float64 {
+	area := grid.LonStep * grid.LatStep
+	if area < 0 {
+		return -area
+	}
+	return area
+}
+
+// Concentration returns the background concentration of a pollutant in µg/m³
+// at a point and time. Grids either side of the time are interpolated
+// linearly when both lie within BACKGROUND_MAX_TIME_GAP_HOURS; otherwise the
+// closest one within the gap is used, then any grid without a valid time.
+func Concentration(lon, lat float64, at time.Time, pollutant string) (float64, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	frames := steps[pollutant]
+	gap := config.AppConfig.BackgroundMaxTimeGap
+	i := sort.Search(len(frames), func(i int) bool { return !frames[i].time.Before(at) })
+
+	var before, after float64
+	var beforeOK, afterOK bool
+	if i > 0 && at.Sub(frames[i-1].time) <= gap {
+		before, beforeOK = frames[i-1].at(lon, lat)
+	}
+	if i < len(frames) && frames[i].time.Sub(at) <= gap {
+		after, afterOK = frames[i].at(lon, lat)
+	}
+
+	switch {
+	case beforeOK && afterOK:
+		weight := float64(at.Sub(frames[i-1].time)) / float64(frames[i].time.Sub(frames[i-1].time))
+		return before + weight*(after-before), true
+	case beforeOK:
+		return before, true
+	case afterOK:
+		return after, true
+	}
+
+	return static[pollutant].at(lon, lat)
+}
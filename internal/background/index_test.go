@@ -0,0 +1,34 @@
+package background
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+)
+
+func TestInitFailsOnUnreadableFile(t *testing.T) {
+	dir := t.TempDir()
+	csv := "lat,lon,pm25\n12,77,10\n12,78,20\n13,77,30\n13,78,40\n"
+	if err := os.WriteFile(filepath.Join(dir, "pm25_clim.csv"), []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// NetCDF-4 files start with the HDF5 signature
+	if err := os.WriteFile(filepath.Join(dir, "cams.nc"), []byte("\x89HDF\r\n\x1a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config.AppConfig = &config.Config{BackgroundGridDir: dir, BackgroundMaxTimeGap: time.Hour}
+	err := Init()
+	if err == nil || !strings.Contains(err.Error(), "cams.nc") {
+		t.Fatalf("Init error %v, want one naming cams.nc", err)
+	}
+
+	// The readable file is indexed regardless
+	if value, ok := Concentration(77.5, 12.5, time.Now(), "pm25"); !ok || value != 25 {
+		t.Errorf("Concentration = %g, %v, want 25 from the CSV grid", value, ok)
+	}
+}
@@ -0,0 +1,321 @@
+package background
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/raster"
+)
+
+const (
+	pollutantPM25 = "pm25"
+	pollutantPM10 = "pm10"
+)
+
+// variableNames are the concentration variables recognised in NetCDF files:
+// CAMS, generic names and WRF-Chem
+var variableNames = map[string][]string{
+	pollutantPM25: {"pm2p5", "pm2p5_conc", "PM2_5_DRY", "pm25", "PM25"},
+	pollutantPM10: {"pm10", "pm10_conc", "PM10"},
+}
+
+// pollutantAliases maps file name tokens and CSV columns to pollutants
+var pollutantAliases = map[string]string{
+	"pm25":  pollutantPM25,
+	"pm2p5": pollutantPM25,
+	"pm2_5": pollutantPM25,
+	"pm10":  pollutantPM10,
+}
+
+// filePollutantPattern matches a pollutant token in a file name
+var filePollutantPattern = regexp.MustCompile(`(?:^|[^a-z0-9])(pm2p5|pm2_5|pm25|pm10)(?:[^a-z0-9]|$)`)
+
+// fileTimePattern matches a time such as 20240115, 20240115T06 or
+// 20240115T0600 in a file name
+var fileTimePattern = regexp.MustCompile(`(\d{8})(?:[T_-]?(\d{2})(\d{2})?)?`)
+
+// parseFileName extracts the pollutant and valid time from a file name such
+// as pm25_20240115T06.tif. Either may be empty.
+func parseFileName(path string) (string, time.Time) {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+
+	var pollutant string
+	if match := filePollutantPattern.FindStringSubmatch(name); match != nil {
+		pollutant = pollutantAliases[match[1]]
+	}
+
+	var at time.Time
+	if match := fileTimePattern.FindStringSubmatch(name); match != nil {
+		if day, err := time.Parse("20060102", match[1]); err == nil {
+			hour, _ := strconv.Atoi(match[2])
+			minute, _ := strconv.Atoi(match[3])
+			at = day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		}
+	}
+	return pollutant, at
+}
+
+// loadFile reads the grids in a NetCDF, GeoTIFF or CSV file
+func loadFile(path string) ([]*Grid, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".nc", ".nc4", ".cdf":
+		return loadNetCDF(path)
+	case ".tif", ".tiff":
+		return loadGeoTIFF(path)
+	case ".csv":
+		return loadCSV(path)
+	}
+	return nil, nil
+}
+
+// loadGeoTIFF reads a single-band GeoTIFF whose pollutant and time are
+// given by the file name
+func loadGeoTIFF(path string) ([]*Grid, error) {
+	pollutant, at := parseFileName(path)
+	if pollutant == "" {
+		return nil, fmt.Errorf("no pollutant in file name; expected e.g. pm25_20240115T06.tif")
+	}
+
+	file, err := raster.OpenGeoTIFF(path)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := file.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	grid := &Grid{
+		Pollutant: pollutant,
+		Time:      at,
+		Lon0:      file.OriginLon + file.ScaleLon/2,
+		LonStep:   file.ScaleLon,
+		Lat0:      file.OriginLat - file.ScaleLat/2,
+		LatStep:   -file.ScaleLat,
+		Width:     file.Width,
+		Height:    file.Height,
+		Values:    decoded.Pixels,
+		Source:    path,
+	}
+	if file.NoData != nil {
+		noData := float32(*file.NoData)
+		for i, value := range grid.Values {
+			if value == noData {
+				grid.Values[i] = float32(math.NaN())
+			}
+		}
+	}
+	return []*Grid{grid}, nil
+}
+
+// loadCSV reads a grid from rows of lat, lon and one column per pollutant
+// (pm25, pm10) or a value column whose pollutant is given by the file name.
+// An optional time column splits the rows into frames; otherwise the time
+// comes from the file name.
+func loadCSV(path string) ([]*Grid, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV header: %w", err)
+	}
+
+	filePollutant, fileTime := parseFileName(path)
+	latCol, lonCol, timeCol := -1, -1, -1
+	valueCols := map[int]string{}
+	for i, name := range header {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "lat", "latitude":
+			latCol = i
+		case "lon", "lng", "longitude":
+			lonCol = i
+		case "time", "timestamp", "valid_time":
+			timeCol = i
+		case "value", "concentration":
+			if filePollutant != "" {
+				valueCols[i] = filePollutant
+			}
+		default:
+			if pollutant, ok := pollutantAliases[name]; ok {
+				valueCols[i] = pollutant
+			}
+		}
+	}
+	if latCol < 0 || lonCol < 0 || len(valueCols) == 0 {
+		return nil, fmt.Errorf("CSV needs lat, lon and pm25, pm10 or value columns")
+	}
+
+	type point struct {
+		lat, lon float64
+		values   map[string]float64
+	}
+	frames := map[time.Time][]point{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV line %d: %w", line, err)
+		}
+
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(record[latCol]), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(record[lonCol]), 64)
+		if latErr != nil || lonErr != nil {
+			return nil, fmt.Errorf("invalid coordinates on CSV line %d", line)
+		}
+		at := fileTime
+		if timeCol >= 0 {
+			if at, err = parseTimestamp(strings.TrimSpace(record[timeCol])); err != nil {
+				return nil, fmt.Errorf("invalid time on CSV line %d: %w", line, err)
+			}
+		}
+
+		p := point{lat: lat, lon: lon, values: map[string]float64{}}
+		for col, pollutant := range valueCols {
+			text := strings.TrimSpace(record[col])
+			if text == "" || strings.EqualFold(text, "nan") {
+				continue
+			}
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value on CSV line %d", pollutant, line)
+			}
+			p.values[pollutant] = value
+		}
+		frames[at] = append(frames[at], p)
+	}
+
+	var grids []*Grid
+	for at, points := range frames {
+		lats, lons := map[float64]bool{}, map[float64]bool{}
+		for _, p := range points {
+			lats[p.lat] = true
+			lons[p.lon] = true
+		}
+		latAxis, err := regularAxis(sortedKeys(lats))
+		if err != nil {
+			return nil, fmt.Errorf("latitudes: %w", err)
+		}
+		lonAxis, err := regularAxis(sortedKeys(lons))
+		if err != nil {
+			return nil, fmt.Errorf("longitudes: %w", err)
+		}
+
+		for _, pollutant := range uniquePollutants(valueCols) {
+			grid := latAxis.grid(lonAxis)
+			grid.Pollutant = pollutant
+			grid.Time = at
+			grid.Source = path
+			for _, p := range points {
+				if value, ok := p.values[pollutant]; ok {
+					grid.Values[latAxis.index(p.lat)*grid.Width+lonAxis.index(p.lon)] = float32(value)
+				}
+			}
+			grids = append(grids, grid)
+		}
+	}
+	return grids, nil
+}
+
+// axis is a regularly spaced coordinate axis
+type axis struct {
+	start, step float64
+	length      int
+}
+
+func (a axis) index(value float64) int {
+	if a.step == 0 {
+		return 0
+	}
+	return int(math.Round((value - a.start) / a.step))
+}
+
+// grid returns an empty grid with a as its latitude axis
+func (a axis) grid(lon axis) *Grid {
+	values := make([]float32, a.length*lon.length)
+	for i := range values {
+		values[i] = float32(math.NaN())
+	}
+	return &Grid{
+		Lon0: lon.start, LonStep: lon.step, Width: lon.length,
+		Lat0: a.start, LatStep: a.step, Height: a.length,
+		Values: values,
+	}
+}
+
+// regularAxis checks that coordinates are evenly spaced, allowing for
+// single-precision rounding and missing rows
+func regularAxis(values []float64) (axis, error) {
+	if len(values) == 0 {
+		return axis{}, fmt.Errorf("no coordinates")
+	}
+	if len(values) == 1 {
+		return axis{start: values[0], step: 1, length: 1}, nil
+	}
+
+	step := values[1] - values[0]
+	for i := 2; i < len(values); i++ {
+		if d := values[i] - values[i-1]; math.Abs(d) < math.Abs(step) {
+			step = d
+		}
+	}
+	if step == 0 {
+		return axis{}, fmt.Errorf("duplicate coordinates")
+	}
+
+	a := axis{start: values[0], step: step, length: int(math.Round((values[len(values)-1]-values[0])/step)) + 1}
+	for _, value := range values {
+		if math.Abs(float64(a.index(value))*step+a.start-value) > math.Abs(step)*0.01 {
+			return axis{}, fmt.Errorf("coordinates are not evenly spaced; regrid the field first (e.g. cdo remapbil)")
+		}
+	}
+	return a, nil
+}
+
+func sortedKeys(set map[float64]bool) []float64 {
+	keys := make([]float64, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Float64s(keys)
+	return keys
+}
+
+func uniquePollutants(cols map[int]string) []string {
+	seen := map[string]bool{}
+	var pollutants []string
+	for _, pollutant := range cols {
+		if !seen[pollutant] {
+			seen[pollutant] = true
+			pollutants = append(pollutants, pollutant)
+		}
+	}
+	sort.Strings(pollutants)
+	return pollutants
+}
+
+// parseTimestamp accepts RFC 3339 and common date-time layouts, in UTC
+func parseTimestamp(text string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02_15:04:05", "2006-01-02"} {
+		if at, err := time.Parse(layout, text); err == nil {
+			return at.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised time %q", text)
+}
@@ -0,0 +1,571 @@
+package background
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// NetCDF classic format tags and data types
+const (
+	ncDimension = 0x0A
+	ncVariable  = 0x0B
+	ncAttribute = 0x0C
+
+	ncByte   = 1
+	ncChar   = 2
+	ncShort  = 3
+	ncInt    = 4
+	ncFloat  = 5
+	ncDouble = 6
+)
+
+var ncTypeSizes = map[int]int{ncByte: 1, ncChar: 1, ncShort: 2, ncInt: 4, ncFloat: 4, ncDouble: 8}
+
+type ncDim struct {
+	name   string
+	length int // 0 for the record dimension
+}
+
+type ncAttr struct {
+	text   string
+	values []float64
+}
+
+type ncVar struct {
+	name   string
+	dims   []int
+	attrs  map[string]ncAttr
+	ncType int
+	vsize  int64
+	begin  int64
+}
+
+// netcdfFile is an open NetCDF classic (CDF-1) or 64-bit offset (CDF-2) file.
+// NetCDF-4 files are HDF5 and are not supported.
+type netcdfFile struct {
+	file      *os.File
+	numRecs   int
+	recordDim int // -1 without a record dimension
+	recSize   int64
+	dims      []ncDim
+	vars      []*ncVar
+}
+
+// ncHeader reads the header fields sequentially
+type ncHeader struct {
+	r       io.Reader
+	offsets int // 4 or 8 byte offsets
+	err     error
+}
+
+func (h *ncHeader) uint32() uint32 {
+	if h.err != nil {
+		return 0
+	}
+	var buf [4]byte
+	_, h.err = io.ReadFull(h.r, buf[:])
+	return binary.BigEndian.Uint32(buf[:])
+}
+
+func (h *ncHeader) offset() int64 {
+	if h.offsets == 8 {
+		high := h.uint32()
+		return int64(high)<<32 | int64(h.uint32())
+	}
+	return int64(h.uint32())
+}
+
+// bytes reads n bytes followed by padding to a 4-byte boundary
+func (h *ncHeader) bytes(n int) []byte {
+	if h.err != nil {
+		return nil
+	}
+	if n < 0 || n > 1<<28 {
+		h.err = fmt.Errorf("invalid NetCDF header length %d", n)
+		return nil
+	}
+	buf := make([]byte, (n+3)&^3)
+	_, h.err = io.ReadFull(h.r, buf)
+	return buf[:n]
+}
+
+func (h *ncHeader) name() string {
+	return string(h.bytes(int(h.uint32())))
+}
+
+// list reads the tag and element count of a dimension, attribute or
+// variable list; absent lists are two zero words
+func (h *ncHeader) list(tag uint32) int {
+	kind, count := h.uint32(), int(h.uint32())
+	if h.err == nil && kind != tag && !(kind == 0 && count == 0) {
+		h.err = fmt.Errorf("malformed NetCDF header")
+	}
+	return count
+}
+
+func (h *ncHeader) attrs() map[string]ncAttr {
+	attrs := map[string]ncAttr{}
+	for i, n := 0, h.list(ncAttribute); i < n && h.err == nil; i++ {
+		name := h.name()
+		ncType := int(h.uint32())
+		count := int(h.uint32())
+		size, ok := ncTypeSizes[ncType]
+		if !ok {
+			h.err = fmt.Errorf("unsupported NetCDF attribute type %d", ncType)
+			return nil
+		}
+		data := h.bytes(count * size)
+		if ncType == ncChar {
+			attrs[name] = ncAttr{text: strings.TrimRight(string(data), "\x00")}
+			continue
+		}
+		values := make([]float64, count)
+		for j := range values {
+			values[j] = decodeNC(data[j*size:], ncType)
+		}
+		attrs[name] = ncAttr{values: values}
+	}
+	return attrs
+}
+
+// openNetCDF reads the header of a NetCDF classic file
+func openNetCDF(path string) (*netcdfFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var magic [4]byte
+	if _, err := io.ReadFull(file, magic[:]); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	h := &ncHeader{r: file, offsets: 4}
+	switch {
+	case string(magic[:3]) == "CDF" && magic[3] == 1:
+	case string(magic[:3]) == "CDF" && magic[3] == 2:
+		h.offsets = 8
+	case string(magic[1:4]) == "HDF":
+		file.Close()
+		return nil, fmt.Errorf("%s is NetCDF-4 (HDF5); convert it with `nccopy -k classic`", path)
+	default:
+		file.Close()
+		return nil, fmt.Errorf("%s is not a NetCDF classic file", path)
+	}
+
+	nc := &netcdfFile{file: file, recordDim: -1}
+	nc.numRecs = int(h.uint32())
+
+	for i, n := 0, h.list(ncDimension); i < n && h.err == nil; i++ {
+		dim := ncDim{name: h.name(), length: int(h.uint32())}
+		if dim.length == 0 {
+			nc.recordDim = i
+		}
+		nc.dims = append(nc.dims, dim)
+	}
+	h.attrs() // global attributes
+
+	var recordVars []*ncVar
+	for i, n := 0, h.list(ncVariable); i < n && h.err == nil; i++ {
+		v := &ncVar{name: h.name()}
+		for j, rank := 0, int(h.uint32()); j < rank && h.err == nil; j++ {
+			dim := int(h.uint32())
+			if dim >= len(nc.dims) {
+				h.err = fmt.Errorf("variable %s has an unknown dimension", v.name)
+				break
+			}
+			v.dims = append(v.dims, dim)
+		}
+		v.attrs = h.attrs()
+		v.ncType = int(h.uint32())
+		v.vsize = int64(h.uint32())
+		v.begin = h.offset()
+		if _, ok := ncTypeSizes[v.ncType]; !ok && h.err == nil {
+			h.err = fmt.Errorf("variable %s has unsupported type %d", v.name, v.ncType)
+		}
+		nc.vars = append(nc.vars, v)
+		if nc.isRecord(v) {
+			recordVars = append(recordVars, v)
+			nc.recSize += v.vsize
+		}
+	}
+	if h.err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading NetCDF header of %s: %w", path, h.err)
+	}
+
+	// A lone record variable is not padded between records
+	if len(recordVars) == 1 {
+		nc.recSize = int64(nc.recordLength(recordVars[0]) * ncTypeSizes[recordVars[0].ncType])
+	}
+	return nc, nil
+}
+
+func (nc *netcdfFile) Close() error {
+	return nc.file.Close()
+}
+
+func (nc *netcdfFile) variable(names ...string) (*ncVar, bool) {
+	for _, name := range names {
+		for _, v := range nc.vars {
+			if v.name == name {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (nc *netcdfFile) isRecord(v *ncVar) bool {
+	return len(v.dims) > 0 && v.dims[0] == nc.recordDim
+}
+
+// shape returns the length of each of the variable's dimensions
+func (nc *netcdfFile) shape(v *ncVar) []int {
+	shape := make([]int, len(v.dims))
+	for i, dim := range v.dims {
+		shape[i] = nc.dims[dim].length
+		if dim == nc.recordDim {
+			shape[i] = nc.numRecs
+		}
+	}
+	return shape
+}
+
+// recordLength is the number of values in one record of a record variable,
+// or in the whole of any other variable
+func (nc *netcdfFile) recordLength(v *ncVar) int {
+	length := 1
+	for i, size := range nc.shape(v) {
+		if i == 0 && nc.isRecord(v) {
+			continue
+		}
+		length *= size
+	}
+	return length
+}
+
+// read returns the values of one record of a record variable (record is
+// ignored for other variables) with scale_factor and add_offset applied and
+// _FillValue and missing_value as NaN
+func (nc *netcdfFile) read(v *ncVar, record int) ([]float64, error) {
+	size := ncTypeSizes[v.ncType]
+	count := nc.recordLength(v)
+	offset := v.begin
+	if nc.isRecord(v) {
+		if record < 0 || record >= nc.numRecs {
+			return nil, fmt.Errorf("record %d of %s out of range", record, v.name)
+		}
+		offset += int64(record) * nc.recSize
+	}
+
+	data := make([]byte, count*size)
+	if _, err := nc.file.ReadAt(data, offset); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", v.name, err)
+	}
+
+	scale, add := 1.0, 0.0
+	if attr, ok := v.attrs["scale_factor"]; ok && len(attr.values) > 0 {
+		scale = attr.values[0]
+	}
+	if attr, ok := v.attrs["add_offset"]; ok && len(attr.values) > 0 {
+		add = attr.values[0]
+	}
+	var missing []float64
+	for _, name := range []string{"_FillValue", "missing_value"} {
+		if attr, ok := v.attrs[name]; ok {
+			missing = append(missing, attr.values...)
+		}
+	}
+
+	values := make([]float64, count)
+	for i := range values {
+		raw := decodeNC(data[i*size:], v.ncType)
+		values[i] = raw*scale + add
+		for _, fill := range missing {
+			if raw == fill {
+				values[i] = math.NaN()
+			}
+		}
+	}
+	return values, nil
+}
+
+// readText returns a char variable's values as strings of its last dimension
+func (nc *netcdfFile) readText(v *ncVar) ([]string, error) {
+	shape := nc.shape(v)
+	if v.ncType != ncChar || len(shape) == 0 {
+		return nil, fmt.Errorf("%s is not a text variable", v.name)
+	}
+	width := shape[len(shape)-1]
+
+	var texts []string
+	records := 1
+	if nc.isRecord(v) {
+		records = nc.numRecs
+	}
+	for record := 0; record < records; record++ {
+		offset := v.begin + int64(record)*nc.recSize
+		data := make([]byte, nc.recordLength(v))
+		if _, err := nc.file.ReadAt(data, offset); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", v.name, err)
+		}
+		for i := 0; i+width <= len(data); i += width {
+			texts = append(texts, strings.TrimRight(string(data[i:i+width]), "\x00 "))
+		}
+	}
+	return texts, nil
+}
+
+func decodeNC(data []byte, ncType int) float64 {
+	switch ncType {
+	case ncByte:
+		return float64(int8(data[0]))
+	case ncChar:
+		return float64(data[0])
+	case ncShort:
+		return float64(int16(binary.BigEndian.Uint16(data)))
+	case ncInt:
+		return float64(int32(binary.BigEndian.Uint32(data)))
+	case ncFloat:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case ncDouble:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
+
+// timeVariables are the coordinate variables holding CF-style numeric times
+var timeVariables = []string{"time", "valid_time", "t"}
+
+// loadNetCDF reads every time step of the PM2.5 and PM10 variables in a
+// NetCDF classic file. Extra dimensions such as model levels use their first
+// index, which is the surface level in CAMS and WRF-Chem output.
+func loadNetCDF(path string) ([]*Grid, error) {
+	nc, err := openNetCDF(path)
+	if err != nil {
+		return nil, err
+	}
+	defer nc.Close()
+
+	lat, latOK := nc.variable("latitude", "lat", "XLAT")
+	lon, lonOK := nc.variable("longitude", "lon", "XLONG")
+	if !latOK || !lonOK {
+		return nil, fmt.Errorf("no latitude/longitude coordinate variables")
+	}
+	latAxis, lonAxis, err := nc.axes(lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	yDim, xDim := lat.dims[len(lat.dims)-1], lon.dims[len(lon.dims)-1]
+	if len(lat.dims) > 1 {
+		yDim = lat.dims[len(lat.dims)-2]
+	}
+
+	times, timeDim, err := nc.times()
+	if err != nil {
+		return nil, err
+	}
+	_, fileTime := parseFileName(path)
+
+	var grids []*Grid
+	for _, pollutant := range []string{pollutantPM25, pollutantPM10} {
+		v, ok := nc.variable(variableNames[pollutant]...)
+		if !ok {
+			continue
+		}
+		if len(v.dims) < 2 || v.dims[len(v.dims)-2] != yDim || v.dims[len(v.dims)-1] != xDim {
+			return nil, fmt.Errorf("%s is not laid out as (latitude, longitude)", v.name)
+		}
+
+		factor := 1.0
+		units := strings.ToLower(v.attrs["units"].text)
+		switch {
+		case strings.HasPrefix(units, "kg"):
+			factor = 1e9
+		case strings.HasPrefix(units, "mg"):
+			factor = 1e3
+		}
+
+		size := latAxis.length * lonAxis.length
+		steps := 1
+		timed := len(v.dims) > 2 && (nc.isRecord(v) || v.dims[0] == timeDim)
+		if timed {
+			steps = nc.shape(v)[0]
+		}
+
+		var all []float64
+		for step := 0; step < steps; step++ {
+			var values []float64
+			if nc.isRecord(v) {
+				if values, err = nc.read(v, step); err != nil {
+					return nil, err
+				}
+			} else {
+				if all == nil {
+					if all, err = nc.read(v, 0); err != nil {
+						return nil, err
+					}
+				}
+				values = all[step*len(all)/steps:]
+			}
+
+			grid := latAxis.grid(lonAxis)
+			grid.Pollutant = pollutant
+			grid.Source = path
+			grid.Time = fileTime
+			if timed && step < len(times) {
+				grid.Time = times[step]
+			} else if !timed && len(times) == 1 {
+				grid.Time = times[0]
+			}
+			for i := 0; i < size; i++ {
+				grid.Values[i] = float32(values[i] * factor)
+			}
+			grids = append(grids, grid)
+		}
+	}
+
+	if len(grids) == 0 {
+		return nil, fmt.Errorf("no PM2.5 or PM10 variable found")
+	}
+	return grids, nil
+}
+
+// axes returns the latitude and longitude axes of the grid. Two-dimensional
+// coordinates, as written by WRF, must describe a regular lat/lon grid.
+func (nc *netcdfFile) axes(lat, lon *ncVar) (axis, axis, error) {
+	latValues, err := nc.read(lat, 0)
+	if err != nil {
+		return axis{}, axis{}, err
+	}
+	lonValues, err := nc.read(lon, 0)
+	if err != nil {
+		return axis{}, axis{}, err
+	}
+
+	if len(lat.dims) > 1 {
+		shape := nc.shape(lat)
+		ny, nx := shape[len(shape)-2], shape[len(shape)-1]
+		if len(latValues) < ny*nx || len(lonValues) < ny*nx {
+			return axis{}, axis{}, fmt.Errorf("latitude and longitude shapes differ")
+		}
+
+		lats, lons := make([]float64, ny), make([]float64, nx)
+		for y := range lats {
+			lats[y] = latValues[y*nx]
+		}
+		copy(lons, lonValues[:nx])
+		latAxis, latErr := regularAxis(lats)
+		lonAxis, lonErr := regularAxis(lons)
+		if latErr != nil || lonErr != nil {
+			return axis{}, axis{}, errCurvilinear
+		}
+		for y := 0; y < ny; y++ {
+			for x := 0; x < nx; x++ {
+				if math.Abs(latValues[y*nx+x]-lats[y]) > math.Abs(latAxis.step)*0.01 ||
+					math.Abs(lonValues[y*nx+x]-lons[x]) > math.Abs(lonAxis.step)*0.01 {
+					return axis{}, axis{}, errCurvilinear
+				}
+			}
+		}
+		return latAxis, lonAxis, nil
+	}
+
+	latAxis, err := regularAxis(latValues)
+	if err != nil {
+		return axis{}, axis{}, fmt.Errorf("latitudes: %w", err)
+	}
+	lonAxis, err := regularAxis(lonValues)
+	if err != nil {
+		return axis{}, axis{}, fmt.Errorf("longitudes: %w", err)
+	}
+	return latAxis, lonAxis, nil
+}
+
+var errCurvilinear = errors.New("grid is not a regular lat/lon grid (e.g. a Lambert conformal WRF domain); regrid it first, e.g. with cdo remapbil")
+
+// times returns the valid times of the file from a CF time variable or the
+// WRF Times variable, together with the time dimension (-1 if none)
+func (nc *netcdfFile) times() ([]time.Time, int, error) {
+	if v, ok := nc.variable(timeVariables...); ok && len(v.dims) <= 1 && v.ncType != ncChar {
+		unit, since, err := parseTimeUnits(v.attrs["units"].text)
+		if err != nil {
+			return nil, -1, fmt.Errorf("%s: %w", v.name, err)
+		}
+
+		var values []float64
+		if nc.isRecord(v) {
+			for record := 0; record < nc.numRecs; record++ {
+				value, err := nc.read(v, record)
+				if err != nil {
+					return nil, -1, err
+				}
+				values = append(values, value...)
+			}
+		} else if values, err = nc.read(v, 0); err != nil {
+			return nil, -1, err
+		}
+
+		times := make([]time.Time, len(values))
+		for i, value := range values {
+			times[i] = since.Add(time.Duration(value * float64(unit)))
+		}
+		dim := -1
+		if len(v.dims) == 1 {
+			dim = v.dims[0]
+		}
+		return times, dim, nil
+	}
+
+	if v, ok := nc.variable("Times"); ok && v.ncType == ncChar && len(v.dims) == 2 {
+		texts, err := nc.readText(v)
+		if err != nil {
+			return nil, -1, err
+		}
+		times := make([]time.Time, len(texts))
+		for i, text := range texts {
+			if times[i], err = time.Parse("2006-01-02_15:04:05", text); err != nil {
+				return nil, -1, fmt.Errorf("invalid WRF time %q", text)
+			}
+		}
+		return times, v.dims[0], nil
+	}
+
+	return nil, -1, nil
+}
+
+// parseTimeUnits parses CF time units such as "hours since 2024-01-01 00:00:00"
+func parseTimeUnits(units string) (time.Duration, time.Time, error) {
+	parts := strings.SplitN(strings.TrimSpace(units), " since ", 2)
+	if len(parts) != 2 {
+		return 0, time.Time{}, fmt.Errorf("unsupported time units %q", units)
+	}
+
+	var unit time.Duration
+	switch strings.ToLower(parts[0]) {
+	case "seconds", "second", "secs", "sec", "s":
+		unit = time.Second
+	case "minutes", "minute", "mins", "min":
+		unit = time.Minute
+	case "hours", "hour", "hrs", "hr", "h":
+		unit = time.Hour
+	case "days", "day", "d":
+		unit = 24 * time.Hour
+	default:
+		return 0, time.Time{}, fmt.Errorf("unsupported time units %q", units)
+	}
+
+	reference := strings.TrimSuffix(strings.TrimSpace(parts[1]), " UTC")
+	since, err := parseTimestamp(reference)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return unit, since, nil
+}
@@ -0,0 +1,216 @@
+// Package cache holds upstream lookups in in-memory LRU caches keyed by
+// geohash cell and time bucket, so that nearby points and repeated corridors
+// share one provider call. Caches can be persisted across restarts.
+package cache
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Cache is a size-bounded LRU cache whose entries expire at the end of the
+// TTL-long time bucket they were stored in. Values are kept JSON-encoded so
+// that they can be persisted. A nil Cache never hits.
+type Cache struct {
+	name      string
+	precision int
+	ttl       time.Duration
+	capacity  int
+
+	mu        sync.Mutex
+	order     *list.List // most recently used first
+	items     map[string]*list.Element
+	hits      uint64
+	misses    uint64
+	evictions uint64
+	dirty     bool
+}
+
+type entry struct {
+	Key     string          `json:"key"`
+	Value   json.RawMessage `json:"value"`
+	Expires time.Time       `json:"expires"`
+}
+
+// Stats reports a cache's size and effectiveness since startup
+type Stats struct {
+	Precision  int     `json:"precision"`
+	TTLSeconds float64 `json:"ttl_seconds"`
+	Entries    int     `json:"entries"`
+	Capacity   int     `json:"capacity"`
+	Hits       uint64  `json:"hits"`
+	Misses     uint64  `json:"misses"`
+	Evictions  uint64  `json:"evictions"`
+	HitRate    float64 `json:"hit_rate"`
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*Cache{}
+)
+
+// New creates and registers a cache of geohash cells of the given precision.
+// It returns nil, which disables caching, when ttl or capacity is not
+// positive. A cache registered under the same name is replaced.
+func New(name string, precision int, ttl time.Duration, capacity int) *Cache {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if ttl <= 0 || capacity <= 0 || precision <= 0 {
+		delete(registry, name)
+		return nil
+	}
+	c := &Cache{
+		name:      name,
+		precision: precision,
+		ttl:       ttl,
+		capacity:  capacity,
+		order:     list.New(),
+		items:     map[string]*list.Element{},
+	}
+	registry[name] = c
+	return c
+}
+
+// Key returns the key of a lookup at a location ([longitude, latitude]) in
+// the current time bucket; prefix distinguishes providers and parameters
+func (c *Cache) Key(prefix string, location [2]float64, now time.Time) string {
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s|%d", c.Cell(prefix, location), now.Truncate(c.ttl).Unix())
+}
+
+// Cell returns the key of a lookup at a location regardless of time
+func (c *Cache) Cell(prefix string, location [2]float64) string {
+	if c == nil {
+		return ""
+	}
+	return prefix + "|" + Geohash(location[1], location[0], c.precision)
+}
+
+// TTL returns how long lookups are reused; zero for a nil Cache
+func (c *Cache) TTL() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.ttl
+}
+
+// Get decodes the value stored under key into target, reporting whether a
+// live entry was found
+func (c *Cache) Get(key string, target interface{}) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if ok && time.Now().After(element.Value.(*entry).Expires) {
+		c.remove(element)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return false
+	}
+	if err := json.Unmarshal(element.Value.(*entry).Value, target); err != nil {
+		c.remove(element)
+		c.misses++
+		return false
+	}
+
+	c.order.MoveToFront(element)
+	c.hits++
+	return true
+}
+
+// Set stores a value until the end of the current time bucket, evicting the
+// least recently used entry when the cache is full
+func (c *Cache) Set(key string, value interface{}) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.put(&entry{Key: key, Value: data, Expires: now.Truncate(c.ttl).Add(c.ttl)})
+}
+
+// put inserts or replaces an entry. Must be called with mu held.
+func (c *Cache) put(e *entry) {
+	if element, ok := c.items[e.Key]; ok {
+		element.Value = e
+		c.order.MoveToFront(element)
+	} else {
+		c.items[e.Key] = c.order.PushFront(e)
+	}
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+	c.dirty = true
+}
+
+// remove deletes an entry. Must be called with mu held.
+func (c *Cache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*entry).Key)
+	c.dirty = true
+}
+
+// Stats returns the cache's statistics
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := Stats{
+		Precision:  c.precision,
+		TTLSeconds: c.ttl.Seconds(),
+		Entries:    c.order.Len(),
+		Capacity:   c.capacity,
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Evictions:  c.evictions,
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
+
+// AllStats returns the statistics of every registered cache by name
+func AllStats() map[string]Stats {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	stats := make(map[string]Stats, len(registry))
+	for name, c := range registry {
+		stats[name] = c.Stats()
+	}
+	return stats
+}
+
+// registered returns the registered caches ordered by name
+func registered() []*Cache {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	caches := make([]*Cache, 0, len(registry))
+	for _, c := range registry {
+		caches = append(caches, c)
+	}
+	sort.Slice(caches, func(i, j int) bool { return caches[i].name < caches[j].name })
+	return caches
+}
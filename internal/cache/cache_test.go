@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGeohash(t *testing.T) {
+	tests := []struct {
+		lat, lon  float64
+		precision int
+		want      string
+	}{
+		{57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{42.6, -5.6, 5, "ezs42"},
+		{-25.382708, -49.265506, 8, "6gkzwgjz"},
+		{37.8324, 112.5584, 9, "ww8p1r4t8"},
+		{0, 0, 1, "s"},
+	}
+
+	for _, tt := range tests {
+		if got := Geohash(tt.lat, tt.lon, tt.precision); got != tt.want {
+			t.Errorf("Geohash(%g, %g, %d) = %q, want %q", tt.lat, tt.lon, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestKeySharesCellAndBucket(t *testing.T) {
+	c := New("test", 6, time.Hour, 10)
+	bucket := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		location [2]float64
+		at       time.Time
+		same     bool
+	}{
+		{"same cell later in the bucket", [2]float64{77.5947, 12.9717}, bucket.Add(59 * time.Minute), true},
+		{"next bucket", [2]float64{77.5946, 12.9716}, bucket.Add(time.Hour), false},
+		{"other cell", [2]float64{77.62, 12.9716}, bucket, false},
+	}
+
+	want := c.Key("waqi", [2]float64{77.5946, 12.9716}, bucket)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Key("waqi", tt.location, tt.at); (got == want) != tt.same {
+				t.Errorf("Key = %q, compared with %q: same %v, want %v", got, want, got == want, tt.same)
+			}
+		})
+	}
+}
+
+func TestGetSetExpiry(t *testing.T) {
+	c := New("test", 6, time.Hour, 10)
+	c.Set("a", 42)
+
+	var got int
+	if !c.Get("a", &got) || got != 42 {
+		t.Fatalf("Get returned %d, want the stored 42", got)
+	}
+
+	// Entries live until the end of the bucket they were stored in
+	c.mu.Lock()
+	expires := c.items["a"].Value.(*entry).Expires
+	c.items["a"].Value.(*entry).Expires = time.Now().Add(-time.Second)
+	c.mu.Unlock()
+	if !expires.Equal(expires.Truncate(time.Hour)) || !expires.After(time.Now()) || expires.After(time.Now().Add(time.Hour)) {
+		t.Errorf("entry expires at %s, want the end of the current hour", expires)
+	}
+
+	if c.Get("a", &got) {
+		t.Error("expired entry was returned")
+	}
+	if stats := c.Stats(); stats.Entries != 0 || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("stats %+v, want the expired entry removed after one hit and one miss", stats)
+	}
+}
+
+func TestSetEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New("test", 6, time.Hour, 2)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	var value int
+	c.Get("a", &value) // b is now the least recently used
+	c.Set("c", 3)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if got := c.Get(key, &value); got != want {
+			t.Errorf("Get(%q) found %v, want %v", key, got, want)
+		}
+	}
+	if evictions := c.Stats().Evictions; evictions != 1 {
+		t.Errorf("%d evictions, want 1", evictions)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	c := New("test", 6, 0, 10)
+	if c != nil {
+		t.Fatal("New with no TTL returned a cache")
+	}
+	c.Set("a", 1)
+	var value int
+	if c.Get("a", &value) || c.Key("waqi", [2]float64{77.59, 12.97}, time.Now()) != "" {
+		t.Error("nil cache hit")
+	}
+}
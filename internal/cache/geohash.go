@@ -0,0 +1,37 @@
+package cache
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash encodes a point as a geohash of the given number of characters.
+// Precision 5 cells are about 4.9 x 4.9 km, 6 about 1.2 x 0.6 km and 7 about
+// 150 x 150 m.
+func Geohash(lat, lon float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+	hash := make([]byte, 0, precision)
+
+	var bits, ch int
+	even := true
+	for len(hash) < precision {
+		// Bits alternate between longitude and latitude, starting with longitude
+		r, value := &latRange, lat
+		if even {
+			r, value = &lonRange, lon
+		}
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if value >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+
+		if bits++; bits == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
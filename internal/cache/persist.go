@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+)
+
+var persistOnce sync.Once
+
+// Init restores the registered caches from CACHE_PERSIST_FILE and saves them
+// back every CACHE_PERSIST_SECONDS while they change. Caches must be created
+// (by the air-quality and weather packages) before Init is called.
+func Init() error {
+	path := config.AppConfig.CachePersistFile
+	if path == "" {
+		logger.Info("Lookup caches ready", "persisted", false, "caches", len(registered()))
+		return nil
+	}
+
+	restored, err := load(path)
+	if err != nil {
+		return err
+	}
+
+	persistOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(config.AppConfig.CachePersistInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				if err := Save(path); err != nil {
+					logger.Error("Failed to persist lookup caches",
+						"file", path,
+						"error", err.Error(),
+					)
+				}
+			}
+		}()
+	})
+
+	logger.Info("Lookup caches ready",
+		"persisted", true,
+		"file", path,
+		"restored_entries", restored,
+	)
+	return nil
+}
+
+// load restores the unexpired entries of the registered caches, returning
+// how many were restored. A missing file is not an error.
+func load(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading cache file %s: %w", path, err)
+	}
+
+	var saved map[string][]*entry
+	if err := json.Unmarshal(data, &saved); err != nil {
+		// A corrupt cache only costs upstream calls
+		logger.Warn("Ignoring unreadable cache file", "file", path, "error", err.Error())
+		return 0, nil
+	}
+
+	var restored int
+	now := time.Now()
+	for _, c := range registered() {
+		entries := saved[c.name]
+		c.mu.Lock()
+		// Entries are saved most recently used first
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].Expires.After(now) {
+				c.put(entries[i])
+				restored++
+			}
+		}
+		c.dirty = false
+		c.mu.Unlock()
+	}
+	return restored, nil
+}
+
+// Flush saves the registered caches to CACHE_PERSIST_FILE if they changed
+// since the last save
+func Flush() error {
+	path := config.AppConfig.CachePersistFile
+	if path == "" {
+		return nil
+	}
+	return Save(path)
+}
+
+// Save writes the unexpired entries of the registered caches to path if any
+// cache changed since the last save
+func Save(path string) error {
+	caches := registered()
+	saved := make(map[string][]*entry, len(caches))
+	var changed bool
+	now := time.Now()
+	for _, c := range caches {
+		c.mu.Lock()
+		changed = changed || c.dirty
+		c.dirty = false
+		entries := make([]*entry, 0, c.order.Len())
+		for element := c.order.Front(); element != nil; element = element.Next() {
+			if e := element.Value.(*entry); e.Expires.After(now) {
+				entries = append(entries, e)
+			}
+		}
+		c.mu.Unlock()
+		saved[c.name] = entries
+	}
+	if !changed {
+		return nil
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("error encoding caches: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing cache file %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing cache file %s: %w", path, err)
+	}
+	return nil
+}
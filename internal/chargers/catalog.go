@@ -0,0 +1,70 @@
+package chargers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+)
+
+var (
+	mu       sync.RWMutex
+	stations []Station
+	index    *Index
+)
+
+// Init loads the charging stations from CHARGING_STATIONS_FILE. Without a
+// file no stations are known and charging stops are never planned.
+func Init() error {
+	path := config.AppConfig.ChargingStationsFile
+	if path == "" {
+		logger.Info("No charging station dataset configured")
+		return nil
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	stations = loaded
+	index = NewIndex(loaded)
+	mu.Unlock()
+
+	logger.Info("Charging stations loaded", "file", path, "stations", len(loaded))
+	return nil
+}
+
+// Load reads an Open Charge Map JSON export or a CSV file of charging stations
+func Load(path string) ([]Station, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening charging stations %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(path)) == ".csv" {
+		return parseChargersCSV(file)
+	}
+	return parseOpenChargeMapJSON(file)
+}
+
+// All returns every loaded charging station
+func All() []Station {
+	mu.RLock()
+	defer mu.RUnlock()
+	return stations
+}
+
+// Near returns the loaded stations that may lie within radiusKm of a point
+// ([longitude, latitude] given as lon, lat); see Index.Near
+func Near(lon, lat, radiusKm float64) []Station {
+	mu.RLock()
+	defer mu.RUnlock()
+	return index.Near(lon, lat, radiusKm)
+}
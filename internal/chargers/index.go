@@ -0,0 +1,78 @@
+package chargers
+
+import (
+	"math"
+)
+
+// cellDegrees is the size of the grid cells stations are bucketed in
+const cellDegrees = 0.1
+
+// kmPerDegree is the length of a degree of latitude
+const kmPerDegree = 111.32
+
+type cell struct {
+	lat, lon int
+}
+
+// Index buckets stations in a latitude/longitude grid so that the stations
+// near a point are found without scanning the whole dataset
+type Index struct {
+	cells map[cell][]Station
+}
+
+// NewIndex builds an index of the stations
+func NewIndex(stations []Station) *Index {
+	index := &Index{cells: map[cell][]Station{}}
+	for _, station := range stations {
+		c := cellOf(station.Location[0], station.Location[1])
+		index.cells[c] = append(index.cells[c], station)
+	}
+	return index
+}
+
+func cellOf(lon, lat float64) cell {
+	return cell{
+		lat: int(math.Floor(lat / cellDegrees)),
+		lon: wrapLonCell(int(math.Floor(lon / cellDegrees))),
+	}
+}
+
+// wrapLonCell maps a longitude cell into [-180°, 180°)
+func wrapLonCell(lon int) int {
+	n := int(math.Round(360 / cellDegrees))
+	lon = (lon + n/2) % n
+	if lon < 0 {
+		lon += n
+	}
+	return lon - n/2
+}
+
+// Near returns the stations in the grid cells overlapping the bounding box of
+// a circle of radiusKm around a point. Callers check the exact distance.
+func (index *Index) Near(lon, lat, radiusKm float64) []Station {
+	if index == nil || len(index.cells) == 0 {
+		return nil
+	}
+
+	latSpan := radiusKm / kmPerDegree
+	minLat := int(math.Floor((lat - latSpan) / cellDegrees))
+	maxLat := int(math.Floor((lat + latSpan) / cellDegrees))
+
+	// Near the poles the circle spans every longitude
+	lonCells := int(math.Round(360 / cellDegrees))
+	minLon, maxLon := -lonCells/2, lonCells/2-1
+	if cos := math.Cos((math.Abs(lat) + latSpan) * math.Pi / 180); cos > 0 {
+		if lonSpan := latSpan / cos; lonSpan < 180 {
+			minLon = int(math.Floor((lon - lonSpan) / cellDegrees))
+			maxLon = int(math.Floor((lon + lonSpan) / cellDegrees))
+		}
+	}
+
+	var near []Station
+	for latCell := minLat; latCell <= maxLat; latCell++ {
+		for lonCell := minLon; lonCell <= maxLon; lonCell++ {
+			near = append(near, index.cells[cell{lat: latCell, lon: wrapLonCell(lonCell)}]...)
+		}
+	}
+	return near
+}
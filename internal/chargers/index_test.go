@@ -0,0 +1,69 @@
+package chargers
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/clean-route/go-backend/internal/geo"
+)
+
+func TestIndexNearMatchesScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var stations []Station
+	for i := 0; i < 2000; i++ {
+		stations = append(stations, Station{
+			ID:       string(rune('a'+i%26)) + string(rune('0'+i/26%10)) + string(rune('0'+i/260)),
+			Location: [2]float64{76 + rng.Float64()*2, 12 + rng.Float64()*2},
+		})
+	}
+	index := NewIndex(stations)
+
+	for _, radius := range []float64{1, 5, 20} {
+		for q := 0; q < 50; q++ {
+			lon, lat := 76+rng.Float64()*2, 12+rng.Float64()*2
+
+			want := withinRadius(stations, lon, lat, radius)
+			got := withinRadius(index.Near(lon, lat, radius), lon, lat, radius)
+			if len(got) != len(want) {
+				t.Fatalf("radius %g at %g,%g: %d stations, want %d", radius, lon, lat, len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("radius %g at %g,%g: station %s, want %s", radius, lon, lat, got[i], want[i])
+				}
+			}
+		}
+	}
+}
+
+func TestIndexNearAcrossAntimeridian(t *testing.T) {
+	index := NewIndex([]Station{
+		{ID: "east", Location: [2]float64{179.99, -16.5}},
+		{ID: "west", Location: [2]float64{-179.99, -16.5}},
+	})
+
+	got := withinRadius(index.Near(179.995, -16.5, 5), 179.995, -16.5, 5)
+	if len(got) != 2 {
+		t.Errorf("found %v, want both stations", got)
+	}
+}
+
+func TestIndexNearEmpty(t *testing.T) {
+	var index *Index
+	if near := index.Near(77, 12, 5); len(near) != 0 {
+		t.Errorf("nil index returned %d stations", len(near))
+	}
+}
+
+// withinRadius returns the sorted ids of the stations within radiusKm
+func withinRadius(stations []Station, lon, lat, radiusKm float64) []string {
+	var ids []string
+	for _, station := range stations {
+		if geo.HaversineDistance(lat, lon, station.Location[1], station.Location[0])/1000 <= radiusKm {
+			ids = append(ids, station.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
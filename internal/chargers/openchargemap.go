@@ -0,0 +1,139 @@
+package chargers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ocmPOI is a point of interest in an Open Charge Map JSON export
+type ocmPOI struct {
+	ID          int `json:"ID"`
+	AddressInfo struct {
+		Title     string  `json:"Title"`
+		Latitude  float64 `json:"Latitude"`
+		Longitude float64 `json:"Longitude"`
+	} `json:"AddressInfo"`
+	Connections []struct {
+		ConnectionType *struct {
+			Title string `json:"Title"`
+		} `json:"ConnectionType"`
+		PowerKW *float64 `json:"PowerKW"`
+	} `json:"Connections"`
+	StatusType *struct {
+		IsOperational *bool `json:"IsOperational"`
+	} `json:"StatusType"`
+}
+
+// parseOpenChargeMapJSON reads an Open Charge Map POI export. Stations that
+// are marked as not operational or have no connector with a known power are
+// left out.
+func parseOpenChargeMapJSON(r io.Reader) ([]Station, error) {
+	var pois []ocmPOI
+	if err := json.NewDecoder(r).Decode(&pois); err != nil {
+		return nil, fmt.Errorf("error parsing Open Charge Map JSON: %w", err)
+	}
+
+	var stations []Station
+	for _, poi := range pois {
+		if poi.StatusType != nil && poi.StatusType.IsOperational != nil && !*poi.StatusType.IsOperational {
+			continue
+		}
+
+		station := Station{
+			ID:       strconv.Itoa(poi.ID),
+			Name:     poi.AddressInfo.Title,
+			Location: [2]float64{poi.AddressInfo.Longitude, poi.AddressInfo.Latitude},
+		}
+		for _, connection := range poi.Connections {
+			if connection.PowerKW == nil || *connection.PowerKW <= 0 {
+				continue
+			}
+			connector := Connector{PowerKW: *connection.PowerKW}
+			if connection.ConnectionType != nil {
+				connector.Type = connection.ConnectionType.Title
+			}
+			station.Connectors = append(station.Connectors, connector)
+		}
+
+		if len(station.Connectors) > 0 {
+			stations = append(stations, station)
+		}
+	}
+	return stations, nil
+}
+
+// parseChargersCSV reads a CSV export with one row per connector. The header
+// must contain id, latitude, longitude and power_kw columns; name (or title)
+// and connector_type are optional. Rows with the same id are merged into one
+// station.
+func parseChargersCSV(r io.Reader) ([]Station, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading charging stations CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		if i, ok := columns["title"]; ok {
+			columns["name"] = i
+		}
+	}
+	for _, required := range []string{"id", "latitude", "longitude", "power_kw"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("charging stations CSV is missing the %s column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var stations []Station
+	index := map[string]int{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading charging stations CSV line %d: %w", line, err)
+		}
+
+		lat, latErr := strconv.ParseFloat(field(record, "latitude"), 64)
+		lon, lonErr := strconv.ParseFloat(field(record, "longitude"), 64)
+		power, powerErr := strconv.ParseFloat(field(record, "power_kw"), 64)
+		if latErr != nil || lonErr != nil || powerErr != nil || power <= 0 {
+			continue
+		}
+
+		id := field(record, "id")
+		connector := Connector{Type: field(record, "connector_type"), PowerKW: power}
+		if i, ok := index[id]; ok {
+			stations[i].Connectors = append(stations[i].Connectors, connector)
+			continue
+		}
+
+		index[id] = len(stations)
+		stations = append(stations, Station{
+			ID:         id,
+			Name:       field(record, "name"),
+			Location:   [2]float64{lon, lat},
+			Connectors: []Connector{connector},
+		})
+	}
+	return stations, nil
+}
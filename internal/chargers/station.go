@@ -0,0 +1,26 @@
+package chargers
+
+// Connector is a charging point of a station
+type Connector struct {
+	Type    string  `json:"type"`
+	PowerKW float64 `json:"power_kw"`
+}
+
+// Station is a public charging station. Location is [longitude, latitude].
+type Station struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Location   [2]float64  `json:"location"`
+	Connectors []Connector `json:"connectors"`
+}
+
+// FastestConnector returns the connector with the highest power
+func (s Station) FastestConnector() Connector {
+	var fastest Connector
+	for _, connector := range s.Connectors {
+		if connector.PowerKW > fastest.PowerKW {
+			fastest = connector
+		}
+	}
+	return fastest
+}
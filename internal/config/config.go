@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,7 +16,193 @@ type Config struct {
 	WAQIAPIKey        string
 	OpenWeatherAPIKey string
 	AWSModelEndpoint  string
+	ModelRegistryFile string
 	IsRailway         bool
+
+	// AdminAPIKeys authenticate administrative endpoints such as the model
+	// registry reload and price updates
+	AdminAPIKeys []string
+
+	// VehicleProfilesFile is a JSON or YAML catalog merged over the built-in
+	// vehicle profiles
+	VehicleProfilesFile string
+
+	// PredictionErrorByHorizon is the empirical PM2.5 RMSE (µg/m³) per delay
+	// code, used when a model does not report its own uncertainty
+	PredictionErrorByHorizon map[uint8]float64
+	// LeapOverlapAsTie treats routes with overlapping exposure intervals as
+	// ties when selecting the least-exposure route. Off by default: route
+	// intervals are wide, so enabling it mostly selects the fastest route.
+	LeapOverlapAsTie bool
+
+	// ModelTimeout bounds a single call to a remote PM2.5 model
+	ModelTimeout time.Duration
+	// ShadowWorkers caps concurrent shadow model evaluations; predictions
+	// beyond it are not shadowed
+	ShadowWorkers int
+	// FallbackDiurnalProfile holds 24 hourly multipliers applied to the WAQI
+	// daily forecast when the PM2.5 model is unavailable. Nil disables it.
+	FallbackDiurnalProfile []float64
+
+	// MinSampleCoverage is the minimum share of air-quality samples along a
+	// route that must succeed for its exposure to be reported
+	MinSampleCoverage float64
+	// FailedSamplePolicy is "interpolate" to fill failed samples from their
+	// neighbours or "skip" to leave them out of the exposure sum
+	FailedSamplePolicy string
+
+	// MaxStationAge and MaxStationDistanceKm bound how old and how far from
+	// the sampled point a station reading may be. Zero disables a limit.
+	MaxStationAge        time.Duration
+	MaxStationDistanceKm float64
+	// StationLimitPolicy is "reject" to treat out-of-limit readings as failed
+	// samples or "downweight" to shrink them toward the route consensus
+	StationLimitPolicy string
+
+	// EVReserveSoC is the battery state of charge in percent below which an
+	// electric vehicle route is flagged
+	EVReserveSoC float64
+
+	// ChargingStationsFile is an Open Charge Map JSON export or CSV file of
+	// charging stations used to plan EV charging stops
+	ChargingStationsFile string
+	// EVChargeTargetSoC is the state of charge in percent a planned stop
+	// charges to, EVChargerMaxDetourKm how far from the route a station may be
+	// and EVMaxChargingStops how many stops a plan may have
+	EVChargeTargetSoC    float64
+	EVChargerMaxDetourKm float64
+	EVMaxChargingStops   int
+
+	// ElevationDEMDir holds SRTM .hgt or GeoTIFF elevation tiles used for
+	// the energy of Mapbox routes; at most ElevationTileCache decoded tiles
+	// are kept in memory
+	ElevationDEMDir    string
+	ElevationTileCache int
+
+	// IdlingExposureFactor weighs the seconds spent idling in congestion when
+	// summing route exposure
+	IdlingExposureFactor float64
+
+	// PricingFile is a JSON or YAML table of regional fuel and electricity
+	// prices replacing the built-in one, TollZonesFile a GeoJSON file of
+	// toll and congestion-charge zones
+	PricingFile   string
+	TollZonesFile string
+
+	// AirQualityProviders is the default order in which air-quality
+	// providers are tried, AirQualityRegionsFile a JSON or YAML file of
+	// regions with their own order
+	AirQualityProviders   []string
+	AirQualityRegionsFile string
+	OpenAQAPIKey          string
+	CPCBAPIKey            string
+
+	// WeatherProviders is the order in which weather providers are tried,
+	// OpenMeteoURL the base URL of a self-hosted Open-Meteo instance
+	WeatherProviders []string
+	OpenMeteoURL     string
+
+	// ObservationAPIKeys authenticate listing the sensors; sensors post
+	// observations with the API key registered for them.
+	// ObservationSensorsFile persists registered sensors. Readings older than
+	// ObservationRetention or more than ObservationMaxClockSkew in the future
+	// are rejected. ObservationBlendWeight weighs community sensors against a
+	// reference station when blending (0 disables blending).
+	ObservationAPIKeys      []string
+	ObservationSensorsFile  string
+	ObservationRetention    time.Duration
+	ObservationMaxClockSkew time.Duration
+	ObservationBlendWeight  float64
+
+	// MQTTBrokerURL enables the MQTT subscriber feeding the observation
+	// store. MQTTTopics are read as JSON at MQTTQoS; MQTTTopicsFile holds
+	// per-topic rules.
+	MQTTBrokerURL  string
+	MQTTClientID   string
+	MQTTUsername   string
+	MQTTPassword   string
+	MQTTTopics     []string
+	MQTTTopicsFile string
+	MQTTQoS        byte
+
+	// BackgroundGridDir holds gridded concentration fields (NetCDF, GeoTIFF
+	// or CSV) used where station coverage is sparse. Frames further than
+	// BackgroundMaxTimeGap from the requested time are ignored; the directory
+	// is rescanned every BackgroundRescanInterval.
+	BackgroundGridDir        string
+	BackgroundMaxTimeGap     time.Duration
+	BackgroundRescanInterval time.Duration
+
+	// HistoryDir holds the embedded database recording every fetched
+	// air-quality and weather reading; readings older than HistoryRetention
+	// are deleted
+	HistoryDir       string
+	HistoryRetention time.Duration
+
+	// Air-quality and weather provider lookups are cached per geohash cell
+	// of the given precision for TTL-long time buckets (a TTL of 0 disables
+	// the cache). CachePersistFile keeps the caches across restarts.
+	AirQualityCachePrecision int
+	AirQualityCacheTTL       time.Duration
+	WeatherCachePrecision    int
+	WeatherCacheTTL          time.Duration
+	CacheMaxEntries          int
+	CachePersistFile         string
+	CachePersistInterval     time.Duration
+
+	// ExposureWorkers is how many route samples are looked up at once.
+	// ProviderWorkers caps concurrent calls per upstream provider, falling
+	// back to ProviderWorkersDefault.
+	ExposureWorkers        int
+	ProviderWorkers        map[string]int
+	ProviderWorkersDefault int
+
+	// Upstream HTTP calls time out after OutboundTimeout, or the provider's
+	// OutboundTimeouts entry. Idempotent calls are retried OutboundRetries
+	// times with jittered exponential backoff from OutboundBackoff up to
+	// OutboundBackoffMax. A provider's circuit breaker opens for
+	// BreakerCooldown once BreakerErrorRate of its last BreakerWindow calls
+	// (and at least BreakerMinCalls) failed.
+	OutboundTimeout    time.Duration
+	OutboundTimeouts   map[string]time.Duration
+	OutboundRetries    int
+	OutboundBackoff    time.Duration
+	OutboundBackoffMax time.Duration
+	BreakerWindow      int
+	BreakerMinCalls    int
+	BreakerErrorRate   float64
+	BreakerCooldown    time.Duration
+
+	// QuotasFile holds per-provider rate limits and daily/monthly quotas.
+	// Usage counts are saved to QuotaUsageFile every QuotaUsageSaveInterval.
+	// A call waits up to QuotaMaxWait for a rate-limit token, and threshold
+	// alerts are also posted to QuotaAlertWebhook.
+	QuotasFile             string
+	QuotaUsageFile         string
+	QuotaUsageSaveInterval time.Duration
+	QuotaMaxWait           time.Duration
+	QuotaAlertWebhook      string
+}
+
+// defaultPredictionErrorByHorizon holds the backtested RMSE of the PM2.5
+// pipeline for each delay code. Horizon 0 is the error of using the nearest
+// station reading as the concentration along the route.
+var defaultPredictionErrorByHorizon = map[uint8]float64{
+	0: 6.0,
+	1: 9.0,
+	2: 11.0,
+	3: 12.5,
+	4: 14.0,
+	5: 15.0,
+	6: 16.0,
+}
+
+// defaultDiurnalProfile is a typical urban PM2.5 daily cycle (local hours
+// 0-23) with morning and late-evening peaks and an afternoon minimum
+var defaultDiurnalProfile = []float64{
+	1.10, 1.08, 1.05, 1.02, 1.00, 1.02, 1.08, 1.18,
+	1.25, 1.20, 1.05, 0.92, 0.84, 0.78, 0.75, 0.74,
+	0.78, 0.86, 0.98, 1.10, 1.18, 1.20, 1.17, 1.13,
 }
 
 var AppConfig *Config
@@ -37,7 +226,112 @@ func Init() error {
 		WAQIAPIKey:        getEnvVar("WAQI_API_KEY"),
 		OpenWeatherAPIKey: getEnvVar("OPEN_WEATHER_API_KEY"),
 		AWSModelEndpoint:  getEnvVar("AWS_MODEL_ENDPOINT"),
+		ModelRegistryFile: getEnvVar("MODEL_REGISTRY_FILE"),
 		IsRailway:         os.Getenv("RAILWAY") == "true",
+
+		AdminAPIKeys: parseList(getEnvVar("ADMIN_API_KEYS"), nil),
+
+		VehicleProfilesFile: getEnvVar("VEHICLE_PROFILES_FILE"),
+
+		PredictionErrorByHorizon: parseHorizonErrors(getEnvVar("PREDICTION_ERROR_BY_HORIZON")),
+		LeapOverlapAsTie:         getEnvVar("LEAP_OVERLAP_AS_TIE") == "true",
+
+		ModelTimeout:           parseSeconds(getEnvVar("MODEL_TIMEOUT_SECONDS"), 10*time.Second),
+		ShadowWorkers:          int(parseFloat(getEnvVar("SHADOW_WORKERS"), 4)),
+		FallbackDiurnalProfile: parseDiurnalProfile(getEnvVar("FALLBACK_DIURNAL_PROFILE")),
+
+		MinSampleCoverage:  parseFloat(getEnvVar("MIN_SAMPLE_COVERAGE"), 0.5),
+		FailedSamplePolicy: getEnvVar("FAILED_SAMPLE_POLICY"),
+
+		MaxStationAge:        time.Duration(parseFloat(getEnvVar("MAX_STATION_AGE_MINUTES"), 180) * float64(time.Minute)),
+		MaxStationDistanceKm: parseFloat(getEnvVar("MAX_STATION_DISTANCE_KM"), 25),
+		StationLimitPolicy:   getEnvVar("STATION_LIMIT_POLICY"),
+
+		EVReserveSoC:         parseFloat(getEnvVar("EV_RESERVE_SOC"), 10),
+		ChargingStationsFile: getEnvVar("CHARGING_STATIONS_FILE"),
+
+		ElevationDEMDir:    getEnvVar("ELEVATION_DEM_DIR"),
+		ElevationTileCache: int(parseFloat(getEnvVar("ELEVATION_TILE_CACHE"), 16)),
+
+		IdlingExposureFactor: parseFloat(getEnvVar("IDLING_EXPOSURE_FACTOR"), 1.5),
+
+		EVChargeTargetSoC:    parseFloat(getEnvVar("EV_CHARGE_TARGET_SOC"), 80),
+		EVChargerMaxDetourKm: parseFloat(getEnvVar("EV_CHARGER_MAX_DETOUR_KM"), 5),
+		EVMaxChargingStops:   int(parseFloat(getEnvVar("EV_MAX_CHARGING_STOPS"), 5)),
+
+		PricingFile:   getEnvVar("PRICING_FILE"),
+		TollZonesFile: getEnvVar("TOLL_ZONES_FILE"),
+
+		AirQualityProviders:   parseList(getEnvVar("AIR_QUALITY_PROVIDERS"), []string{"waqi"}),
+		AirQualityRegionsFile: getEnvVar("AIR_QUALITY_REGIONS_FILE"),
+		OpenAQAPIKey:          getEnvVar("OPENAQ_API_KEY"),
+		CPCBAPIKey:            getEnvVar("CPCB_API_KEY"),
+
+		WeatherProviders: parseList(getEnvVar("WEATHER_PROVIDERS"), []string{"openweather", "open_meteo"}),
+		OpenMeteoURL:     getEnvVar("OPEN_METEO_URL"),
+
+		ObservationAPIKeys:      parseList(getEnvVar("OBSERVATION_API_KEYS"), nil),
+		ObservationSensorsFile:  getEnvVar("OBSERVATION_SENSORS_FILE"),
+		ObservationRetention:    time.Duration(parseFloat(getEnvVar("OBSERVATION_RETENTION_HOURS"), 24) * float64(time.Hour)),
+		ObservationMaxClockSkew: parseSeconds(getEnvVar("OBSERVATION_MAX_CLOCK_SKEW_SECONDS"), 5*time.Minute),
+		ObservationBlendWeight:  parseFloat(getEnvVar("OBSERVATION_BLEND_WEIGHT"), 0.5),
+
+		MQTTBrokerURL:  getEnvVar("MQTT_BROKER_URL"),
+		MQTTClientID:   getEnvVar("MQTT_CLIENT_ID"),
+		MQTTUsername:   getEnvVar("MQTT_USERNAME"),
+		MQTTPassword:   getEnvVar("MQTT_PASSWORD"),
+		MQTTTopics:     parseList(getEnvVar("MQTT_TOPICS"), nil),
+		MQTTTopicsFile: getEnvVar("MQTT_TOPICS_FILE"),
+
+		BackgroundGridDir:        getEnvVar("BACKGROUND_GRID_DIR"),
+		BackgroundMaxTimeGap:     time.Duration(parseFloat(getEnvVar("BACKGROUND_MAX_TIME_GAP_HOURS"), 3) * float64(time.Hour)),
+		BackgroundRescanInterval: time.Duration(parseFloat(getEnvVar("BACKGROUND_RESCAN_MINUTES"), 10) * float64(time.Minute)),
+
+		HistoryDir:       getEnvVar("HISTORY_DIR"),
+		HistoryRetention: time.Duration(parseFloat(getEnvVar("HISTORY_RETENTION_DAYS"), 90) * float64(24*time.Hour)),
+
+		AirQualityCachePrecision: int(parseFloat(getEnvVar("AIR_QUALITY_CACHE_PRECISION"), 6)),
+		AirQualityCacheTTL:       time.Duration(parseFloat(getEnvVar("AIR_QUALITY_CACHE_TTL_MINUTES"), 15) * float64(time.Minute)),
+		WeatherCachePrecision:    int(parseFloat(getEnvVar("WEATHER_CACHE_PRECISION"), 5)),
+		WeatherCacheTTL:          time.Duration(parseFloat(getEnvVar("WEATHER_CACHE_TTL_MINUTES"), 30) * float64(time.Minute)),
+		CacheMaxEntries:          int(parseFloat(getEnvVar("CACHE_MAX_ENTRIES"), 10000)),
+		CachePersistFile:         getEnvVar("CACHE_PERSIST_FILE"),
+		CachePersistInterval:     parseSeconds(getEnvVar("CACHE_PERSIST_SECONDS"), time.Minute),
+
+		ExposureWorkers:        int(parseFloat(getEnvVar("EXPOSURE_WORKERS"), 8)),
+		ProviderWorkers:        parseWorkerLimits(getEnvVar("PROVIDER_WORKERS")),
+		ProviderWorkersDefault: int(parseFloat(getEnvVar("PROVIDER_WORKERS_DEFAULT"), 8)),
+
+		OutboundTimeout:    parseSeconds(getEnvVar("OUTBOUND_TIMEOUT_SECONDS"), 10*time.Second),
+		OutboundTimeouts:   parseProviderSeconds(getEnvVar("OUTBOUND_TIMEOUTS")),
+		OutboundRetries:    int(parseFloat(getEnvVar("OUTBOUND_RETRIES"), 2)),
+		OutboundBackoff:    time.Duration(parseFloat(getEnvVar("OUTBOUND_BACKOFF_MS"), 200) * float64(time.Millisecond)),
+		OutboundBackoffMax: time.Duration(parseFloat(getEnvVar("OUTBOUND_BACKOFF_MAX_MS"), 2000) * float64(time.Millisecond)),
+
+		BreakerWindow:    int(parseFloat(getEnvVar("BREAKER_WINDOW"), 20)),
+		BreakerMinCalls:  int(parseFloat(getEnvVar("BREAKER_MIN_CALLS"), 10)),
+		BreakerErrorRate: parseFloat(getEnvVar("BREAKER_ERROR_RATE"), 0.5),
+		BreakerCooldown:  parseSeconds(getEnvVar("BREAKER_COOLDOWN_SECONDS"), 30*time.Second),
+
+		QuotasFile:             getEnvVar("QUOTAS_FILE"),
+		QuotaUsageFile:         getEnvVar("QUOTA_USAGE_FILE"),
+		QuotaUsageSaveInterval: parseSeconds(getEnvVar("QUOTA_USAGE_SAVE_SECONDS"), 30*time.Second),
+		QuotaMaxWait:           time.Duration(parseFloat(getEnvVar("QUOTA_MAX_WAIT_MS"), 1000) * float64(time.Millisecond)),
+		QuotaAlertWebhook:      getEnvVar("QUOTA_ALERT_WEBHOOK"),
+	}
+
+	if AppConfig.StationLimitPolicy != "downweight" {
+		AppConfig.StationLimitPolicy = "reject"
+	}
+	if AppConfig.MQTTClientID == "" {
+		AppConfig.MQTTClientID = "clean-route-service"
+	}
+	AppConfig.MQTTQoS = 1
+	if qos := parseFloat(getEnvVar("MQTT_QOS"), 1); qos >= 0 && qos <= 2 {
+		AppConfig.MQTTQoS = byte(qos)
+	}
+	if AppConfig.FailedSamplePolicy != "skip" {
+		AppConfig.FailedSamplePolicy = "interpolate"
 	}
 
 	return nil
@@ -54,3 +348,132 @@ func getEnvVar(key string) string {
 	}
 	return ""
 }
+
+// parseHorizonErrors parses "delayCode:rmse" pairs such as "0:6,1:9.5" on top
+// of the built-in defaults
+func parseHorizonErrors(value string) map[uint8]float64 {
+	errorsByHorizon := make(map[uint8]float64, len(defaultPredictionErrorByHorizon))
+	for horizon, rmse := range defaultPredictionErrorByHorizon {
+		errorsByHorizon[horizon] = rmse
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		horizon, err := strconv.ParseUint(parts[0], 10, 8)
+		if err != nil {
+			continue
+		}
+		rmse, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || rmse < 0 {
+			continue
+		}
+		errorsByHorizon[uint8(horizon)] = rmse
+	}
+
+	return errorsByHorizon
+}
+
+// parseFloat parses a float with a fallback for empty or invalid values
+func parseFloat(value string, fallback float64) float64 {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+// parseSeconds parses a duration given in (fractional) seconds
+func parseSeconds(value string, fallback time.Duration) time.Duration {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		return fallback
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// parseDiurnalProfile accepts "off", 24 comma-separated multipliers, or an
+// empty value for the default profile. Multipliers are normalized to a mean of 1.
+func parseDiurnalProfile(value string) []float64 {
+	if value == "off" {
+		return nil
+	}
+
+	profile := defaultDiurnalProfile
+	if parts := strings.Split(value, ","); len(parts) == 24 {
+		custom := make([]float64, 0, 24)
+		for _, part := range parts {
+			multiplier, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || multiplier < 0 {
+				custom = nil
+				break
+			}
+			custom = append(custom, multiplier)
+		}
+		if custom != nil {
+			profile = custom
+		}
+	}
+
+	var sum float64
+	for _, multiplier := range profile {
+		sum += multiplier
+	}
+	if sum == 0 {
+		return nil
+	}
+
+	normalized := make([]float64, len(profile))
+	for i, multiplier := range profile {
+		normalized[i] = multiplier * float64(len(profile)) / sum
+	}
+	return normalized
+}
+
+// parseWorkerLimits parses comma-separated provider=count pairs such as
+// "waqi=4,openweather=2", ignoring malformed entries
+func parseWorkerLimits(value string) map[string]int {
+	limits := map[string]int{}
+	for _, item := range parseList(value, nil) {
+		name, count, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(count)); err == nil && n > 0 {
+			limits[strings.TrimSpace(name)] = n
+		}
+	}
+	return limits
+}
+
+// parseProviderSeconds parses comma-separated provider=seconds pairs such as
+// "mapbox=15,graphhopper=20", ignoring malformed entries
+func parseProviderSeconds(value string) map[string]time.Duration {
+	durations := map[string]time.Duration{}
+	for _, item := range parseList(value, nil) {
+		name, seconds, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		if d := parseSeconds(strings.TrimSpace(seconds), 0); d > 0 {
+			durations[strings.TrimSpace(name)] = d
+		}
+	}
+	return durations
+}
+
+// parseList parses a comma-separated list, falling back when it is empty
+func parseList(value string, fallback []string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return fallback
+	}
+	return list
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseDiurnalProfile(t *testing.T) {
+	ramp := make([]string, 24)
+	for hour := range ramp {
+		ramp[hour] = "1"
+	}
+	ramp[0] = "25"
+	scale := 24 / sum(defaultDiurnalProfile)
+	defaults := []float64{defaultDiurnalProfile[0] * scale, defaultDiurnalProfile[1] * scale}
+
+	tests := []struct {
+		name  string
+		value string
+		want  []float64 // hours 0 and 1 after normalization
+	}{
+		{"default", "", defaults},
+		{"custom 24 hours", strings.Join(ramp, ","), []float64{12.5, 0.5}},
+		{"wrong number of hours", "2,1,1", defaults},
+		{"negative multiplier", "-1" + strings.Repeat(",1", 23), defaults},
+		{"off", "off", nil},
+		{"all zero", "0" + strings.Repeat(",0", 23), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := parseDiurnalProfile(tt.value)
+			if tt.want == nil {
+				if profile != nil {
+					t.Fatalf("profile %v, want none", profile)
+				}
+				return
+			}
+			if len(profile) != 24 {
+				t.Fatalf("profile has %d hours, want 24", len(profile))
+			}
+			if mean := sum(profile) / 24; math.Abs(mean-1) > 1e-9 {
+				t.Errorf("profile mean %g, want 1", mean)
+			}
+			for hour, want := range tt.want {
+				if math.Abs(profile[hour]-want) > 1e-9 {
+					t.Errorf("hour %d multiplier %g, want %g", hour, profile[hour], want)
+				}
+			}
+		})
+	}
+}
+
+func sum(values []float64) float64 {
+	var total float64
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
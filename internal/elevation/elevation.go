@@ -0,0 +1,190 @@
+package elevation
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/raster"
+)
+
+// tile is a loaded elevation raster
+type tile interface {
+	// elevation returns the interpolated elevation in meters at a point, or
+	// false if the point is outside the tile or has no data
+	elevation(lon, lat float64) (float64, bool)
+}
+
+var (
+	mu sync.RWMutex
+
+	// hgtFiles maps an SRTM tile name such as N12E077 to its file
+	hgtFiles map[string]string
+	// geotiffs are the GeoTIFF rasters in the DEM directory, loaded on first use
+	geotiffs []*raster.GeoTIFF
+
+	// tilesMu guards the cache of decoded tiles, which keeps the
+	// ELEVATION_TILE_CACHE most recently used ones
+	tilesMu sync.Mutex
+	tiles   = map[string]*list.Element{}
+	lru     = list.New()
+)
+
+// cachedTile is a tile decoded once, by the first lookup that needs it
+type cachedTile struct {
+	path string
+	once sync.Once
+	// tile is nil for a file that failed to load
+	tile tile
+}
+
+// Init indexes the SRTM .hgt and GeoTIFF files in ELEVATION_DEM_DIR. Tiles are
+// only decoded when a route first needs them.
+func Init() error {
+	dir := config.AppConfig.ElevationDEMDir
+	if dir == "" {
+		logger.Info("No elevation model configured")
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error reading elevation directory %s: %w", dir, err)
+	}
+
+	hgt := map[string]string{}
+	var tiffs []*raster.GeoTIFF
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		switch ext {
+		case ".hgt":
+			hgt[strings.ToUpper(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))] = path
+		case ".tif", ".tiff":
+			file, err := raster.OpenGeoTIFF(path)
+			if err != nil {
+				logger.Warn("Skipping unreadable GeoTIFF elevation file",
+					"file", path,
+					"error", err.Error(),
+				)
+				continue
+			}
+			tiffs = append(tiffs, file)
+		}
+	}
+
+	mu.Lock()
+	hgtFiles = hgt
+	geotiffs = tiffs
+	mu.Unlock()
+
+	tilesMu.Lock()
+	tiles = map[string]*list.Element{}
+	lru.Init()
+	tilesMu.Unlock()
+
+	logger.Info("Elevation model indexed",
+		"directory", dir,
+		"hgt_tiles", len(hgt),
+		"geotiff_files", len(tiffs),
+	)
+	return nil
+}
+
+// Lookup returns the elevation in meters at a point. ok is false when no
+// loaded tile covers the point.
+func Lookup(lon, lat float64) (float64, bool) {
+	mu.RLock()
+	path, hasHGT := hgtFiles[hgtTileName(lon, lat)]
+	tiffs := geotiffs
+	mu.RUnlock()
+
+	if hasHGT {
+		if t := loadTile(path, func() (tile, error) { return loadHGT(path) }); t != nil {
+			if value, ok := t.elevation(lon, lat); ok {
+				return value, true
+			}
+		}
+	}
+
+	for _, file := range tiffs {
+		if !file.Contains(lon, lat) {
+			continue
+		}
+		if t := loadTile(file.Path, loadGeoTIFF(file)); t != nil {
+			if value, ok := t.elevation(lon, lat); ok {
+				return value, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// loadTile returns the cached tile for a path, decoding it on first use.
+// Concurrent lookups of a tile being decoded wait for it; lookups of other
+// tiles do not. The least recently used tiles beyond ELEVATION_TILE_CACHE are
+// evicted.
+func loadTile(path string, load func() (tile, error)) tile {
+	tilesMu.Lock()
+	element, ok := tiles[path]
+	if ok {
+		lru.MoveToFront(element)
+	} else {
+		element = lru.PushFront(&cachedTile{path: path})
+		tiles[path] = element
+		for lru.Len() > tileCacheSize() {
+			oldest := lru.Back()
+			lru.Remove(oldest)
+			delete(tiles, oldest.Value.(*cachedTile).path)
+		}
+	}
+	cached := element.Value.(*cachedTile)
+	tilesMu.Unlock()
+
+	cached.once.Do(func() {
+		t, err := load()
+		if err != nil {
+			logger.Error("Failed to load elevation tile",
+				"file", path,
+				"error", err.Error(),
+			)
+			return
+		}
+		cached.tile = t
+	})
+	return cached.tile
+}
+
+func tileCacheSize() int {
+	if n := config.AppConfig.ElevationTileCache; n > 0 {
+		return n
+	}
+	return 1
+}
+
+// geotiffTile is a decoded GeoTIFF elevation raster
+type geotiffTile struct {
+	*raster.Raster
+}
+
+func (t geotiffTile) elevation(lon, lat float64) (float64, bool) {
+	return t.Value(lon, lat)
+}
+
+func loadGeoTIFF(file *raster.GeoTIFF) func() (tile, error) {
+	return func() (tile, error) {
+		decoded, err := file.Load()
+		if err != nil {
+			return nil, err
+		}
+		return geotiffTile{decoded}, nil
+	}
+}
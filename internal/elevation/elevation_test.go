@@ -0,0 +1,114 @@
+package elevation
+
+import (
+	"container/list"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/clean-route/go-backend/internal/config"
+)
+
+// constTile is a tile with the same elevation everywhere
+type constTile float64
+
+func (t constTile) elevation(lon, lat float64) (float64, bool) { return float64(t), true }
+
+func resetTiles(t *testing.T, capacity int) {
+	t.Helper()
+	config.AppConfig = &config.Config{ElevationTileCache: capacity}
+	tilesMu.Lock()
+	tiles = map[string]*list.Element{}
+	lru.Init()
+	tilesMu.Unlock()
+}
+
+func TestLookupHGT(t *testing.T) {
+	dir := t.TempDir()
+	// A 3x3 tile: rows run north to south, the centre is a void
+	samples := []int16{
+		100, 200, 300,
+		100, hgt_void, 300,
+		100, 200, 300,
+	}
+	data := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.BigEndian.PutUint16(data[i*2:], uint16(sample))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "N12E077.hgt"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config.AppConfig = &config.Config{ElevationDEMDir: dir, ElevationTileCache: 4}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		lon, lat float64
+		want     float64
+		wantOK   bool
+	}{
+		{"western edge", 77, 12.75, 100, true},
+		{"next to the void", 77.25, 12.75, 400.0 / 3, true},
+		{"outside the tiles", 10, 10, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Lookup(tt.lon, tt.lat)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Lookup(%g, %g) = %g, %v, want %g, %v", tt.lon, tt.lat, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLoadTileOncePerPath(t *testing.T) {
+	resetTiles(t, 4)
+
+	var loads atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loadTile("a.hgt", func() (tile, error) {
+				loads.Add(1)
+				return constTile(1), nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("tile decoded %d times, want 1", n)
+	}
+}
+
+func TestLoadTileEvictsLeastRecentlyUsed(t *testing.T) {
+	resetTiles(t, 2)
+
+	loads := map[string]int{}
+	load := func(path string) {
+		loadTile(path, func() (tile, error) {
+			loads[path]++
+			return constTile(1), nil
+		})
+	}
+
+	load("a")
+	load("b")
+	load("a") // a is now the most recently used
+	load("c") // evicts b
+	load("a")
+	load("b")
+
+	if loads["a"] != 1 || loads["b"] != 2 || loads["c"] != 1 {
+		t.Errorf("decodes %v, want a:1 b:2 c:1", loads)
+	}
+}
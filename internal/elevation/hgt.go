@@ -0,0 +1,110 @@
+package elevation
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/clean-route/go-backend/internal/raster"
+)
+
+// hgt_void marks SRTM samples without data
+const hgt_void = -32768
+
+// hgtTile is an SRTM tile covering one degree of latitude and longitude,
+// whose south-west corner is at (lon, lat)
+type hgtTile struct {
+	lon, lat int
+	size     int
+	samples  []int16
+}
+
+// hgtTileName returns the SRTM name of the tile containing a point, e.g. N12E077
+func hgtTileName(lon, lat float64) string {
+	latBase := int(math.Floor(lat))
+	lonBase := int(math.Floor(lon))
+
+	latPrefix, lonPrefix := "N", "E"
+	if latBase < 0 {
+		latPrefix = "S"
+	}
+	if lonBase < 0 {
+		lonPrefix = "W"
+	}
+	return fmt.Sprintf("%s%02d%s%03d", latPrefix, abs(latBase), lonPrefix, abs(lonBase))
+}
+
+// loadHGT reads an SRTM1 (3601x3601) or SRTM3 (1201x1201) .hgt file
+func loadHGT(path string) (tile, error) {
+	name := strings.ToUpper(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	lon, lat, err := parseHGTName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	size := int(math.Sqrt(float64(len(data) / 2)))
+	if size < 2 || size*size*2 != len(data) {
+		return nil, fmt.Errorf("%s is not a square SRTM tile", path)
+	}
+
+	samples := make([]int16, size*size)
+	for i := range samples {
+		samples[i] = int16(binary.BigEndian.Uint16(data[i*2:]))
+	}
+
+	return &hgtTile{lon: lon, lat: lat, size: size, samples: samples}, nil
+}
+
+func parseHGTName(name string) (lon int, lat int, err error) {
+	if len(name) != 7 || !strings.ContainsAny(name[:1], "NS") || !strings.ContainsAny(name[3:4], "EW") {
+		return 0, 0, fmt.Errorf("invalid SRTM tile name %s", name)
+	}
+
+	lat, err = strconv.Atoi(name[1:3])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid SRTM tile name %s", name)
+	}
+	lon, err = strconv.Atoi(name[4:7])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid SRTM tile name %s", name)
+	}
+
+	if name[0] == 'S' {
+		lat = -lat
+	}
+	if name[3] == 'W' {
+		lon = -lon
+	}
+	return lon, lat, nil
+}
+
+func (t *hgtTile) elevation(lon, lat float64) (float64, bool) {
+	// Rows run from the northern edge southwards; samples lie on the edges
+	cells := float64(t.size - 1)
+	col := (lon - float64(t.lon)) * cells
+	row := (float64(t.lat+1) - lat) * cells
+
+	return raster.Bilinear(col, row, t.size, t.size, func(x, y int) (float64, bool) {
+		value := t.samples[y*t.size+x]
+		if value == hgt_void {
+			return 0, false
+		}
+		return float64(value), true
+	})
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
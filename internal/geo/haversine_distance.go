@@ -0,0 +1,17 @@
+package geo
+
+import "math"
+
+const earth_radius = 6371000.0 // in meters
+
+// HaversineDistance returns the great-circle distance in meters between two points
+func HaversineDistance(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	deltaPhi := (lat2 - lat1) * math.Pi / 180
+	deltaLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+	return 2 * earth_radius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,22 @@
+package geo
+
+// RingContains is the even-odd ray casting test of a point against a ring of
+// [longitude, latitude] points. The ring may or may not repeat its first point.
+func RingContains(ring [][2]float64, point [2]float64) bool {
+	if len(ring) < 3 {
+		return false
+	}
+
+	inside := false
+	j := len(ring) - 1
+	for i := 0; i < len(ring); i++ {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > point[1]) != (yj > point[1]) &&
+			point[0] < (xj-xi)*(point[1]-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+		j = i
+	}
+	return inside
+}
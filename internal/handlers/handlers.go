@@ -1,15 +1,31 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/clean-route/go-backend/internal/airquality"
+	"github.com/clean-route/go-backend/internal/cache"
 	"github.com/clean-route/go-backend/internal/errors"
+	"github.com/clean-route/go-backend/internal/history"
 	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/middleware"
 	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/observations"
+	"github.com/clean-route/go-backend/internal/outbound"
+	"github.com/clean-route/go-backend/internal/predictor"
+	"github.com/clean-route/go-backend/internal/pricing"
 	"github.com/clean-route/go-backend/internal/services"
+	"github.com/clean-route/go-backend/internal/vehicles"
+	"github.com/clean-route/go-backend/internal/weather"
 )
 
 var routeService = services.NewRouteService()
@@ -37,6 +53,11 @@ func FindRoute(c *gin.Context) {
 	)
 
 	result, err := routeService.FindSingleRoute(req)
+	if appErr := errors.GetAppError(err); appErr != nil && appErr.Type == errors.ErrorTypeValidation {
+		// The service validates the vehicle profile before routing
+		c.Error(appErr)
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to find single route",
 			"error", err.Error(),
@@ -82,6 +103,11 @@ func FindAllRoutes(c *gin.Context) {
 	)
 
 	result, err := routeService.FindAllRoutes(req)
+	if appErr := errors.GetAppError(err); appErr != nil && appErr.Type == errors.ErrorTypeValidation {
+		// The service validates the vehicle profile before routing
+		c.Error(appErr)
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to find all routes",
 			"error", err.Error(),
@@ -104,6 +130,49 @@ func FindAllRoutes(c *gin.Context) {
 	})
 }
 
+// CompareModes handles requests comparing the best route of several modes
+func CompareModes(c *gin.Context) {
+	var req models.CompareModesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid request format for CompareModes",
+			"error", err.Error(),
+			"request_id", c.GetString("request_id"),
+		)
+
+		appErr := errors.NewValidationError("Invalid request format", err)
+		c.Error(appErr)
+		return
+	}
+
+	logger.Info("Processing mode comparison request",
+		"request_id", c.GetString("request_id"),
+		"source", req.Source,
+		"destination", req.Destination,
+		"modes", req.Modes,
+		"route_preference", req.RoutePreference,
+	)
+
+	result, err := routeService.CompareModes(req)
+	if err != nil {
+		logger.Warn("Failed to compare modes",
+			"error", err.Error(),
+			"request_id", c.GetString("request_id"),
+		)
+		c.Error(err)
+		return
+	}
+
+	logger.Info("Successfully compared modes",
+		"request_id", c.GetString("request_id"),
+		"recommended_mode", result.RecommendedMode,
+	)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
 // GetWeatherData handles weather data requests
 func GetWeatherData(c *gin.Context) {
 	latStr := c.Query("lat")
@@ -153,16 +222,29 @@ func GetWeatherData(c *gin.Context) {
 		"lon", lon,
 	)
 
-	location := []float64{lon, lat}
-	weatherData := services.FetchWeatherData(location)
+	forecast, failed, err := weather.Fetch([2]float64{lon, lat})
+	if err != nil {
+		logger.Error("Failed to fetch weather data",
+			"error", err.Error(),
+			"request_id", c.GetString("request_id"),
+			"lat", lat,
+			"lon", lon,
+			"failed_providers", failed,
+		)
+
+		appErr := errors.NewExternalError("Failed to fetch weather data", err)
+		c.Error(appErr)
+		return
+	}
 
 	logger.Info("Successfully fetched weather data",
 		"request_id", c.GetString("request_id"),
+		"source", forecast.Source,
 	)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Data:    weatherData,
+		Data:    forecast,
 	})
 }
 
@@ -215,14 +297,14 @@ func GetAQIData(c *gin.Context) {
 		"lon", lon,
 	)
 
-	location := []float64{lon, lat}
-	aqiValue, err := services.FetchAQIData(location, 0) // Default delay code
+	reading, failed, err := airquality.Nearest([2]float64{lon, lat}, airquality.PollutantPM25)
 	if err != nil {
 		logger.Error("Failed to fetch AQI data",
 			"error", err.Error(),
 			"request_id", c.GetString("request_id"),
 			"lat", lat,
 			"lon", lon,
+			"failed_providers", failed,
 		)
 
 		appErr := errors.NewExternalError("Failed to fetch AQI data", err)
@@ -230,15 +312,20 @@ func GetAQIData(c *gin.Context) {
 		return
 	}
 
+	// Readings are concentrations; the AQI is derived for display
+	aqiValue, _ := airquality.ConcentrationToAQI(reading.Pollutant, reading.Concentration)
+
 	logger.Info("Successfully fetched AQI data",
 		"request_id", c.GetString("request_id"),
 		"aqi_value", aqiValue,
+		"source", reading.Source,
 	)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Data: gin.H{
-			"aqi": aqiValue,
+			"aqi":     aqiValue,
+			"reading": reading,
 		},
 	})
 }
@@ -260,9 +347,10 @@ func GetPredictedPM25(c *gin.Context) {
 	logger.Info("Processing PM2.5 prediction request",
 		"request_id", c.GetString("request_id"),
 		"features_count", len(req.Features),
+		"model", req.Model,
 	)
 
-	predictions, err := services.GetPredictedPm25(req.Features)
+	forecast, err := services.GetPredictedPm25(req.Features, req.Model)
 	if err != nil {
 		logger.Error("Failed to get PM2.5 predictions",
 			"error", err.Error(),
@@ -277,13 +365,395 @@ func GetPredictedPM25(c *gin.Context) {
 
 	logger.Info("Successfully generated PM2.5 predictions",
 		"request_id", c.GetString("request_id"),
-		"predictions_count", len(predictions),
+		"model", forecast.Model,
+		"predictions_count", len(forecast.Values),
+	)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"model":       forecast.Model,
+			"predictions": forecast.Values,
+			"intervals":   forecast.Intervals,
+		},
+	})
+}
+
+// GetModelMetrics reports live and shadow PM2.5 model metrics side by side
+func GetModelMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"models": predictor.Default.Metrics(),
+		},
+	})
+}
+
+// ReloadModels re-reads the model registry file so traffic shares and shadow
+// models can be changed without a restart
+func ReloadModels(c *gin.Context) {
+	if err := predictor.Reload(); err != nil {
+		logger.Error("Failed to reload model registry",
+			"error", err.Error(),
+			"request_id", c.GetString("request_id"),
+		)
+
+		appErr := errors.NewBadRequestError("Failed to reload model registry", err)
+		c.Error(appErr)
+		return
+	}
+
+	logger.Info("Reloaded model registry",
+		"request_id", c.GetString("request_id"),
+	)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"models": predictor.Default.Metrics(),
+		},
+	})
+}
+
+// GetVehicleProfiles lists the vehicle profiles that can be passed as
+// vehicle_profile in route requests
+func GetVehicleProfiles(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"profiles": vehicles.List(),
+		},
+	})
+}
+
+// GetPrices lists the regional fuel and electricity prices and the toll and
+// congestion-charge zones used for trip costs
+func GetPrices(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"prices": pricing.Prices(),
+			"zones":  pricing.Zones(),
+		},
+	})
+}
+
+// UpdatePrices replaces the regional prices until the next restart
+func UpdatePrices(c *gin.Context) {
+	var table pricing.PriceTable
+	if err := c.ShouldBindJSON(&table); err != nil {
+		logger.Error("Invalid request format for UpdatePrices",
+			"error", err.Error(),
+			"request_id", c.GetString("request_id"),
+		)
+
+		appErr := errors.NewValidationError("Invalid request format", err)
+		c.Error(appErr)
+		return
+	}
+
+	if err := pricing.SetPrices(table); err != nil {
+		logger.Warn("Invalid price table",
+			"error", err.Error(),
+			"request_id", c.GetString("request_id"),
+		)
+
+		appErr := errors.NewValidationError("Invalid price table", err)
+		c.Error(appErr)
+		return
+	}
+
+	logger.Info("Updated regional prices",
+		"request_id", c.GetString("request_id"),
+		"regions", len(table.Regions),
 	)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Data: gin.H{
-			"predictions": predictions,
+			"prices": pricing.Prices(),
+		},
+	})
+}
+
+const (
+	// maxObservationBatch bounds how many observations one request may carry
+	maxObservationBatch = 1000
+	// maxObservationBody bounds the size of an observation request body
+	maxObservationBody = 1 << 20
+)
+
+// IngestObservations stores a single community sensor observation, or a JSON
+// array of them. Every observation must be for a sensor registered with the
+// request's API key. A single observation that fails validation is an
+// error; a batch reports the observations it rejected.
+func IngestObservations(c *gin.Context) {
+	key := middleware.RequestAPIKey(c)
+	if key == "" {
+		c.Error(errors.NewUnauthorizedError("Missing or invalid API key", nil))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxObservationBody))
+	if err != nil {
+		message := "Failed to read request body"
+		if _, ok := err.(*http.MaxBytesError); ok {
+			message = fmt.Sprintf("Request body exceeds %d bytes", maxObservationBody)
+		}
+		appErr := errors.NewBadRequestError(message, err)
+		c.Error(appErr)
+		return
+	}
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || trimmed[0] != '[' {
+		var observation observations.Observation
+		if err := json.Unmarshal(body, &observation); err != nil {
+			logger.Error("Invalid request format for IngestObservations",
+				"error", err.Error(),
+				"request_id", c.GetString("request_id"),
+			)
+
+			appErr := errors.NewValidationError("Invalid request format", err)
+			c.Error(appErr)
+			return
+		}
+
+		if err := observations.Authorize(observation.SensorID, key); err != nil {
+			logger.Warn("Rejected observation for a sensor not bound to the API key",
+				"request_id", c.GetString("request_id"),
+				"sensor_id", observation.SensorID,
+			)
+
+			appErr := errors.NewUnauthorizedError("API key is not registered for the sensor", nil)
+			c.Error(appErr)
+			return
+		}
+
+		stored, err := observations.Add(observation)
+		if err != nil {
+			logger.Warn("Rejected observation",
+				"error", err.Error(),
+				"request_id", c.GetString("request_id"),
+				"sensor_id", observation.SensorID,
+			)
+
+			appErr := errors.NewValidationError("Invalid observation", err)
+			c.Error(appErr)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Data: gin.H{
+				"observation": stored,
+			},
+		})
+		return
+	}
+
+	var batch []observations.Observation
+	if err := json.Unmarshal(body, &batch); err != nil {
+		logger.Error("Invalid request format for IngestObservations",
+			"error", err.Error(),
+			"request_id", c.GetString("request_id"),
+		)
+
+		appErr := errors.NewValidationError("Invalid request format", err)
+		c.Error(appErr)
+		return
+	}
+	if len(batch) > maxObservationBatch {
+		appErr := errors.NewValidationError(fmt.Sprintf("A batch may carry at most %d observations", maxObservationBatch), nil)
+		c.Error(appErr)
+		return
+	}
+
+	accepted := 0
+	rejected := []models.RejectedObservation{}
+	for i, observation := range batch {
+		err := observations.Authorize(observation.SensorID, key)
+		if err == nil {
+			_, err = observations.Add(observation)
+		}
+		if err != nil {
+			rejected = append(rejected, models.RejectedObservation{
+				Index:    i,
+				SensorID: observation.SensorID,
+				Reason:   err.Error(),
+			})
+			continue
+		}
+		accepted++
+	}
+
+	logger.Info("Ingested observation batch",
+		"request_id", c.GetString("request_id"),
+		"accepted", accepted,
+		"rejected", len(rejected),
+	)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"accepted": accepted,
+			"rejected": rejected,
+		},
+	})
+}
+
+// RegisterSensor registers a community sensor or updates its location, name
+// and calibration
+func RegisterSensor(c *gin.Context) {
+	var sensor observations.Sensor
+	if err := c.ShouldBindJSON(&sensor); err != nil {
+		logger.Error("Invalid request format for RegisterSensor",
+			"error", err.Error(),
+			"request_id", c.GetString("request_id"),
+		)
+
+		appErr := errors.NewValidationError("Invalid request format", err)
+		c.Error(appErr)
+		return
+	}
+
+	registered, err := observations.RegisterSensor(sensor)
+	if err != nil {
+		logger.Warn("Invalid sensor registration",
+			"error", err.Error(),
+			"request_id", c.GetString("request_id"),
+			"sensor_id", sensor.ID,
+		)
+
+		appErr := errors.NewValidationError("Invalid sensor", err)
+		c.Error(appErr)
+		return
+	}
+
+	logger.Info("Registered sensor",
+		"request_id", c.GetString("request_id"),
+		"sensor_id", registered.ID,
+	)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"sensor": registered,
+		},
+	})
+}
+
+// GetSensors lists the registered community sensors and the state of the
+// MQTT subscriber
+func GetSensors(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"sensors": observations.Sensors(),
+			"mqtt":    observations.Stats(),
+		},
+	})
+}
+
+// GetHistory queries the recorded air-quality and weather readings by kind,
+// source, station or bounding box (minLon,minLat,maxLon,maxLat) over a time
+// range, which defaults to the last 24 hours
+func GetHistory(c *gin.Context) {
+	query := history.Query{
+		Kind:    c.Query("kind"),
+		Source:  c.Query("source"),
+		Station: c.Query("station"),
+		To:      time.Now(),
+		Limit:   1000,
+	}
+
+	switch query.Kind {
+	case "", history.KindAirQuality, history.KindWeather, history.KindObservation:
+	default:
+		c.Error(errors.NewValidationError("kind must be air_quality, weather or observation", nil))
+		return
+	}
+
+	if bbox := c.Query("bbox"); bbox != "" {
+		parts := strings.Split(bbox, ",")
+		if len(parts) != 4 {
+			c.Error(errors.NewValidationError("bbox must be minLon,minLat,maxLon,maxLat", nil))
+			return
+		}
+		var box [4]float64
+		for i, part := range parts {
+			value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				c.Error(errors.NewValidationError("Invalid bbox coordinate", err))
+				return
+			}
+			box[i] = value
+		}
+		query.BBox = &box
+	}
+
+	for name, target := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if value := c.Query(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.Error(errors.NewValidationError(fmt.Sprintf("Invalid %s time, expected RFC 3339", name), err))
+				return
+			}
+			*target = parsed
+		}
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-24 * time.Hour)
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > 10000 {
+			c.Error(errors.NewValidationError("limit must be between 1 and 10000", err))
+			return
+		}
+		query.Limit = limit
+	}
+
+	records, err := history.Find(query)
+	if err != nil {
+		logger.Error("Failed to query reading history",
+			"error", err.Error(),
+			"request_id", c.GetString("request_id"),
+		)
+		c.Error(errors.NewInternalError("Failed to query reading history", err))
+		return
+	}
+	if records == nil {
+		records = []history.Record{}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"records": records,
+			"count":   len(records),
+			"from":    query.From,
+			"to":      query.To,
+		},
+	})
+}
+
+// GetCacheStats reports the hit/miss statistics of the provider lookup caches
+func GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    gin.H{"caches": cache.AllStats()},
+	})
+}
+
+// GetUpstreams reports the circuit breaker state, call counts and quota usage
+// of every upstream provider client
+func GetUpstreams(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"healthy":   outbound.Healthy(),
+			"upstreams": outbound.Statuses(),
 		},
 	})
 }
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/middleware"
+	"github.com/clean-route/go-backend/internal/observations"
+)
+
+func TestIngestObservations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.AppConfig = &config.Config{ObservationMaxClockSkew: time.Minute}
+	if err := observations.Init(); err != nil {
+		t.Fatalf("observations.Init: %v", err)
+	}
+	for _, sensor := range []observations.Sensor{
+		{ID: "s1", Location: [2]float64{77.59, 12.97}, APIKey: "s1-secret"},
+		{ID: "s2", Location: [2]float64{77.60, 12.97}, APIKey: "s2-secret"},
+	} {
+		if _, err := observations.RegisterSensor(sensor); err != nil {
+			t.Fatalf("RegisterSensor: %v", err)
+		}
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	observation := func(sensorID string) string {
+		return `{"sensor_id": "` + sensorID + `", "pollutant": "pm25", "value": 20, "timestamp": "` + now + `"}`
+	}
+
+	tests := []struct {
+		name         string
+		key          string
+		body         string
+		want         int
+		wantAccepted int
+	}{
+		{"own sensor", "s1-secret", observation("s1"), http.StatusOK, 0},
+		{"another sensor", "s1-secret", observation("s2"), http.StatusUnauthorized, 0},
+		{"no key", "", observation("s1"), http.StatusUnauthorized, 0},
+		{"batch with another sensor", "s2-secret", "[" + observation("s2") + "," + observation("s1") + "]", http.StatusOK, 1},
+		{"body too large", "s1-secret", "[" + strings.Repeat(" ", maxObservationBody) + "]", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(middleware.ErrorResponseMiddleware())
+			router.POST("/observations", IngestObservations)
+
+			req := httptest.NewRequest(http.MethodPost, "/observations", strings.NewReader(tt.body))
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.wantAccepted > 0 {
+				var response struct {
+					Data struct {
+						Accepted int `json:"accepted"`
+					} `json:"data"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &response)
+				if response.Data.Accepted != tt.wantAccepted {
+					t.Errorf("accepted %d, want %d", response.Data.Accepted, tt.wantAccepted)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package history persists every air-quality and weather reading fetched from
+// upstream providers in an embedded bbolt database. Records are keyed by
+// measurement time and indexed by station, so time-range and station queries
+// read only the records they return, and retention deletes from the oldest
+// key. Writes are queued and committed in batches off the request path.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	KindAirQuality = "air_quality"
+	KindWeather    = "weather"
+	// KindObservation records are community sensor observations, kept so
+	// that the observation store survives restarts
+	KindObservation = "observation"
+)
+
+// Record is one reading fetched from a provider
+type Record struct {
+	Kind        string `json:"kind"`
+	Source      string `json:"source"`
+	StationID   string `json:"station_id,omitempty"`
+	StationName string `json:"station_name,omitempty"`
+	// Location is the station's [longitude, latitude], or the queried point
+	// for gridded providers without stations
+	Location [2]float64 `json:"location"`
+	// Timestamp is when the reading was measured; FetchedAt when it was
+	// received from the provider
+	Timestamp time.Time          `json:"timestamp"`
+	FetchedAt time.Time          `json:"fetched_at"`
+	Values    map[string]float64 `json:"values"`
+
+	// LookupKey identifies the lookup that fetched the reading, such as the
+	// pollutant and geohash cell of the queried point, and Payload is the
+	// provider's full answer; Recent returns the payload to repeats of the
+	// lookup. Neither is returned by Find.
+	LookupKey string          `json:"-"`
+	Payload   json.RawMessage `json:"-"`
+}
+
+// station identifies the station a record came from, falling back to its
+// location rounded to about 10 m
+func (r Record) station() string {
+	if r.StationID != "" {
+		return r.StationID
+	}
+	return fmt.Sprintf("%.4f,%.4f", r.Location[1], r.Location[0])
+}
+
+// Query selects records. Zero fields match everything; BBox is
+// [minLon, minLat, maxLon, maxLat].
+type Query struct {
+	Kind    string
+	Source  string
+	Station string
+	BBox    *[4]float64
+	From    time.Time
+	To      time.Time
+	// Limit keeps the most recent records
+	Limit int
+}
+
+func (q Query) matches(r Record) bool {
+	if q.Kind != "" && r.Kind != q.Kind {
+		return false
+	}
+	if q.Source != "" && r.Source != q.Source {
+		return false
+	}
+	if q.Station != "" && r.StationID != q.Station && r.station() != q.Station {
+		return false
+	}
+	if q.BBox != nil {
+		box := q.BBox
+		if r.Location[0] < box[0] || r.Location[1] < box[1] || r.Location[0] > box[2] || r.Location[1] > box[3] {
+			return false
+		}
+	}
+	if !q.From.IsZero() && r.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && r.Timestamp.After(q.To) {
+		return false
+	}
+	return true
+}
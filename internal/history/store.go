@@ -0,0 +1,481 @@
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+)
+
+const (
+	dbFile = "history.db"
+	// queueSize bounds the readings waiting to be written; readings arriving
+	// while the queue is full are dropped
+	queueSize = 4096
+	// maxBatch bounds the readings committed in one transaction
+	maxBatch = 256
+)
+
+// Buckets of the database. Record keys are the measurement time in Unix
+// nanoseconds followed by a sequence number, both big-endian, so that keys
+// sort by time.
+var (
+	// record key -> JSON record
+	recordsBucket = []byte("records")
+	// station, 0, record key -> nothing
+	stationsBucket = []byte("stations")
+	// record key -> provider payload
+	payloadsBucket = []byte("payloads")
+	// dedupe key -> record key of the latest record, so that repeated
+	// fetches of an unchanged reading are stored once
+	latestBucket = []byte("latest")
+	// kind and lookup key -> fetch time and record key
+	lookupsBucket = []byte("lookups")
+)
+
+// write is a queued record, or a flush request when flushed is set
+type write struct {
+	record  Record
+	flushed chan struct{}
+}
+
+var (
+	mu     sync.RWMutex
+	db     *bolt.DB
+	writes chan write
+	// writerDone is closed when the writer has committed the queue
+	writerDone chan struct{}
+)
+
+// Init opens the store in HISTORY_DIR, applies the retention policy and
+// starts the writer. Without HISTORY_DIR nothing is recorded.
+func Init() error {
+	path := config.AppConfig.HistoryDir
+	if path == "" {
+		logger.Info("No reading history store configured")
+		return nil
+	}
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return fmt.Errorf("error creating history directory %s: %w", path, err)
+	}
+	if err := Close(); err != nil {
+		return err
+	}
+
+	opened, err := bolt.Open(filepath.Join(path, dbFile), 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("error opening history store in %s: %w", path, err)
+	}
+	err = opened.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{recordsBucket, stationsBucket, payloadsBucket, latestBucket, lookupsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		opened.Close()
+		return fmt.Errorf("error creating history buckets: %w", err)
+	}
+
+	prune(opened, time.Now().UTC())
+
+	mu.Lock()
+	db = opened
+	writes = make(chan write, queueSize)
+	writerDone = make(chan struct{})
+	go writeLoop(opened, writes, writerDone)
+	mu.Unlock()
+
+	logger.Info("Reading history store ready",
+		"file", opened.Path(),
+		"retention_days", config.AppConfig.HistoryRetention.Hours()/24,
+	)
+	return nil
+}
+
+// Close writes the queued readings and closes the store
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if db == nil {
+		return nil
+	}
+	close(writes)
+	<-writerDone
+	err := db.Close()
+	db, writes, writerDone = nil, nil, nil
+	if err != nil {
+		return fmt.Errorf("error closing history store: %w", err)
+	}
+	return nil
+}
+
+func dedupeKey(r Record) string {
+	names := make([]string, 0, len(r.Values))
+	for name := range r.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return r.Kind + "|" + r.Source + "|" + r.station() + "|" + strings.Join(names, ",")
+}
+
+func lookupKey(kind, key string) []byte {
+	return []byte(kind + "|" + key)
+}
+
+func recordKey(at time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	nanos := at.UnixNano()
+	if nanos < 0 {
+		nanos = 0
+	}
+	binary.BigEndian.PutUint64(key, uint64(nanos))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func recordTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key[:8]))).UTC()
+}
+
+// upperKey is the largest record key at or before to; zero means no limit
+func upperKey(to time.Time) []byte {
+	if to.IsZero() {
+		return recordKey(time.Unix(0, math.MaxInt64), math.MaxUint64)
+	}
+	return recordKey(to, math.MaxUint64)
+}
+
+func stationPrefix(station string) []byte {
+	return append([]byte(station), 0)
+}
+
+// Add queues a fetched reading to be recorded unless the same reading was
+// already recorded. It never blocks: history must never slow down or break
+// the request that fetched the reading, so readings are dropped while the
+// queue is full.
+func Add(r Record) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if writes == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	if r.FetchedAt.IsZero() {
+		r.FetchedAt = now
+	}
+	if r.Timestamp.IsZero() {
+		r.Timestamp = r.FetchedAt
+	}
+	r.Timestamp = r.Timestamp.UTC()
+	if limit := config.AppConfig.HistoryRetention; limit > 0 && now.Sub(r.Timestamp) > limit {
+		return
+	}
+
+	select {
+	case writes <- write{record: r}:
+	default:
+		logger.Warn("Reading history queue is full, dropping reading",
+			"kind", r.Kind,
+			"source", r.Source,
+		)
+	}
+}
+
+// flush waits until the readings queued so far are written
+func flush() {
+	done := make(chan struct{})
+	mu.RLock()
+	if writes == nil {
+		mu.RUnlock()
+		return
+	}
+	writes <- write{flushed: done}
+	mu.RUnlock()
+	<-done
+}
+
+// writeLoop commits the queued readings in batches and applies the retention
+// policy once a day, until the queue is closed
+func writeLoop(store *bolt.DB, queue <-chan write, done chan<- struct{}) {
+	defer close(done)
+
+	// Init applied the retention policy today
+	pruned := time.Now().UTC().Format("2006-01-02")
+	for first := range queue {
+		batch := []write{first}
+	drain:
+		for len(batch) < maxBatch {
+			select {
+			case next, ok := <-queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+
+		err := store.Update(func(tx *bolt.Tx) error {
+			for _, w := range batch {
+				if w.flushed != nil {
+					continue
+				}
+				if err := put(tx, w.record); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Error("Failed to record reading history",
+				"readings", len(batch),
+				"error", err.Error(),
+			)
+		}
+		for _, w := range batch {
+			if w.flushed != nil {
+				close(w.flushed)
+			}
+		}
+
+		now := time.Now().UTC()
+		if day := now.Format("2006-01-02"); day != pruned {
+			pruned = day
+			prune(store, now)
+		}
+	}
+}
+
+// put stores a record unless its dedupe key already has a record at least as
+// recent, and points the record's lookup at the stored record
+func put(tx *bolt.Tx, r Record) error {
+	records := tx.Bucket(recordsBucket)
+	latest := tx.Bucket(latestBucket)
+
+	dedupe := []byte(dedupeKey(r))
+	key := latest.Get(dedupe)
+	newer := key == nil || recordTime(key).Before(r.Timestamp)
+	// Sensors push observations, possibly out of order, and the observation
+	// store rejects duplicates itself
+	if newer || r.Kind == KindObservation {
+		seq, err := records.NextSequence()
+		if err != nil {
+			return err
+		}
+		key = recordKey(r.Timestamp, seq)
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if err := records.Put(key, data); err != nil {
+			return err
+		}
+		if err := tx.Bucket(stationsBucket).Put(append(stationPrefix(r.station()), key...), nil); err != nil {
+			return err
+		}
+		if len(r.Payload) > 0 {
+			if err := tx.Bucket(payloadsBucket).Put(key, r.Payload); err != nil {
+				return err
+			}
+		}
+		if newer {
+			if err := latest.Put(dedupe, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.LookupKey == "" {
+		return nil
+	}
+	value := binary.BigEndian.AppendUint64(nil, uint64(r.FetchedAt.UnixNano()))
+	return tx.Bucket(lookupsBucket).Put(lookupKey(r.Kind, r.LookupKey), append(value, key...))
+}
+
+// prune deletes the records measured, and the lookups fetched, before
+// HISTORY_RETENTION_DAYS
+func prune(store *bolt.DB, now time.Time) {
+	limit := config.AppConfig.HistoryRetention
+	if limit <= 0 {
+		return
+	}
+	cutoff := now.Add(-limit)
+	cutoffKey := recordKey(cutoff, 0)
+
+	var deleted int
+	err := store.Update(func(tx *bolt.Tx) error {
+		stations := tx.Bucket(stationsBucket)
+		payloads := tx.Bucket(payloadsBucket)
+		c := tx.Bucket(recordsBucket).Cursor()
+		for key, data := c.First(); key != nil && bytes.Compare(key, cutoffKey) < 0; key, data = c.First() {
+			var r Record
+			if err := json.Unmarshal(data, &r); err == nil {
+				if err := stations.Delete(append(stationPrefix(r.station()), key...)); err != nil {
+					return err
+				}
+			}
+			if err := payloads.Delete(key); err != nil {
+				return err
+			}
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			deleted++
+		}
+
+		// Drop the dedupe and lookup entries of deleted records. Keys are
+		// collected first: deleting under a cursor skips the following key.
+		for _, index := range []struct {
+			bucket []byte
+			offset int
+		}{{latestBucket, 0}, {lookupsBucket, 8}} {
+			bucket := tx.Bucket(index.bucket)
+			var expired [][]byte
+			bucket.ForEach(func(key, value []byte) error {
+				if len(value) != index.offset+16 || bytes.Compare(value[index.offset:], cutoffKey) < 0 {
+					expired = append(expired, append([]byte(nil), key...))
+				}
+				return nil
+			})
+			for _, key := range expired {
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to apply history retention", "error", err.Error())
+		return
+	}
+	if deleted > 0 {
+		logger.Info("Deleted expired reading history",
+			"records", deleted,
+			"before", cutoff.Format(time.RFC3339),
+		)
+	}
+}
+
+// Find returns the records matching the query ordered by timestamp. Records
+// are read newest first from To, through the station index when the query
+// names a station, and reading stops at From or once Limit records match.
+func Find(q Query) ([]Record, error) {
+	mu.RLock()
+	store := db
+	mu.RUnlock()
+	if store == nil {
+		return nil, nil
+	}
+
+	var records []Record
+	err := store.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(recordsBucket)
+		c := bucket.Cursor()
+		var prefix []byte
+		if q.Station != "" {
+			c = tx.Bucket(stationsBucket).Cursor()
+			prefix = stationPrefix(q.Station)
+		}
+
+		// Seek to the last key at or before To
+		upper := append(append([]byte(nil), prefix...), upperKey(q.To)...)
+		key, data := c.Seek(upper)
+		if key == nil {
+			key, data = c.Last()
+		} else if bytes.Compare(key, upper) > 0 {
+			key, data = c.Prev()
+		}
+
+		for ; key != nil && bytes.HasPrefix(key, prefix); key, data = c.Prev() {
+			recordKey := key[len(prefix):]
+			if len(recordKey) != 16 {
+				continue
+			}
+			if !q.From.IsZero() && recordTime(recordKey).Before(q.From) {
+				break
+			}
+			if prefix != nil {
+				if data = bucket.Get(recordKey); data == nil {
+					continue
+				}
+			}
+
+			var r Record
+			if err := json.Unmarshal(data, &r); err != nil {
+				return fmt.Errorf("error decoding history record: %w", err)
+			}
+			if !q.matches(r) {
+				continue
+			}
+			records = append(records, r)
+			if q.Limit > 0 && len(records) == q.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Oldest first
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// Recent returns the record, with its payload, last fetched for a lookup
+// within maxAge, so that repeated lookups can be answered without calling
+// the provider again
+func Recent(kind, key string, maxAge time.Duration) (Record, bool) {
+	mu.RLock()
+	store := db
+	mu.RUnlock()
+	if store == nil || key == "" || maxAge <= 0 {
+		return Record{}, false
+	}
+
+	var r Record
+	var found bool
+	store.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(lookupsBucket).Get(lookupKey(kind, key))
+		if len(value) != 24 {
+			return nil
+		}
+		fetchedAt := time.Unix(0, int64(binary.BigEndian.Uint64(value[:8])))
+		if time.Since(fetchedAt) > maxAge {
+			return nil
+		}
+		data := tx.Bucket(recordsBucket).Get(value[8:])
+		payload := tx.Bucket(payloadsBucket).Get(value[8:])
+		if data == nil || payload == nil || json.Unmarshal(data, &r) != nil {
+			return nil
+		}
+		r.FetchedAt = fetchedAt.UTC()
+		r.Payload = append(json.RawMessage(nil), payload...)
+		found = true
+		return nil
+	})
+	return r, found
+}
@@ -0,0 +1,190 @@
+package history
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/clean-route/go-backend/internal/config"
+)
+
+func openStore(t *testing.T) {
+	t.Helper()
+	config.AppConfig = &config.Config{HistoryDir: t.TempDir(), HistoryRetention: 7 * 24 * time.Hour}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() { Close() })
+}
+
+func reading(station string, lon float64, at time.Time, pm25 float64) Record {
+	return Record{
+		Kind:      KindAirQuality,
+		Source:    "waqi",
+		StationID: station,
+		Location:  [2]float64{lon, 12.97},
+		Timestamp: at,
+		Values:    map[string]float64{"pm25": pm25},
+	}
+}
+
+func values(records []Record) []float64 {
+	list := make([]float64, len(records))
+	for i, r := range records {
+		list[i] = r.Values["pm25"]
+	}
+	return list
+}
+
+func equal(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFind(t *testing.T) {
+	openStore(t)
+
+	base := time.Now().UTC().Truncate(time.Hour).Add(-10 * time.Hour)
+	for hour := 0; hour < 6; hour++ {
+		at := base.Add(time.Duration(hour) * time.Hour)
+		Add(reading("@1", 77.5, at, float64(10+hour)))
+		Add(reading("@2", 78.5, at, float64(20+hour)))
+	}
+	flush()
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []float64
+	}{
+		{"station", Query{Station: "@1"}, []float64{10, 11, 12, 13, 14, 15}},
+		{"station time range", Query{Station: "@2", From: base.Add(2 * time.Hour), To: base.Add(4 * time.Hour)}, []float64{22, 23, 24}},
+		{"most recent within limit", Query{Station: "@1", Limit: 2}, []float64{14, 15}},
+		{"bounding box", Query{BBox: &[4]float64{78, 12, 79, 13}, From: base.Add(4 * time.Hour)}, []float64{24, 25}},
+		{"limit across stations", Query{Limit: 3}, []float64{24, 15, 25}},
+		{"unknown station", Query{Station: "@3"}, []float64{}},
+		{"before the first record", Query{To: base.Add(-time.Hour)}, []float64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := Find(tt.query)
+			if err != nil {
+				t.Fatalf("Find: %v", err)
+			}
+			if got := values(records); !equal(got, tt.want) {
+				t.Errorf("Find returned %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddStoresUnchangedReadingsOnce(t *testing.T) {
+	openStore(t)
+
+	at := time.Now().UTC().Add(-time.Hour)
+	Add(reading("@1", 77.5, at, 40))
+	Add(reading("@1", 77.5, at, 40))
+	Add(reading("@1", 77.5, at.Add(-time.Hour), 35)) // older than the stored reading
+	Add(reading("@1", 77.5, at.Add(30*time.Minute), 42))
+	flush()
+
+	// Still deduplicated after a restart
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	Add(reading("@1", 77.5, at.Add(30*time.Minute), 42))
+	flush()
+
+	records, err := Find(Query{Station: "@1"})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if got := values(records); !equal(got, []float64{40, 42}) {
+		t.Errorf("stored %v, want 40 and 42 once each", got)
+	}
+}
+
+func TestRecent(t *testing.T) {
+	openStore(t)
+
+	r := reading("@1", 77.5, time.Now().UTC().Add(-time.Hour), 40)
+	r.LookupKey = "waqi|pm25|tdr1y1"
+	r.Payload = json.RawMessage(`{"concentration":40}`)
+	Add(r)
+	// The same reading looked up from another cell points that cell at it
+	r.LookupKey = "waqi|pm25|tdr1y2"
+	r.Payload = nil
+	Add(r)
+	flush()
+
+	tests := []struct {
+		name   string
+		key    string
+		maxAge time.Duration
+		wantOK bool
+	}{
+		{"fresh lookup", "waqi|pm25|tdr1y1", time.Minute, true},
+		{"other cell of the same reading", "waqi|pm25|tdr1y2", time.Minute, true},
+		{"stale lookup", "waqi|pm25|tdr1y1", time.Nanosecond, false},
+		{"unknown lookup", "waqi|pm25|tdr1y3", time.Minute, false},
+		{"no key", "", time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, ok := Recent(KindAirQuality, tt.key, tt.maxAge)
+			if ok != tt.wantOK {
+				t.Fatalf("Recent found %v, want %v", ok, tt.wantOK)
+			}
+			if ok && string(record.Payload) != `{"concentration":40}` {
+				t.Errorf("payload %s, want the stored reading", record.Payload)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	openStore(t)
+
+	now := time.Now().UTC()
+	old := reading("@1", 77.5, now.Add(-8*24*time.Hour), 30)
+	old.LookupKey = "waqi|pm25|tdr1y1"
+	old.FetchedAt = old.Timestamp
+	recent := reading("@1", 77.5, now.Add(-time.Hour), 40)
+	// Written directly: Add already rejects readings past the retention
+	err := db.Update(func(tx *bolt.Tx) error {
+		if err := put(tx, old); err != nil {
+			return err
+		}
+		return put(tx, recent)
+	})
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	prune(db, now)
+
+	records, err := Find(Query{Station: "@1"})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if got := values(records); !equal(got, []float64{40}) {
+		t.Errorf("kept %v, want only the recent reading", got)
+	}
+	db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(lookupsBucket).Stats().KeyN != 0 || tx.Bucket(stationsBucket).Stats().KeyN != 1 {
+			t.Error("indexes of the expired reading were kept")
+		}
+		return nil
+	})
+}
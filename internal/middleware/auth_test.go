@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		keys   []string
+		header string
+		value  string
+		want   int
+	}{
+		{"header key", []string{"secret"}, "X-API-Key", "secret", http.StatusOK},
+		{"bearer token", []string{"other", "secret"}, "Authorization", "Bearer secret", http.StatusOK},
+		{"wrong key", []string{"secret"}, "X-API-Key", "guess", http.StatusUnauthorized},
+		{"missing key", []string{"secret"}, "", "", http.StatusUnauthorized},
+		{"no keys configured", nil, "X-API-Key", "secret", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorResponseMiddleware())
+			router.POST("/admin", APIKeyAuth(tt.keys), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/admin", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
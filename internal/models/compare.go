@@ -0,0 +1,48 @@
+package models
+
+// CompareModesRequest asks for the best route of several modes between the
+// same origin and destination
+type CompareModesRequest struct {
+	Source      [2]float64 `json:"source" binding:"required"`
+	Destination [2]float64 `json:"destination" binding:"required"`
+	DelayCode   uint8      `json:"delayCode"`
+	// Modes defaults to driving-traffic, scooter, bike, foot and transit
+	Modes []string `json:"modes,omitempty"`
+	// RoutePreference picks the route of each mode, balanced by default
+	RoutePreference string `json:"route_preference,omitempty"`
+	Condition       string `json:"condition"`
+	// VehicleProfiles replaces the default vehicle profile of a mode
+	VehicleProfiles map[string]string `json:"vehicle_profiles,omitempty"`
+	// Weights of duration, cost, co2 and dose in the recommendation; each
+	// defaults to 1
+	Weights map[string]float64 `json:"weights,omitempty"`
+}
+
+// ModeSummary is one row of a mode comparison
+type ModeSummary struct {
+	Mode      string `json:"mode"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+
+	Distance    float64 `json:"distance"` // meters
+	Duration    float64 `json:"duration"` // seconds
+	Cost        float64 `json:"cost"`
+	Currency    string  `json:"currency,omitempty"`
+	CO2Grams    float64 `json:"co2_g"`
+	Exposure    float64 `json:"exposure"`
+	InhaledDose float64 `json:"inhaled_dose"`
+	// KCal is reported for walking and cycling
+	KCal *float64 `json:"kcal,omitempty"`
+	// Score is the weighted, normalised sum the recommendation minimises
+	Score float64 `json:"score"`
+}
+
+// ModeComparison compares the best route of each mode
+type ModeComparison struct {
+	Source          []float64     `json:"source"`
+	Destination     []float64     `json:"destination"`
+	DelayCode       uint8         `json:"delayCode"`
+	RoutePref       string        `json:"route_preference"`
+	Modes           []ModeSummary `json:"modes"`
+	RecommendedMode string        `json:"recommended_mode,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// TripCost is the estimated cost of driving a route: the fuel or electricity
+// it uses at the prices of its region plus the toll and congestion-charge
+// zones it enters
+type TripCost struct {
+	Total    float64 `json:"total"`
+	Currency string  `json:"currency"`
+	Region   string  `json:"region"`
+
+	// Energy is the cost of EnergyQuantity EnergyUnit ("l", "kg" or "kWh")
+	// bought at UnitPrice
+	Energy         float64 `json:"energy"`
+	EnergyQuantity float64 `json:"energy_quantity"`
+	EnergyUnit     string  `json:"energy_unit"`
+	UnitPrice      float64 `json:"unit_price"`
+
+	Tolls float64      `json:"tolls"`
+	Zones []ZoneCharge `json:"zones,omitempty"`
+}
+
+// ZoneCharge is the charge for entering a toll or congestion-charge zone
+type ZoneCharge struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	Charge float64 `json:"charge"`
+}
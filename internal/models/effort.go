@@ -0,0 +1,24 @@
+package models
+
+const (
+	EffortGradeEasy     = "easy"
+	EffortGradeModerate = "moderate"
+	EffortGradeHard     = "hard"
+	EffortGradeVeryHard = "very_hard"
+)
+
+// HumanEffort is the physical effort of walking or cycling a route
+type HumanEffort struct {
+	// KCal is the energy burned above resting
+	KCal float64 `json:"kcal"`
+	// AveragePowerW is the mean metabolic power above resting
+	AveragePowerW float64 `json:"average_power_w"`
+	// MET is the mean intensity in metabolic equivalents
+	MET     float64 `json:"met"`
+	Grade   string  `json:"grade"`
+	Ascend  float64 `json:"ascend"`
+	Descend float64 `json:"descend"`
+	// AssistRatio is the share of the mechanical work supplied by a
+	// pedal-assist motor
+	AssistRatio float64 `json:"assist_ratio,omitempty"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// Emissions are the estimated emissions of a trip in grams. CO2 includes
+// electricity generation for electric vehicles; NOx and PM are tailpipe only.
+type Emissions struct {
+	CO2Grams float64 `json:"co2_g"`
+	NOxGrams float64 `json:"nox_g"`
+	PMGrams  float64 `json:"pm_g"`
+}
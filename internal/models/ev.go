@@ -0,0 +1,47 @@
+package models
+
+// EVEnergy is the battery balance of an electric vehicle over a route
+type EVEnergy struct {
+	ConsumedKWh        float64  `json:"consumed_kwh"`
+	TractionKWh        float64  `json:"traction_kwh"`
+	RegeneratedKWh     float64  `json:"regenerated_kwh"`
+	AuxiliaryKWh       float64  `json:"auxiliary_kwh"`
+	BatteryCapacityKWh float64  `json:"battery_capacity_kwh"`
+	StartSoC           float64  `json:"start_soc"`
+	ArrivalSoC         float64  `json:"arrival_soc"`
+	ReserveSoC         float64  `json:"reserve_soc"`
+	BelowReserve       bool     `json:"below_reserve"`
+	AmbientTempC       *float64 `json:"ambient_temp_c,omitempty"`
+
+	ChargingPlan *ChargingPlan `json:"charging_plan,omitempty"`
+}
+
+// ChargingStop is a planned charging stop on an electric vehicle route
+type ChargingStop struct {
+	StationID     string     `json:"station_id"`
+	Name          string     `json:"name"`
+	Location      [2]float64 `json:"location"`
+	ConnectorType string     `json:"connector_type,omitempty"`
+	PowerKW       float64    `json:"power_kw"`
+	DistanceKm    float64    `json:"distance_km"`
+	DetourKm      float64    `json:"detour_km"`
+	ArrivalSoC    float64    `json:"arrival_soc"`
+	DepartureSoC  float64    `json:"departure_soc"`
+	EnergyKWh     float64    `json:"energy_kwh"`
+	ChargeMinutes float64    `json:"charge_minutes"`
+}
+
+// ChargingPlan lists the charging stops needed to finish a route above the
+// reserve state of charge. Distance, DrivingTime and Geometry describe the
+// route through the stops when it could be fetched.
+type ChargingPlan struct {
+	Feasible           bool           `json:"feasible"`
+	Reason             string         `json:"reason,omitempty"`
+	Stops              []ChargingStop `json:"stops"`
+	ViaPoints          [][2]float64   `json:"via_points"`
+	ArrivalSoC         float64        `json:"arrival_soc"`
+	TotalChargeMinutes float64        `json:"total_charge_minutes"`
+	Distance           float64        `json:"distance,omitempty"`
+	DrivingTime        float64        `json:"driving_time,omitempty"`
+	Geometry           [][]float64    `json:"geometry,omitempty"`
+}
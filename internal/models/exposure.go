@@ -0,0 +1,112 @@
+package models
+
+// Interval is a confidence interval around an estimate
+type Interval struct {
+	Lower      float64 `json:"lower"`
+	Upper      float64 `json:"upper"`
+	Confidence float64 `json:"confidence"`
+	Source     string  `json:"source,omitempty"`
+}
+
+// Overlaps reports whether two intervals share any value
+func (i Interval) Overlaps(other Interval) bool {
+	return i.Lower <= other.Upper && other.Lower <= i.Upper
+}
+
+const (
+	// ExposureSourceObserved means exposure was computed from current station readings
+	ExposureSourceObserved = "observed"
+	// ExposureSourceModel means exposure was computed from PM2.5 model forecasts
+	ExposureSourceModel = "model"
+	// ExposureSourceWAQIForecast means the PM2.5 model was unavailable and the
+	// WAQI daily forecast was used as a fallback estimate
+	ExposureSourceWAQIForecast = "waqi_forecast"
+)
+
+const (
+	// ProviderWAQI identifies the WAQI air-quality feed
+	ProviderWAQI = "waqi"
+	// ProviderOpenWeather identifies the OpenWeather feed
+	ProviderOpenWeather = "openweather"
+	// ProviderOpenMeteo identifies the Open-Meteo forecast API
+	ProviderOpenMeteo = "open_meteo"
+	// ProviderPM25Model identifies the PM2.5 model registry
+	ProviderPM25Model = "pm25_model"
+	// ProviderOpenAQ identifies the OpenAQ v3 air-quality API
+	ProviderOpenAQ = "openaq"
+	// ProviderSensorCommunity identifies the Sensor.Community sensor network
+	ProviderSensorCommunity = "sensor_community"
+	// ProviderCPCB identifies India's CPCB real-time air-quality feed
+	ProviderCPCB = "cpcb"
+	// ProviderObservations identifies readings ingested from community sensors
+	ProviderObservations = "observations"
+	// ProviderBlended identifies a station reading blended with nearby
+	// community sensor readings
+	ProviderBlended = "blended"
+	// ProviderElevation identifies the local elevation model
+	ProviderElevation = "elevation"
+	// ProviderMapbox identifies the Mapbox Directions API
+	ProviderMapbox = "mapbox"
+	// ProviderGraphHopper identifies the GraphHopper Routing API
+	ProviderGraphHopper = "graphhopper"
+	// ProviderBackground identifies the gridded background concentration
+	// fields loaded from BACKGROUND_GRID_DIR
+	ProviderBackground = "background"
+)
+
+const (
+	SampleStatusOK           = "ok"
+	SampleStatusFailed       = "failed"
+	SampleStatusStale        = "rejected_stale"
+	SampleStatusTooFar       = "rejected_distance"
+	SampleStatusDownweighted = "downweighted"
+	SampleStatusBackground   = "background"
+)
+
+// SampleDiagnostic describes the station reading used for one route sample
+type SampleDiagnostic struct {
+	Location          []float64 `json:"location"`
+	Station           string    `json:"station,omitempty"`
+	Source            string    `json:"source,omitempty"`
+	PM25              float64   `json:"pm25"`
+	StationAgeMinutes *float64  `json:"station_age_minutes,omitempty"`
+	StationDistanceKm *float64  `json:"station_distance_km,omitempty"`
+	Weight            float64   `json:"weight"`
+	Status            string    `json:"status"`
+	Interpolated      bool      `json:"interpolated"`
+}
+
+// DataQuality describes how complete the upstream data behind a route's
+// exposure estimate was. Samples taken from the background grids instead of
+// a station count towards SamplesSucceeded as well as SamplesBackground.
+type DataQuality struct {
+	SamplesRequested    int      `json:"samples_requested"`
+	SamplesSucceeded    int      `json:"samples_succeeded"`
+	SamplesInterpolated int      `json:"samples_interpolated"`
+	SamplesSkipped      int      `json:"samples_skipped"`
+	SamplesRejected     int      `json:"samples_rejected"`
+	SamplesBackground   int      `json:"samples_background"`
+	Coverage            float64  `json:"coverage"`
+	ProvidersDegraded   []string `json:"providers_degraded"`
+	FallbackUsed        bool     `json:"fallback_used"`
+
+	Samples []SampleDiagnostic `json:"samples,omitempty"`
+}
+
+// Degrade records a provider that failed at least once for the route
+func (q *DataQuality) Degrade(provider string) {
+	for _, p := range q.ProvidersDegraded {
+		if p == provider {
+			return
+		}
+	}
+	q.ProvidersDegraded = append(q.ProvidersDegraded, provider)
+}
+
+// RouteExposure is the PM2.5 exposure accumulated along a route
+type RouteExposure struct {
+	Total    float64
+	Interval Interval
+	Source   string
+	Quality  DataQuality
+}
@@ -1,5 +1,7 @@
 package graphhopperroutes
 
+import models "github.com/clean-route/go-backend/internal/models"
+
 type Waypoint struct {
 	Type        string        `json:"type"`
 	Coordinates []Coordinates `json:"coordinates"`
@@ -32,7 +34,16 @@ type Path struct {
 	Descend          float64                `json:"descend"`
 	SnappedWaypoints Waypoint               `json:"snapped_waypoints"`
 	TotalEnergy      float64                `json:"total_energy"`
+	Emissions        models.Emissions       `json:"emissions"`
+	EVEnergy         *models.EVEnergy       `json:"ev_energy,omitempty"`
+	Cost             models.TripCost        `json:"cost"`
+	Effort           *models.HumanEffort    `json:"effort,omitempty"`
 	TotalExposure    float64                `json:"total_exposure"`
+	ExposureInterval models.Interval        `json:"exposure_interval"`
+	ExposureSource   string                 `json:"exposure_source"`
+	VentilationRate  float64                `json:"ventilation_rate"`
+	InhaledDose      float64                `json:"inhaled_dose"`
+	DataQuality      models.DataQuality     `json:"data_quality"`
 }
 
 type Hint struct {
@@ -57,9 +68,11 @@ type RouteList struct {
 	DelayCode   uint8     `json:"delayCode"`
 	Mode        string    `json:"mode"`
 	RoutePref   string    `json:"route_preference"`
-	Fastest     Path     `json:"fastest"`
-	Shortest    Path     `json:"shortest"`
-	LeapG        Path     `json:"leap_graphhopper"`
-	Lco2G        Path     `json:"lco2_graphhopper"`
-	Balanced    Path     `json:"balanced"`
+	Fastest     Path      `json:"fastest"`
+	Shortest    Path      `json:"shortest"`
+	LeapG       Path      `json:"leap_graphhopper"`
+	LeapTied    bool      `json:"leap_tied"`
+	Lco2G       Path      `json:"lco2_graphhopper"`
+	Balanced    Path      `json:"balanced"`
+	Cheapest    Path      `json:"cheapest"`
 }
@@ -1,6 +1,8 @@
 package mapboxroutes
 
-import models "github.com/clean-route/go-backend/internal/models/graphhopper"
+import (
+	appmodels "github.com/clean-route/go-backend/internal/models"
+)
 
 // Define structs to represent the JSON data
 
@@ -62,6 +64,16 @@ type Leg struct {
 	Steps           []Step        `json:"steps"`
 	Distance        float64       `json:"distance"`
 	Summary         string        `json:"summary"`
+	Annotation      Annotation    `json:"annotation"`
+}
+
+// Annotation holds per-segment traffic data between consecutive coordinates
+// of a leg's geometry
+type Annotation struct {
+	Distance   []float64 `json:"distance"`
+	Duration   []float64 `json:"duration"`
+	Speed      []float64 `json:"speed"`
+	Congestion []string  `json:"congestion"`
 }
 
 type Admin struct {
@@ -70,17 +82,26 @@ type Admin struct {
 }
 
 type Route struct {
-	WeightTypical   float64    `json:"weight_typical"`
-	Waypoints       []Waypoint `json:"waypoints"`
-	DurationTypical float64    `json:"duration_typical"`
-	WeightName      string     `json:"weight_name"`
-	Weight          float64    `json:"weight"`
-	Duration        float64    `json:"duration"`
-	Distance        float64    `json:"distance"`
-	Legs            []Leg      `json:"legs"`
-	Geometry        Geometry   `json:"geometry"`
-	TotalEnergy     float64    `json:"total_energy"`
-	TotalExposure   float64    `json:"total_exposure"`
+	WeightTypical    float64               `json:"weight_typical"`
+	Waypoints        []Waypoint            `json:"waypoints"`
+	DurationTypical  float64               `json:"duration_typical"`
+	WeightName       string                `json:"weight_name"`
+	Weight           float64               `json:"weight"`
+	Duration         float64               `json:"duration"`
+	Distance         float64               `json:"distance"`
+	Legs             []Leg                 `json:"legs"`
+	Geometry         Geometry              `json:"geometry"`
+	IdleTime         float64               `json:"idle_time"`
+	TotalEnergy      float64               `json:"total_energy"`
+	Emissions        appmodels.Emissions   `json:"emissions"`
+	EVEnergy         *appmodels.EVEnergy   `json:"ev_energy,omitempty"`
+	Cost             appmodels.TripCost    `json:"cost"`
+	TotalExposure    float64               `json:"total_exposure"`
+	ExposureInterval appmodels.Interval    `json:"exposure_interval"`
+	ExposureSource   string                `json:"exposure_source"`
+	VentilationRate  float64               `json:"ventilation_rate"`
+	InhaledDose      float64               `json:"inhaled_dose"`
+	DataQuality      appmodels.DataQuality `json:"data_quality"`
 }
 
 type RouteData struct {
@@ -90,16 +111,16 @@ type RouteData struct {
 }
 
 type RouteList struct {
-	Source      []float64   `json:"source"`
-	Destination []float64   `json:"destination"`
-	DelayCode   uint8       `json:"delayCode"`
-	Mode        string      `json:"mode"`
-	RoutePref   string      `json:"route_preference"`
-	Fastest     Route       `json:"fastest"`
-	Shortest    Route       `json:"shortest"`
-	Leap        Route       `json:"leap"`
-	Lco2        Route       `json:"lco2"`
-	Balanced    Route       `json:"balanced"`
-	LeapG       models.Path `json:"leap_graphhopper"`
-	Lco2G       models.Path `json:"lco2_graphhopper"`
+	Source      []float64 `json:"source"`
+	Destination []float64 `json:"destination"`
+	DelayCode   uint8     `json:"delayCode"`
+	Mode        string    `json:"mode"`
+	RoutePref   string    `json:"route_preference"`
+	Fastest     Route     `json:"fastest"`
+	Shortest    Route     `json:"shortest"`
+	Leap        Route     `json:"leap"`
+	LeapTied    bool      `json:"leap_tied"`
+	Lco2        Route     `json:"lco2"`
+	Balanced    Route     `json:"balanced"`
+	Cheapest    Route     `json:"cheapest"`
 }
@@ -0,0 +1,8 @@
+package models
+
+// RejectedObservation reports why an observation in a batch was not stored
+type RejectedObservation struct {
+	Index    int    `json:"index"`
+	SensorID string `json:"sensor_id"`
+	Reason   string `json:"reason"`
+}
@@ -25,17 +25,23 @@ type CurrentWeather struct {
 	WindSpeed        float64   `json:"wind_speed"`
 	WindDeg          float64   `json:"wind_deg"`
 	Weather          []Weather `json:"weather"`
+	Rain             *Rain     `json:"rain,omitempty"`
+}
+
+// Rain represents the rain volume in the JSON
+type Rain struct {
+	OneHour float64 `json:"1h"` // mm/h
 }
 
 // MinutelyData represents the minutely data in the JSON
 type MinutelyData struct {
-	Dt            uint64 `json:"dt"`
-	Precipitation float64   `json:"precipitation"`
+	Dt            uint64  `json:"dt"`
+	Precipitation float64 `json:"precipitation"`
 }
 
 // HourlyData represents the hourly data in the JSON
 type HourlyData struct {
-	Dt               uint64     `json:"dt"`
+	Dt               uint64    `json:"dt"`
 	Temp             float64   `json:"temp"`
 	FeelsLike        float64   `json:"feels_like"`
 	Pressure         float64   `json:"pressure"`
@@ -50,6 +56,7 @@ type HourlyData struct {
 	Weather          []Weather `json:"weather"`
 	Pop              float64   `json:"pop"`
 	RelativeHumidity float64   `json:"relative_humidity"`
+	Rain             *Rain     `json:"rain,omitempty"`
 }
 
 // WeatherData represents the overall weather data in the JSON
@@ -62,6 +69,3 @@ type WeatherData struct {
 	Minutely       []MinutelyData `json:"minutely"`
 	Hourly         []HourlyData   `json:"hourly"`
 }
-
-
-
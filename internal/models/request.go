@@ -10,11 +10,39 @@ type RouteRequest struct {
 	VehicleMass     int        `json:"vehicle_mass"`
 	Condition       string     `json:"condition"`
 	EngineType      string     `json:"engine_type"`
+
+	VehicleProfile   string            `json:"vehicle_profile,omitempty"`
+	VehicleOverrides *VehicleOverrides `json:"vehicle_overrides,omitempty"`
+
+	// StartSoC and ReserveSoC are battery states of charge in percent, used
+	// for electric vehicles
+	StartSoC   *float64 `json:"start_soc,omitempty"`
+	ReserveSoC *float64 `json:"reserve_soc,omitempty"`
+}
+
+// VehicleOverrides replaces individual parameters of a vehicle profile
+type VehicleOverrides struct {
+	MassKg            *float64                  `json:"mass_kg,omitempty"`
+	BodyMassKg        *float64                  `json:"body_mass_kg,omitempty"`
+	DragCoefficient   *float64                  `json:"drag_coefficient,omitempty"`
+	FrontalAreaM2     *float64                  `json:"frontal_area_m2,omitempty"`
+	RollingResistance *float64                  `json:"rolling_resistance,omitempty"`
+	FuelType          *string                   `json:"fuel_type,omitempty"`
+	BatteryKWh        *float64                  `json:"battery_capacity_kwh,omitempty"`
+	RegenEfficiency   *float64                  `json:"regen_efficiency,omitempty"`
+	EfficiencyCurve   []EfficiencyPointOverride `json:"efficiency_curve,omitempty"`
+}
+
+// EfficiencyPointOverride is a point on an overriding drivetrain efficiency curve
+type EfficiencyPointOverride struct {
+	SpeedKmh   float64 `json:"speed_kmh"`
+	Efficiency float64 `json:"efficiency"`
 }
 
 // PM25PredictionRequest represents the request for PM2.5 prediction
 type PM25PredictionRequest struct {
 	Features []FeatureVector `json:"features" binding:"required"`
+	Model    string          `json:"model,omitempty"`
 }
 
 // FeatureVector represents the feature vector for ML prediction
@@ -0,0 +1,368 @@
+// Package mqtt is a minimal MQTT 3.1.1 client that subscribes to topics and
+// receives messages at QoS 0, 1 and 2. It does not publish.
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configure a connection to a broker
+type Options struct {
+	// Broker is tcp://host:port or mqtt://host:port, or ssl://, tls:// or
+	// mqtts:// for TLS. The port defaults to 1883, or 8883 with TLS.
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// KeepAlive is how often the client pings an idle broker
+	KeepAlive time.Duration
+	// DialTimeout bounds connecting and waiting for CONNACK and SUBACK
+	DialTimeout time.Duration
+	// MaxPacketSize bounds the packets accepted from the broker; larger
+	// packets close the connection. Defaults to 1 MiB.
+	MaxPacketSize int
+}
+
+// Subscription is a topic filter and the maximum QoS to receive it at
+type Subscription struct {
+	Filter string
+	QoS    byte
+}
+
+// Message is a message published to a subscribed topic
+type Message struct {
+	Topic    string
+	Payload  []byte
+	QoS      byte
+	Retained bool
+}
+
+// ErrRefused is returned when the broker refuses the connection or a
+// subscription
+var ErrRefused = errors.New("MQTT broker refused the request")
+
+var connackReasons = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Client is a connection to a broker
+type Client struct {
+	opts   Options
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMu  sync.Mutex
+	packetID uint16
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Dial connects to the broker with a clean session
+func Dial(opts Options) (*Client, error) {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = 30 * time.Second
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 10 * time.Second
+	}
+	if opts.MaxPacketSize <= 0 {
+		opts.MaxPacketSize = 1 << 20
+	}
+
+	conn, err := dialBroker(opts.Broker, opts.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		opts:   opts,
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		done:   make(chan struct{}),
+	}
+	if err := c.connect(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func dialBroker(broker string, timeout time.Duration) (net.Conn, error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid MQTT broker URL %q", broker)
+	}
+
+	useTLS := false
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS = true
+	default:
+		return nil, fmt.Errorf("unsupported MQTT broker scheme %q", u.Scheme)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if useTLS {
+			port = "8883"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if useTLS {
+		return tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	}
+	return dialer.Dial("tcp", host)
+}
+
+func (c *Client) connect() error {
+	var flags byte = 0x02 // clean session
+	if c.opts.Username != "" {
+		flags |= 0x80
+		if c.opts.Password != "" {
+			flags |= 0x40
+		}
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // protocol level 3.1.1
+	body = appendUint16(body, uint16(c.opts.KeepAlive/time.Second))
+	body = appendString(body, c.opts.ClientID)
+	if c.opts.Username != "" {
+		body = appendString(body, c.opts.Username)
+		if c.opts.Password != "" {
+			body = appendString(body, c.opts.Password)
+		}
+	}
+	if err := c.write(packet{kind: packetConnect, body: body}); err != nil {
+		return err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(c.opts.DialTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	ack, err := readPacket(c.reader, c.opts.MaxPacketSize)
+	if err != nil {
+		return fmt.Errorf("error reading CONNACK: %w", err)
+	}
+	if ack.kind != packetConnack || len(ack.body) != 2 {
+		return fmt.Errorf("expected CONNACK: %w", errMalformed)
+	}
+	if code := ack.body[1]; code != 0 {
+		return fmt.Errorf("%w: %s", ErrRefused, connackReasons[code])
+	}
+	return nil
+}
+
+// Subscribe subscribes to the topic filters. It must be called before Run.
+func (c *Client) Subscribe(subscriptions []Subscription) error {
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	id := c.nextPacketID()
+	body := appendUint16(nil, id)
+	for _, subscription := range subscriptions {
+		body = appendString(body, subscription.Filter)
+		body = append(body, subscription.QoS)
+	}
+	// SUBSCRIBE has the reserved flags 0010
+	if err := c.write(packet{kind: packetSubscribe, flags: 0x02, body: body}); err != nil {
+		return err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(c.opts.DialTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	ack, err := readPacket(c.reader, c.opts.MaxPacketSize)
+	if err != nil {
+		return fmt.Errorf("error reading SUBACK: %w", err)
+	}
+	if ack.kind != packetSuback {
+		return fmt.Errorf("expected SUBACK: %w", errMalformed)
+	}
+	ackID, codes, err := readUint16(ack.body)
+	if err != nil || ackID != id || len(codes) != len(subscriptions) {
+		return fmt.Errorf("invalid SUBACK: %w", errMalformed)
+	}
+	for i, code := range codes {
+		if code == 0x80 {
+			return fmt.Errorf("%w: subscription to %q", ErrRefused, subscriptions[i].Filter)
+		}
+	}
+	return nil
+}
+
+// Run delivers messages to handler until the connection is lost or closed.
+// Messages are acknowledged after handler returns. Run always returns a
+// non-nil error.
+func (c *Client) Run(handler func(Message)) error {
+	go c.keepAlive()
+	defer c.Close()
+
+	// QoS 2 messages delivered but not yet released by the broker
+	pending := map[uint16]bool{}
+
+	for {
+		// The broker disconnects clients silent for 1.5 keep-alive periods;
+		// wait for its PINGRESP as long
+		c.conn.SetReadDeadline(time.Now().Add(c.opts.KeepAlive * 3 / 2))
+
+		p, err := readPacket(c.reader, c.opts.MaxPacketSize)
+		if err != nil {
+			select {
+			case <-c.done:
+				return net.ErrClosed
+			default:
+				return fmt.Errorf("MQTT connection lost: %w", err)
+			}
+		}
+
+		switch p.kind {
+		case packetPublish:
+			message, id, err := decodePublish(p)
+			if err != nil {
+				return err
+			}
+			switch message.QoS {
+			case 0:
+				handler(message)
+			case 1:
+				handler(message)
+				err = c.write(packet{kind: packetPuback, body: appendUint16(nil, id)})
+			case 2:
+				// Deliver once even when the broker resends before PUBREL
+				if !pending[id] {
+					pending[id] = true
+					handler(message)
+				}
+				err = c.write(packet{kind: packetPubrec, body: appendUint16(nil, id)})
+			}
+			if err != nil {
+				return err
+			}
+		case packetPubrel:
+			id, _, err := readUint16(p.body)
+			if err != nil {
+				return err
+			}
+			delete(pending, id)
+			if err := c.write(packet{kind: packetPubcomp, body: appendUint16(nil, id)}); err != nil {
+				return err
+			}
+		case packetPingresp, packetSuback:
+		default:
+			return fmt.Errorf("unexpected MQTT packet type %d: %w", p.kind, errMalformed)
+		}
+	}
+}
+
+func decodePublish(p packet) (Message, uint16, error) {
+	message := Message{
+		QoS:      (p.flags >> 1) & 0x03,
+		Retained: p.flags&0x01 != 0,
+	}
+	if message.QoS > 2 {
+		return Message{}, 0, errMalformed
+	}
+
+	topic, rest, err := readString(p.body)
+	if err != nil {
+		return Message{}, 0, err
+	}
+	message.Topic = topic
+
+	var id uint16
+	if message.QoS > 0 {
+		if id, rest, err = readUint16(rest); err != nil {
+			return Message{}, 0, err
+		}
+	}
+	message.Payload = rest
+	return message, id, nil
+}
+
+func (c *Client) keepAlive() {
+	ticker := time.NewTicker(c.opts.KeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.write(packet{kind: packetPingreq}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Close disconnects from the broker
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.write(packet{kind: packetDisconnect})
+		err = c.conn.Close()
+	})
+	return err
+}
+
+func (c *Client) write(p packet) error {
+	data, err := p.encode()
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.opts.DialTimeout))
+	_, err = c.conn.Write(data)
+	return err
+}
+
+func (c *Client) nextPacketID() uint16 {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.packetID++
+	if c.packetID == 0 {
+		c.packetID = 1
+	}
+	return c.packetID
+}
+
+// MatchTopic reports whether a topic matches a filter with the + (one level)
+// and # (remaining levels) wildcards
+func MatchTopic(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
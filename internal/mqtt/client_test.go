@@ -0,0 +1,218 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// testBroker accepts one connection and hands it to serve
+type testBroker struct {
+	listener net.Listener
+	done     chan struct{}
+}
+
+func startBroker(t *testing.T, serve func(conn *brokerConn)) *testBroker {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	broker := &testBroker{listener: listener, done: make(chan struct{})}
+	go func() {
+		defer close(broker.done)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serve(&brokerConn{t: t, conn: conn, reader: bufio.NewReader(conn)})
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		<-broker.done
+	})
+	return broker
+}
+
+func (b *testBroker) url() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+// brokerConn is the broker side of a client connection
+type brokerConn struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (b *brokerConn) read(kind byte) (packet, bool) {
+	b.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	p, err := readPacket(b.reader, maxRemainingBytes)
+	if err != nil {
+		b.t.Errorf("broker: reading packet type %d: %v", kind, err)
+		return packet{}, false
+	}
+	if p.kind != kind {
+		b.t.Errorf("broker: got packet type %d, want %d", p.kind, kind)
+		return packet{}, false
+	}
+	return p, true
+}
+
+func (b *brokerConn) write(p packet) {
+	data, err := p.encode()
+	if err == nil {
+		_, err = b.conn.Write(data)
+	}
+	if err != nil {
+		b.t.Errorf("broker: writing packet type %d: %v", p.kind, err)
+	}
+}
+
+// accept completes the CONNECT and SUBSCRIBE handshake
+func (b *brokerConn) accept() bool {
+	if _, ok := b.read(packetConnect); !ok {
+		return false
+	}
+	b.write(packet{kind: packetConnack, body: []byte{0, 0}})
+	subscribe, ok := b.read(packetSubscribe)
+	if !ok {
+		return false
+	}
+	b.write(packet{kind: packetSuback, body: append(subscribe.body[:2:2], 1)})
+	return true
+}
+
+func (b *brokerConn) publish(qos byte, id uint16, topic string, payload string) {
+	body := appendString(nil, topic)
+	if qos > 0 {
+		body = appendUint16(body, id)
+	}
+	b.write(packet{kind: packetPublish, flags: qos << 1, body: append(body, payload...)})
+}
+
+// expectAck reads an acknowledgement of the packet id
+func (b *brokerConn) expectAck(kind byte, id uint16) {
+	p, ok := b.read(kind)
+	if !ok {
+		return
+	}
+	if got, _, err := readUint16(p.body); err != nil || got != id {
+		b.t.Errorf("broker: packet type %d acknowledged id %d, want %d", kind, got, id)
+	}
+}
+
+func dialTest(t *testing.T, broker *testBroker) *Client {
+	t.Helper()
+	client, err := Dial(Options{Broker: broker.url(), ClientID: "test", DialTimeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	if err := client.Subscribe([]Subscription{{Filter: "sensors/#", QoS: 2}}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	return client
+}
+
+func TestDialRefused(t *testing.T) {
+	broker := startBroker(t, func(b *brokerConn) {
+		if _, ok := b.read(packetConnect); ok {
+			b.write(packet{kind: packetConnack, body: []byte{0, 4}})
+		}
+	})
+
+	_, err := Dial(Options{Broker: broker.url(), ClientID: "test", DialTimeout: 2 * time.Second})
+	if !errors.Is(err, ErrRefused) {
+		t.Fatalf("Dial error %v, want ErrRefused", err)
+	}
+}
+
+func TestRunAcknowledgesQoS1(t *testing.T) {
+	broker := startBroker(t, func(b *brokerConn) {
+		if !b.accept() {
+			return
+		}
+		b.publish(1, 7, "sensors/a/pm", `{"pm25": 12}`)
+		b.expectAck(packetPuback, 7)
+	})
+
+	client := dialTest(t, broker)
+	var messages []Message
+	client.Run(func(message Message) { messages = append(messages, message) })
+	<-broker.done
+
+	if len(messages) != 1 || messages[0].Topic != "sensors/a/pm" || messages[0].QoS != 1 ||
+		!bytes.Equal(messages[0].Payload, []byte(`{"pm25": 12}`)) {
+		t.Errorf("messages %+v, want the one QoS 1 message", messages)
+	}
+}
+
+func TestRunDeliversQoS2Once(t *testing.T) {
+	broker := startBroker(t, func(b *brokerConn) {
+		if !b.accept() {
+			return
+		}
+		// The broker resends the message before it sees PUBREC
+		b.publish(2, 9, "sensors/a/pm", "first")
+		b.expectAck(packetPubrec, 9)
+		b.publish(2, 9, "sensors/a/pm", "first")
+		b.expectAck(packetPubrec, 9)
+		b.write(packet{kind: packetPubrel, flags: 0x02, body: appendUint16(nil, 9)})
+		b.expectAck(packetPubcomp, 9)
+		// Released ids are reused for new messages
+		b.publish(2, 9, "sensors/a/pm", "second")
+		b.expectAck(packetPubrec, 9)
+	})
+
+	client := dialTest(t, broker)
+	var payloads []string
+	client.Run(func(message Message) { payloads = append(payloads, string(message.Payload)) })
+	<-broker.done
+
+	if len(payloads) != 2 || payloads[0] != "first" || payloads[1] != "second" {
+		t.Errorf("delivered %q, want first and second once each", payloads)
+	}
+}
+
+func TestRunRejectsOversizedPacket(t *testing.T) {
+	broker := startBroker(t, func(b *brokerConn) {
+		if !b.accept() {
+			return
+		}
+		// Remaining length of 256 MB with no body behind it
+		b.conn.Write([]byte{packetPublish << 4, 0xff, 0xff, 0xff, 0x7f})
+	})
+
+	client := dialTest(t, broker)
+	err := client.Run(func(Message) { t.Error("oversized message delivered") })
+	if !errors.Is(err, errTooLarge) {
+		t.Errorf("Run error %v, want errTooLarge", err)
+	}
+}
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"sensors/a/pm", "sensors/a/pm", true},
+		{"sensors/a/pm", "sensors/b/pm", false},
+		{"sensors/+/pm", "sensors/b/pm", true},
+		{"sensors/+/pm", "sensors/b/c/pm", false},
+		{"sensors/+", "sensors", false},
+		{"sensors/#", "sensors/b/c/pm", true},
+		{"sensors/#", "sensors", true},
+		{"#", "sensors/b", true},
+		{"sensors/a", "sensors/a/pm", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchTopic(tt.filter, tt.topic); got != tt.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	packetConnect     byte = 1
+	packetConnack     byte = 2
+	packetPublish     byte = 3
+	packetPuback      byte = 4
+	packetPubrec      byte = 5
+	packetPubrel      byte = 6
+	packetPubcomp     byte = 7
+	packetSubscribe   byte = 8
+	packetSuback      byte = 9
+	packetPingreq     byte = 12
+	packetPingresp    byte = 13
+	packetDisconnect  byte = 14
+	maxRemainingBytes      = 268435455
+)
+
+// packet is a control packet as read from or written to the wire
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+var (
+	errMalformed = errors.New("malformed MQTT packet")
+	errTooLarge  = errors.New("MQTT packet is too large")
+)
+
+// readPacket reads one control packet, refusing bodies longer than maxBody
+// before allocating them
+func readPacket(r *bufio.Reader, maxBody int) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+
+	// Remaining length is a variable-length integer of up to four bytes
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errMalformed
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+
+	if length > maxBody {
+		return packet{}, fmt.Errorf("%w: %d bytes", errTooLarge, length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+func (p packet) encode() ([]byte, error) {
+	length := len(p.body)
+	if length > maxRemainingBytes {
+		return nil, fmt.Errorf("MQTT packet of %d bytes is too large", length)
+	}
+
+	out := []byte{p.kind<<4 | p.flags}
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		out = append(out, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(out, p.body...), nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	return binary.BigEndian.AppendUint16(buf, v)
+}
+
+// readString reads a length-prefixed string at the start of buf and returns
+// it with the rest of buf
+func readString(buf []byte) (string, []byte, error) {
+	if len(buf) < 2 {
+		return "", nil, errMalformed
+	}
+	length := int(binary.BigEndian.Uint16(buf))
+	if len(buf) < 2+length {
+		return "", nil, errMalformed
+	}
+	return string(buf[2 : 2+length]), buf[2+length:], nil
+}
+
+func readUint16(buf []byte) (uint16, []byte, error) {
+	if len(buf) < 2 {
+		return 0, nil, errMalformed
+	}
+	return binary.BigEndian.Uint16(buf), buf[2:], nil
+}
@@ -0,0 +1,76 @@
+package observations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Reasons an observation is rejected
+var (
+	ErrUnknownSensor        = errors.New("sensor is not registered")
+	ErrUnsupportedPollutant = errors.New("pollutant is not supported")
+	ErrOutOfRange           = errors.New("value is outside the plausible range")
+	ErrInvalidLocation      = errors.New("location is not a valid [longitude, latitude]")
+	ErrClockSkew            = errors.New("timestamp is in the future")
+	ErrTooOld               = errors.New("timestamp is older than the retention period")
+	ErrDuplicate            = errors.New("reading was already received")
+	ErrKeyNotBound          = errors.New("API key is not bound to the sensor")
+)
+
+// valueRanges bounds plausible concentrations (µg/m³) per pollutant. Low-cost
+// optical sensors saturate well below these.
+var valueRanges = map[string][2]float64{
+	"pm25": {0, 1000},
+	"pm10": {0, 2000},
+}
+
+// Observation is a reading from a community sensor. Location may be omitted
+// for sensors at their registered location.
+type Observation struct {
+	SensorID  string     `json:"sensor_id"`
+	Location  [2]float64 `json:"location"`
+	Pollutant string     `json:"pollutant"`
+	Value     float64    `json:"value"`
+	Timestamp time.Time  `json:"timestamp"`
+
+	// Calibrated is Value with the sensor's calibration applied, set when
+	// the observation is stored
+	Calibrated float64 `json:"calibrated"`
+}
+
+func (o Observation) hasLocation() bool {
+	return o.Location != [2]float64{}
+}
+
+// validate checks the observation against the pollutant ranges and the
+// clock, before the sensor is looked up
+func (o Observation) validate(now time.Time, retention time.Duration, maxSkew time.Duration) error {
+	if o.SensorID == "" {
+		return fmt.Errorf("sensor_id is required")
+	}
+	if o.Timestamp.IsZero() {
+		return fmt.Errorf("timestamp is required")
+	}
+	bounds, ok := valueRanges[o.Pollutant]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnsupportedPollutant, o.Pollutant)
+	}
+	if o.Value < bounds[0] || o.Value > bounds[1] {
+		return fmt.Errorf("%w: %g not in [%g, %g]", ErrOutOfRange, o.Value, bounds[0], bounds[1])
+	}
+	if o.hasLocation() && !validLocation(o.Location) {
+		return ErrInvalidLocation
+	}
+	if o.Timestamp.After(now.Add(maxSkew)) {
+		return fmt.Errorf("%w by %s", ErrClockSkew, o.Timestamp.Sub(now).Round(time.Second))
+	}
+	if retention > 0 && now.Sub(o.Timestamp) > retention {
+		return ErrTooOld
+	}
+	return nil
+}
+
+func validLocation(location [2]float64) bool {
+	return location[0] >= -180 && location[0] <= 180 && location[1] >= -90 && location[1] <= 90
+}
@@ -0,0 +1,197 @@
+package observations
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Payload formats understood by the MQTT subscriber
+const (
+	FormatJSON            = "json"
+	FormatSensorCommunity = "sensor_community"
+)
+
+// sensorCommunityPollutants maps Sensor.Community value types, after any
+// sensor model prefix (SDS_, PMS_, SPS30_ ...), to pollutants
+var sensorCommunityPollutants = map[string]string{
+	"P1": "pm10",
+	"P2": "pm25",
+}
+
+// parseJSONPayload reads observations from a JSON object or array. Each
+// object either has "pollutant" and "value", or one key per pollutant
+// ("pm25", "pm10"). fields renames payload keys to these names first, and
+// sensorID is used when an object has no "sensor_id". Objects without a
+// timestamp are stamped with receivedAt.
+func parseJSONPayload(payload []byte, fields map[string]string, sensorID string, receivedAt time.Time) ([]Observation, error) {
+	var raw interface{}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("error parsing JSON payload: %w", err)
+	}
+
+	var objects []map[string]interface{}
+	switch value := raw.(type) {
+	case map[string]interface{}:
+		objects = append(objects, value)
+	case []interface{}:
+		for _, item := range value {
+			object, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("JSON payload array must hold objects")
+			}
+			objects = append(objects, object)
+		}
+	default:
+		return nil, fmt.Errorf("JSON payload must be an object or array")
+	}
+
+	var parsed []Observation
+	for _, object := range objects {
+		for from, to := range fields {
+			if value, ok := object[from]; ok {
+				delete(object, from)
+				object[to] = value
+			}
+		}
+
+		base := Observation{SensorID: sensorID, Timestamp: receivedAt}
+		if id, ok := object["sensor_id"]; ok {
+			base.SensorID = fmt.Sprint(id)
+		}
+		if value, ok := object["timestamp"]; ok {
+			timestamp, err := parseTimestamp(value)
+			if err != nil {
+				return nil, err
+			}
+			base.Timestamp = timestamp
+		}
+		if location, ok := parseLocation(object); ok {
+			base.Location = location
+		}
+
+		if pollutant, ok := object["pollutant"].(string); ok {
+			value, ok := toFloat(object["value"])
+			if !ok {
+				return nil, fmt.Errorf("payload value is not a number")
+			}
+			observation := base
+			observation.Pollutant = pollutant
+			observation.Value = value
+			parsed = append(parsed, observation)
+			continue
+		}
+
+		for pollutant := range valueRanges {
+			value, ok := toFloat(object[pollutant])
+			if !ok {
+				continue
+			}
+			observation := base
+			observation.Pollutant = pollutant
+			observation.Value = value
+			parsed = append(parsed, observation)
+		}
+	}
+
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("payload has no pollutant values")
+	}
+	return parsed, nil
+}
+
+type sensorCommunityPayload struct {
+	ESP8266ID        string `json:"esp8266id"`
+	SensorDataValues []struct {
+		ValueType string `json:"value_type"`
+		Value     string `json:"value"`
+	} `json:"sensordatavalues"`
+}
+
+// parseSensorCommunityPayload reads the JSON a Sensor.Community (airrohr)
+// node sends to a custom API. The sensor id is "esp8266-<chip id>" unless
+// sensorID is set.
+func parseSensorCommunityPayload(payload []byte, sensorID string, receivedAt time.Time) ([]Observation, error) {
+	var data sensorCommunityPayload
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, fmt.Errorf("error parsing Sensor.Community payload: %w", err)
+	}
+	if sensorID == "" {
+		if data.ESP8266ID == "" {
+			return nil, fmt.Errorf("Sensor.Community payload has no esp8266id")
+		}
+		sensorID = "esp8266-" + data.ESP8266ID
+	}
+
+	var parsed []Observation
+	for _, value := range data.SensorDataValues {
+		valueType := value.ValueType
+		if i := strings.LastIndex(valueType, "_"); i >= 0 {
+			valueType = valueType[i+1:]
+		}
+		pollutant, ok := sensorCommunityPollutants[valueType]
+		if !ok {
+			// Temperature, humidity, signal strength ...
+			continue
+		}
+		concentration, err := strconv.ParseFloat(value.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", value.ValueType, value.Value)
+		}
+		parsed = append(parsed, Observation{
+			SensorID:  sensorID,
+			Pollutant: pollutant,
+			Value:     concentration,
+			Timestamp: receivedAt,
+		})
+	}
+
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("payload has no particulate matter values")
+	}
+	return parsed, nil
+}
+
+// parseTimestamp accepts RFC 3339 strings and Unix seconds or milliseconds
+func parseTimestamp(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case string:
+		timestamp, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", v)
+		}
+		return timestamp, nil
+	case float64:
+		// Values past 2286 in seconds are taken as milliseconds
+		if v > 1e10 {
+			return time.UnixMilli(int64(v)).UTC(), nil
+		}
+		return time.Unix(int64(v), 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %v", value)
+}
+
+// parseLocation reads "location" as [longitude, latitude], or "lat"/"lon"
+func parseLocation(object map[string]interface{}) ([2]float64, bool) {
+	if location, ok := object["location"].([]interface{}); ok && len(location) == 2 {
+		lon, lonOK := toFloat(location[0])
+		lat, latOK := toFloat(location[1])
+		return [2]float64{lon, lat}, lonOK && latOK
+	}
+	lat, latOK := toFloat(object["lat"])
+	lon, lonOK := toFloat(object["lon"])
+	return [2]float64{lon, lat}, lonOK && latOK
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		return parsed, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,145 @@
+package observations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Calibration maps a sensor's raw value to a reference-grade concentration:
+// calibrated = Slope*raw + Intercept
+type Calibration struct {
+	Slope     float64 `json:"slope" yaml:"slope"`
+	Intercept float64 `json:"intercept" yaml:"intercept"`
+}
+
+// Sensor is a registered community sensor
+type Sensor struct {
+	ID       string     `json:"id" yaml:"id"`
+	Name     string     `json:"name,omitempty" yaml:"name,omitempty"`
+	Location [2]float64 `json:"location" yaml:"location"`
+	// Calibration is keyed by pollutant; pollutants without one are stored
+	// uncalibrated
+	Calibration  map[string]Calibration `json:"calibration,omitempty" yaml:"calibration,omitempty"`
+	RegisteredAt time.Time              `json:"registered_at" yaml:"registered_at"`
+
+	// APIKey is the key the sensor posts observations with. It is only read
+	// on registration; the store keeps its SHA-256 in KeyHash, which is
+	// never returned by the API.
+	APIKey  string `json:"api_key,omitempty" yaml:"-"`
+	KeyHash string `json:"key_sha256,omitempty" yaml:"key_sha256,omitempty"`
+}
+
+// public returns the sensor without its key hash
+func (s Sensor) public() Sensor {
+	s.KeyHash = ""
+	return s
+}
+
+// hashKey returns the hex SHA-256 of an API key
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Calibrate applies the sensor's calibration for the pollutant to a raw value
+func (s Sensor) Calibrate(pollutant string, value float64) float64 {
+	calibration, ok := s.Calibration[pollutant]
+	if !ok {
+		return value
+	}
+	calibrated := calibration.Slope*value + calibration.Intercept
+	if calibrated < 0 {
+		return 0
+	}
+	return calibrated
+}
+
+func (s Sensor) validate() error {
+	if strings.TrimSpace(s.ID) == "" {
+		return fmt.Errorf("sensor id is required")
+	}
+	if !validLocation(s.Location) || s.Location == [2]float64{} {
+		return fmt.Errorf("sensor %q: %w", s.ID, ErrInvalidLocation)
+	}
+	for pollutant, calibration := range s.Calibration {
+		if _, ok := valueRanges[pollutant]; !ok {
+			return fmt.Errorf("sensor %q: %w: %q", s.ID, ErrUnsupportedPollutant, pollutant)
+		}
+		if calibration.Slope <= 0 {
+			return fmt.Errorf("sensor %q: %s calibration slope must be positive", s.ID, pollutant)
+		}
+	}
+	return nil
+}
+
+// sensorsFile is the on-disk format of OBSERVATION_SENSORS_FILE
+type sensorsFile struct {
+	Sensors []Sensor `json:"sensors" yaml:"sensors"`
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func loadSensorsFile(path string) ([]Sensor, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		// Written on the first registration
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading sensors %s: %w", path, err)
+	}
+
+	var file sensorsFile
+	if isYAML(path) {
+		err = yaml.Unmarshal(data, &file)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing sensors %s: %w", path, err)
+	}
+
+	for _, sensor := range file.Sensors {
+		if err := sensor.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return file.Sensors, nil
+}
+
+// saveSensorsFile writes the sensors to a temporary file and renames it over
+// path so a crash never leaves a partial file
+func saveSensorsFile(path string, sensors []Sensor) error {
+	file := sensorsFile{Sensors: sensors}
+
+	var data []byte
+	var err error
+	if isYAML(path) {
+		data, err = yaml.Marshal(file)
+	} else {
+		data, err = json.MarshalIndent(file, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("error encoding sensors: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing sensors %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing sensors %s: %w", path, err)
+	}
+	return nil
+}
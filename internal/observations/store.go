@@ -0,0 +1,263 @@
+package observations
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/geo"
+	"github.com/clean-route/go-backend/internal/history"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+var (
+	mu       sync.RWMutex
+	sensors  = map[string]Sensor{}
+	readings = map[string][]Observation{} // by sensor id, oldest first
+)
+
+// Init loads the registered sensors from OBSERVATION_SENSORS_FILE and the
+// observations within the retention period from the reading history, which
+// must be initialized first
+func Init() error {
+	loaded := map[string]Sensor{}
+	if path := config.AppConfig.ObservationSensorsFile; path != "" {
+		list, err := loadSensorsFile(path)
+		if err != nil {
+			return err
+		}
+		for _, sensor := range list {
+			if sensor.APIKey != "" {
+				sensor.KeyHash = hashKey(sensor.APIKey)
+				sensor.APIKey = ""
+			}
+			loaded[sensor.ID] = sensor
+		}
+	}
+
+	var from time.Time
+	if retention := config.AppConfig.ObservationRetention; retention > 0 {
+		from = time.Now().Add(-retention)
+	}
+	records, err := history.Find(history.Query{Kind: history.KindObservation, From: from})
+	if err != nil {
+		return fmt.Errorf("error restoring observations: %w", err)
+	}
+
+	mu.Lock()
+	sensors = loaded
+	readings = map[string][]Observation{}
+	var restored int
+	for _, record := range records {
+		for pollutant, value := range record.Values {
+			observation := Observation{
+				SensorID:  record.StationID,
+				Location:  record.Location,
+				Pollutant: pollutant,
+				Value:     value,
+				Timestamp: record.Timestamp,
+			}
+			if _, err := store(observation, time.Now()); err == nil {
+				restored++
+			}
+		}
+	}
+	mu.Unlock()
+
+	logger.Info("Observation store ready",
+		"sensors", len(loaded),
+		"observations_restored", restored,
+	)
+	return nil
+}
+
+// RegisterSensor adds a sensor or replaces the location, name, calibration
+// and, when given, API key of a registered one, and persists the registry
+// when OBSERVATION_SENSORS_FILE is set
+func RegisterSensor(sensor Sensor) (Sensor, error) {
+	if err := sensor.validate(); err != nil {
+		return Sensor{}, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if existing, ok := sensors[sensor.ID]; ok {
+		sensor.RegisteredAt = existing.RegisteredAt
+		if sensor.KeyHash == "" {
+			sensor.KeyHash = existing.KeyHash
+		}
+	} else {
+		sensor.RegisteredAt = time.Now().UTC()
+	}
+	if sensor.APIKey != "" {
+		sensor.KeyHash = hashKey(sensor.APIKey)
+		sensor.APIKey = ""
+	}
+
+	if path := config.AppConfig.ObservationSensorsFile; path != "" {
+		updated := make([]Sensor, 0, len(sensors)+1)
+		for id, existing := range sensors {
+			if id != sensor.ID {
+				updated = append(updated, existing)
+			}
+		}
+		updated = append(updated, sensor)
+		sortSensors(updated)
+		if err := saveSensorsFile(path, updated); err != nil {
+			return Sensor{}, err
+		}
+	}
+
+	sensors[sensor.ID] = sensor
+	return sensor.public(), nil
+}
+
+// Authorize checks that key is the API key registered for a sensor
+func Authorize(sensorID, key string) error {
+	mu.RLock()
+	sensor, ok := sensors[sensorID]
+	mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownSensor, sensorID)
+	}
+	if sensor.KeyHash == "" || subtle.ConstantTimeCompare([]byte(hashKey(key)), []byte(sensor.KeyHash)) != 1 {
+		return fmt.Errorf("%w: %q", ErrKeyNotBound, sensorID)
+	}
+	return nil
+}
+
+// Sensors returns the registered sensors ordered by id
+func Sensors() []Sensor {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]Sensor, 0, len(sensors))
+	for _, sensor := range sensors {
+		list = append(list, sensor.public())
+	}
+	sortSensors(list)
+	return list
+}
+
+func sortSensors(list []Sensor) {
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+}
+
+// Add validates an observation, calibrates it and stores it, persisting it
+// in the reading history. The returned observation carries the sensor
+// location when it had none.
+func Add(observation Observation) (Observation, error) {
+	now := time.Now()
+	retention := config.AppConfig.ObservationRetention
+	if err := observation.validate(now, retention, config.AppConfig.ObservationMaxClockSkew); err != nil {
+		return Observation{}, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	stored, err := store(observation, now)
+	if err != nil {
+		return Observation{}, err
+	}
+
+	history.Add(history.Record{
+		Kind:      history.KindObservation,
+		Source:    models.ProviderObservations,
+		StationID: stored.SensorID,
+		Location:  stored.Location,
+		Timestamp: stored.Timestamp,
+		Values:    map[string]float64{stored.Pollutant: stored.Value},
+	})
+	return stored, nil
+}
+
+// store calibrates an observation of a registered sensor and inserts it
+// into the sensor's history. Must be called with mu held.
+func store(observation Observation, now time.Time) (Observation, error) {
+	retention := config.AppConfig.ObservationRetention
+	sensor, ok := sensors[observation.SensorID]
+	if !ok {
+		return Observation{}, fmt.Errorf("%w: %q", ErrUnknownSensor, observation.SensorID)
+	}
+	if !observation.hasLocation() {
+		observation.Location = sensor.Location
+	}
+	observation.Timestamp = observation.Timestamp.UTC()
+	observation.Calibrated = sensor.Calibrate(observation.Pollutant, observation.Value)
+
+	series := readings[observation.SensorID]
+	for _, stored := range series {
+		if stored.Pollutant == observation.Pollutant && stored.Timestamp.Equal(observation.Timestamp) {
+			return Observation{}, ErrDuplicate
+		}
+	}
+
+	// Keep the series ordered; readings mostly arrive in order
+	index := sort.Search(len(series), func(i int) bool {
+		return series[i].Timestamp.After(observation.Timestamp)
+	})
+	series = append(series, Observation{})
+	copy(series[index+1:], series[index:])
+	series[index] = observation
+
+	readings[observation.SensorID] = prune(series, now, retention)
+	return observation, nil
+}
+
+// prune drops the readings older than the retention period
+func prune(history []Observation, now time.Time, retention time.Duration) []Observation {
+	if retention <= 0 {
+		return history
+	}
+	cutoff := now.Add(-retention)
+	index := sort.Search(len(history), func(i int) bool {
+		return !history[i].Timestamp.Before(cutoff)
+	})
+	return history[index:]
+}
+
+// Nearby is the latest reading of a sensor near a location
+type Nearby struct {
+	Observation
+	Sensor     Sensor
+	DistanceKm float64
+}
+
+// Near returns the latest reading of the pollutant from every sensor within
+// radiusKm of the location ([longitude, latitude]) that is at most maxAge
+// old, nearest first. Zero radius or age means no limit.
+func Near(location [2]float64, pollutant string, radiusKm float64, maxAge time.Duration) []Nearby {
+	now := time.Now()
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var nearby []Nearby
+	for id, history := range readings {
+		for i := len(history) - 1; i >= 0; i-- {
+			observation := history[i]
+			if observation.Pollutant != pollutant {
+				continue
+			}
+			if maxAge > 0 && now.Sub(observation.Timestamp) > maxAge {
+				break
+			}
+
+			km := geo.HaversineDistance(location[1], location[0], observation.Location[1], observation.Location[0]) / 1000
+			if radiusKm <= 0 || km <= radiusKm {
+				nearby = append(nearby, Nearby{Observation: observation, Sensor: sensors[id].public(), DistanceKm: km})
+			}
+			break
+		}
+	}
+
+	sort.Slice(nearby, func(i, j int) bool { return nearby[i].DistanceKm < nearby[j].DistanceKm })
+	return nearby
+}
@@ -0,0 +1,141 @@
+package observations
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/history"
+)
+
+func TestAuthorize(t *testing.T) {
+	setupStore(t)
+	for _, sensor := range []Sensor{
+		{ID: "keyed", Location: [2]float64{77.59, 12.97}, APIKey: "keyed-secret"},
+		{ID: "keyless", Location: [2]float64{77.60, 12.97}},
+	} {
+		if _, err := RegisterSensor(sensor); err != nil {
+			t.Fatalf("RegisterSensor: %v", err)
+		}
+	}
+	// Updating without a key keeps the registered one
+	if _, err := RegisterSensor(Sensor{ID: "keyed", Name: "Rooftop", Location: [2]float64{77.59, 12.97}}); err != nil {
+		t.Fatalf("RegisterSensor: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		sensorID string
+		key      string
+		want     error
+	}{
+		{"registered key", "keyed", "keyed-secret", nil},
+		{"other key", "keyed", "other-secret", ErrKeyNotBound},
+		{"sensor without a key", "keyless", "keyed-secret", ErrKeyNotBound},
+		{"unknown sensor", "missing", "keyed-secret", ErrUnknownSensor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Authorize(tt.sensorID, tt.key); !errors.Is(err, tt.want) {
+				t.Errorf("Authorize error %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	for _, sensor := range Sensors() {
+		if sensor.KeyHash != "" || sensor.APIKey != "" {
+			t.Errorf("sensor %s is listed with its key", sensor.ID)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	setupStore(t, "s1")
+	config.AppConfig.ObservationRetention = time.Hour
+	config.AppConfig.ObservationMaxClockSkew = time.Minute
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if _, err := Add(Observation{SensorID: "s1", Pollutant: "pm25", Value: 30, Timestamp: now}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		observation Observation
+		want        error
+	}{
+		{"duplicate", Observation{SensorID: "s1", Pollutant: "pm25", Value: 31, Timestamp: now}, ErrDuplicate},
+		{"unknown sensor", Observation{SensorID: "s9", Pollutant: "pm25", Value: 31, Timestamp: now}, ErrUnknownSensor},
+		{"out of range", Observation{SensorID: "s1", Pollutant: "pm25", Value: 1500, Timestamp: now}, ErrOutOfRange},
+		{"unsupported pollutant", Observation{SensorID: "s1", Pollutant: "o3", Value: 31, Timestamp: now}, ErrUnsupportedPollutant},
+		{"in the future", Observation{SensorID: "s1", Pollutant: "pm25", Value: 31, Timestamp: now.Add(time.Hour)}, ErrClockSkew},
+		{"too old", Observation{SensorID: "s1", Pollutant: "pm25", Value: 31, Timestamp: now.Add(-2 * time.Hour)}, ErrTooOld},
+		{"other pollutant at the same time", Observation{SensorID: "s1", Pollutant: "pm10", Value: 31, Timestamp: now}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Add(tt.observation); !errors.Is(err, tt.want) {
+				t.Errorf("Add error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestObservationsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	config.AppConfig = &config.Config{
+		HistoryDir:             dir,
+		HistoryRetention:       24 * time.Hour,
+		ObservationSensorsFile: filepath.Join(dir, "sensors.json"),
+		ObservationRetention:   time.Hour,
+	}
+	if err := history.Init(); err != nil {
+		t.Fatalf("history.Init: %v", err)
+	}
+	defer history.Close()
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	sensor := Sensor{
+		ID:          "s1",
+		Location:    [2]float64{77.59, 12.97},
+		Calibration: map[string]Calibration{"pm25": {Slope: 0.5}},
+	}
+	if _, err := RegisterSensor(sensor); err != nil {
+		t.Fatalf("RegisterSensor: %v", err)
+	}
+
+	now := time.Now().UTC()
+	// Received out of order
+	for _, minutes := range []int{5, 15, 10} {
+		observation := Observation{SensorID: "s1", Pollutant: "pm25", Value: float64(minutes), Timestamp: now.Add(-time.Duration(minutes) * time.Minute)}
+		if _, err := Add(observation); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	// A restart writes the queued history and reloads the store; a shorter
+	// retention then drops the oldest observation
+	if err := history.Init(); err != nil {
+		t.Fatalf("history.Init: %v", err)
+	}
+	config.AppConfig.ObservationRetention = 12 * time.Minute
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	mu.RLock()
+	series := readings["s1"]
+	mu.RUnlock()
+	if len(series) != 2 {
+		t.Fatalf("restored %d observations, want the 2 within the retention", len(series))
+	}
+	for i, want := range []float64{10, 5} {
+		if series[i].Value != want || series[i].Calibrated != want/2 {
+			t.Errorf("observation %d: %g calibrated to %g, want %g calibrated to %g",
+				i, series[i].Value, series[i].Calibrated, want, want/2)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package observations
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/mqtt"
+)
+
+// Reconnect delays double from the minimum up to the maximum
+var (
+	mqtt_min_backoff = time.Second
+	mqtt_max_backoff = time.Minute
+)
+
+// TopicRule maps the messages on an MQTT topic filter to observations
+type TopicRule struct {
+	Topic string `json:"topic" yaml:"topic"`
+	QoS   byte   `json:"qos" yaml:"qos"`
+	// Format is FormatJSON (default) or FormatSensorCommunity
+	Format string `json:"format" yaml:"format"`
+	// SensorIDLevel, when set, takes the sensor id from that topic level
+	// (1-based), e.g. 2 for sensors/<id>/pm
+	SensorIDLevel int `json:"sensor_id_level,omitempty" yaml:"sensor_id_level,omitempty"`
+	// Fields renames JSON payload keys to observation fields, e.g.
+	// {"pm2_5": "pm25", "device": "sensor_id"}
+	Fields map[string]string `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+func (r TopicRule) validate() error {
+	if r.Topic == "" {
+		return fmt.Errorf("MQTT topic rule needs a topic")
+	}
+	if r.QoS > 2 {
+		return fmt.Errorf("MQTT topic %q: qos must be 0, 1 or 2", r.Topic)
+	}
+	if r.Format != FormatJSON && r.Format != FormatSensorCommunity {
+		return fmt.Errorf("MQTT topic %q: unknown format %q", r.Topic, r.Format)
+	}
+	return nil
+}
+
+// sensorID returns the sensor id from the topic level the rule names
+func (r TopicRule) sensorID(topic string) string {
+	if r.SensorIDLevel <= 0 {
+		return ""
+	}
+	levels := strings.Split(topic, "/")
+	if r.SensorIDLevel > len(levels) {
+		return ""
+	}
+	return levels[r.SensorIDLevel-1]
+}
+
+// topicsFile is the on-disk format of MQTT_TOPICS_FILE
+type topicsFile struct {
+	Topics []TopicRule `json:"topics" yaml:"topics"`
+}
+
+// SubscriberStats reports the state of the MQTT subscriber
+type SubscriberStats struct {
+	Enabled   bool   `json:"enabled"`
+	Connected bool   `json:"connected"`
+	Received  uint64 `json:"received"`
+	Accepted  uint64 `json:"accepted"`
+	Rejected  uint64 `json:"rejected"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+var (
+	subscriberMu    sync.Mutex
+	subscriberStop  chan struct{}
+	subscriberStats SubscriberStats
+
+	received, accepted, rejected atomic.Uint64
+)
+
+// StartSubscriber subscribes to the topics configured by MQTT_TOPICS_FILE or
+// MQTT_TOPICS on MQTT_BROKER_URL and stores the observations received. The
+// connection is kept up in the background, reconnecting with backoff. It is
+// a no-op without a broker.
+func StartSubscriber() error {
+	broker := config.AppConfig.MQTTBrokerURL
+	if broker == "" {
+		return nil
+	}
+
+	rules, err := loadTopicRules()
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return fmt.Errorf("MQTT_BROKER_URL is set but no MQTT topics are configured")
+	}
+
+	opts := mqtt.Options{
+		Broker:   broker,
+		ClientID: config.AppConfig.MQTTClientID,
+		Username: config.AppConfig.MQTTUsername,
+		Password: config.AppConfig.MQTTPassword,
+	}
+
+	subscriberMu.Lock()
+	defer subscriberMu.Unlock()
+	if subscriberStop != nil {
+		return fmt.Errorf("MQTT subscriber is already running")
+	}
+	subscriberStop = make(chan struct{})
+	subscriberStats = SubscriberStats{Enabled: true}
+
+	go runSubscriber(opts, rules, subscriberStop)
+
+	logger.Info("MQTT subscriber started",
+		"broker", broker,
+		"topics", len(rules),
+	)
+	return nil
+}
+
+// StopSubscriber disconnects the MQTT subscriber
+func StopSubscriber() {
+	subscriberMu.Lock()
+	defer subscriberMu.Unlock()
+	if subscriberStop != nil {
+		close(subscriberStop)
+		subscriberStop = nil
+	}
+}
+
+// Stats returns the MQTT subscriber counters
+func Stats() SubscriberStats {
+	subscriberMu.Lock()
+	stats := subscriberStats
+	subscriberMu.Unlock()
+
+	stats.Received = received.Load()
+	stats.Accepted = accepted.Load()
+	stats.Rejected = rejected.Load()
+	return stats
+}
+
+func setConnected(connected bool, err error) {
+	subscriberMu.Lock()
+	defer subscriberMu.Unlock()
+	subscriberStats.Connected = connected
+	if err != nil {
+		subscriberStats.LastError = err.Error()
+	}
+}
+
+func loadTopicRules() ([]TopicRule, error) {
+	var rules []TopicRule
+
+	if path := config.AppConfig.MQTTTopicsFile; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading MQTT topics %s: %w", path, err)
+		}
+		var file topicsFile
+		if isYAML(path) {
+			err = yaml.Unmarshal(data, &file)
+		} else {
+			err = json.Unmarshal(data, &file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing MQTT topics %s: %w", path, err)
+		}
+		rules = file.Topics
+	}
+
+	for _, topic := range config.AppConfig.MQTTTopics {
+		rules = append(rules, TopicRule{Topic: topic, QoS: config.AppConfig.MQTTQoS})
+	}
+
+	for i := range rules {
+		if rules[i].Format == "" {
+			rules[i].Format = FormatJSON
+		}
+		if err := rules[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+func runSubscriber(opts mqtt.Options, rules []TopicRule, stop chan struct{}) {
+	subscriptions := make([]mqtt.Subscription, len(rules))
+	for i, rule := range rules {
+		subscriptions[i] = mqtt.Subscription{Filter: rule.Topic, QoS: rule.QoS}
+	}
+
+	backoff := mqtt_min_backoff
+	for {
+		err := subscribeOnce(opts, subscriptions, rules, stop, func() { backoff = mqtt_min_backoff })
+		setConnected(false, err)
+
+		select {
+		case <-stop:
+			logger.Info("MQTT subscriber stopped")
+			return
+		default:
+		}
+
+		logger.Warn("MQTT connection failed, reconnecting",
+			"error", err.Error(),
+			"broker", opts.Broker,
+			"retry_in", backoff.String(),
+		)
+
+		select {
+		case <-stop:
+			logger.Info("MQTT subscriber stopped")
+			return
+		case <-time.After(backoff):
+		}
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// nextBackoff doubles the reconnect delay up to mqtt_max_backoff
+func nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > mqtt_max_backoff {
+		return mqtt_max_backoff
+	}
+	return backoff
+}
+
+// subscribeOnce connects, subscribes and handles messages until the
+// connection is lost or stop is closed
+func subscribeOnce(opts mqtt.Options, subscriptions []mqtt.Subscription, rules []TopicRule, stop chan struct{}, connected func()) error {
+	client, err := mqtt.Dial(opts)
+	if err != nil {
+		return err
+	}
+	if err := client.Subscribe(subscriptions); err != nil {
+		client.Close()
+		return err
+	}
+
+	connected()
+	setConnected(true, nil)
+	logger.Info("MQTT subscriber connected", "broker", opts.Broker)
+
+	go func() {
+		<-stop
+		client.Close()
+	}()
+	return client.Run(func(message mqtt.Message) {
+		handleMessage(rules, message)
+	})
+}
+
+// handleMessage stores the observations in a message using the first rule
+// whose topic filter matches
+func handleMessage(rules []TopicRule, message mqtt.Message) {
+	received.Add(1)
+
+	for _, rule := range rules {
+		if !mqtt.MatchTopic(rule.Topic, message.Topic) {
+			continue
+		}
+
+		now := time.Now().UTC()
+		var parsed []Observation
+		var err error
+		if rule.Format == FormatSensorCommunity {
+			parsed, err = parseSensorCommunityPayload(message.Payload, rule.sensorID(message.Topic), now)
+		} else {
+			parsed, err = parseJSONPayload(message.Payload, rule.Fields, rule.sensorID(message.Topic), now)
+		}
+		if err != nil {
+			rejected.Add(1)
+			logger.Debug("Rejected MQTT message",
+				"topic", message.Topic,
+				"error", err.Error(),
+			)
+			return
+		}
+
+		for _, observation := range parsed {
+			if _, err := Add(observation); err != nil {
+				rejected.Add(1)
+				logger.Debug("Rejected MQTT observation",
+					"topic", message.Topic,
+					"sensor_id", observation.SensorID,
+					"error", err.Error(),
+				)
+				continue
+			}
+			accepted.Add(1)
+		}
+		return
+	}
+}
@@ -0,0 +1,182 @@
+package observations
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/mqtt"
+)
+
+func setupStore(t *testing.T, ids ...string) {
+	t.Helper()
+	config.AppConfig = &config.Config{}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	for i, id := range ids {
+		sensor := Sensor{ID: id, Location: [2]float64{77.59 + float64(i)/100, 12.97}}
+		if _, err := RegisterSensor(sensor); err != nil {
+			t.Fatalf("RegisterSensor: %v", err)
+		}
+	}
+}
+
+// latest returns the latest pm25 value stored per sensor
+func latest() map[string]float64 {
+	values := map[string]float64{}
+	for _, nearby := range Near([2]float64{77.59, 12.97}, "pm25", 0, 0) {
+		values[nearby.SensorID] = nearby.Value
+	}
+	return values
+}
+
+func TestNextBackoff(t *testing.T) {
+	tests := []struct {
+		backoff, want time.Duration
+	}{
+		{time.Second, 2 * time.Second},
+		{16 * time.Second, 32 * time.Second},
+		{32 * time.Second, time.Minute},
+		{time.Minute, time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := nextBackoff(tt.backoff); got != tt.want {
+			t.Errorf("nextBackoff(%s) = %s, want %s", tt.backoff, got, tt.want)
+		}
+	}
+}
+
+func TestHandleMessageMapsTopicRules(t *testing.T) {
+	setupStore(t, "s1", "esp8266-42", "s3")
+
+	rules := []TopicRule{
+		{Topic: "sensors/+/pm", Format: FormatJSON, SensorIDLevel: 2, Fields: map[string]string{"pm2_5": "pm25"}},
+		{Topic: "airrohr/#", Format: FormatSensorCommunity},
+		{Topic: "sensors/#", Format: FormatJSON},
+	}
+	messages := []mqtt.Message{
+		// Sensor id from the second topic level, renamed field
+		{Topic: "sensors/s1/pm", Payload: []byte(`{"pm2_5": 31}`)},
+		{Topic: "airrohr/node", Payload: []byte(`{"esp8266id": "42", "sensordatavalues": [{"value_type": "SDS_P2", "value": "18.5"}]}`)},
+		// The first matching rule decides, so this JSON is not renamed
+		{Topic: "sensors/s3/pm", Payload: []byte(`{"sensor_id": "s3", "pm2_5": 99}`)},
+		// Only the catch-all rule matches
+		{Topic: "sensors/s3", Payload: []byte(`{"sensor_id": "s3", "pm25": 7}`)},
+	}
+	for _, message := range messages {
+		handleMessage(rules, message)
+	}
+
+	want := map[string]float64{"s1": 31, "esp8266-42": 18.5, "s3": 7}
+	got := latest()
+	if len(got) != len(want) {
+		t.Fatalf("stored %v, want %v", got, want)
+	}
+	for id, value := range want {
+		if got[id] != value {
+			t.Errorf("sensor %s: pm25 %g, want %g", id, got[id], value)
+		}
+	}
+}
+
+func TestSubscriberReconnectsWithBackoff(t *testing.T) {
+	setupStore(t, "s1")
+	mqtt_min_backoff = 50 * time.Millisecond
+	defer func() { mqtt_min_backoff = time.Second }()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan time.Time, 2)
+	go func() {
+		// The first connection is refused as unavailable
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- time.Now()
+		readClientPacket(conn)
+		conn.Write([]byte{0x20, 2, 0, 3})
+		conn.Close()
+
+		conn, err = listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		accepted <- time.Now()
+		readClientPacket(conn)
+		conn.Write([]byte{0x20, 2, 0, 0})
+		subscribe := readClientPacket(conn)
+		if len(subscribe) < 2 {
+			return
+		}
+		conn.Write([]byte{0x90, 3, subscribe[0], subscribe[1], 0})
+
+		topic, payload := "sensors/s1/pm", `{"pm2_5": 24}`
+		body := binary.BigEndian.AppendUint16(nil, uint16(len(topic)))
+		body = append(append(body, topic...), payload...)
+		conn.Write(append([]byte{0x30, byte(len(body))}, body...))
+		io.Copy(io.Discard, conn)
+	}()
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	rules := []TopicRule{{Topic: "sensors/+/pm", Format: FormatJSON, SensorIDLevel: 2, Fields: map[string]string{"pm2_5": "pm25"}}}
+	go func() {
+		runSubscriber(mqtt.Options{Broker: "tcp://" + listener.Addr().String(), ClientID: "test"}, rules, stop)
+		close(stopped)
+	}()
+	defer func() {
+		close(stop)
+		<-stopped
+	}()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for latest()["s1"] != 24 {
+		if time.Now().After(deadline) {
+			t.Fatal("no observation received after reconnecting")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	first, second := <-accepted, <-accepted
+	if wait := second.Sub(first); wait < mqtt_min_backoff {
+		t.Errorf("reconnected after %s, want at least %s", wait, mqtt_min_backoff)
+	}
+}
+
+// readClientPacket reads a packet sent by the client and returns its body
+func readClientPacket(conn net.Conn) []byte {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	reader := bufio.NewReader(conn)
+	if _, err := reader.ReadByte(); err != nil {
+		return nil
+	}
+	length, multiplier := 0, 1
+	for {
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return nil
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil
+	}
+	return body
+}
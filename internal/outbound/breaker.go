@@ -0,0 +1,181 @@
+package outbound
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// breaker is a circuit breaker that opens when the failure rate of the last
+// window calls reaches errorRate, rejects calls for cooldown, then lets one
+// probe call through and closes again if it succeeds
+type breaker struct {
+	window    int
+	minCalls  int
+	errorRate float64
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	outcomes []bool // ring buffer of recent results, true for failures
+	next     int
+	calls    int
+	failures int
+	openedAt time.Time
+	probing  bool
+	trips    uint64
+	// generation changes whenever the breaker opens or closes, so that
+	// calls let through before then do not count towards the new state
+	generation uint64
+}
+
+// permit is handed out by allow and identifies the call whose result is
+// recorded
+type permit struct {
+	generation uint64
+	probe      bool
+}
+
+func newBreaker(window, minCalls int, errorRate float64, cooldown time.Duration) *breaker {
+	if window < 1 {
+		window = 1
+	}
+	return &breaker{
+		window:    window,
+		minCalls:  minCalls,
+		errorRate: errorRate,
+		cooldown:  cooldown,
+		state:     StateClosed,
+		outcomes:  make([]bool, window),
+	}
+}
+
+// allow reports whether a call may proceed and returns the permit its
+// result is recorded with
+func (b *breaker) allow(now time.Time) (permit, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return permit{}, false
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return permit{generation: b.generation, probe: true}, true
+	case StateHalfOpen:
+		if b.probing {
+			return permit{}, false
+		}
+		b.probing = true
+		return permit{generation: b.generation, probe: true}, true
+	}
+	return permit{generation: b.generation}, true
+}
+
+// rejects reports whether allow would reject a call now, without taking the
+// half-open probe
+func (b *breaker) rejects(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		return now.Sub(b.openedAt) < b.cooldown
+	case StateHalfOpen:
+		return b.probing
+	}
+	return false
+}
+
+// record adds the result of a call that allow let through. While half-open
+// only the probe decides the state; results of calls let through before the
+// breaker last opened or closed are ignored.
+func (b *breaker) record(p permit, failed bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if p.generation != b.generation {
+		return
+	}
+	if b.state == StateHalfOpen {
+		if !p.probe {
+			return
+		}
+		b.probing = false
+		if failed {
+			b.open(now)
+		} else {
+			b.reset()
+		}
+		return
+	}
+	if b.state == StateOpen {
+		return
+	}
+
+	if b.calls == b.window {
+		if b.outcomes[b.next] {
+			b.failures--
+		}
+	} else {
+		b.calls++
+	}
+	b.outcomes[b.next] = failed
+	if failed {
+		b.failures++
+	}
+	b.next = (b.next + 1) % b.window
+
+	if b.errorRate > 0 && b.calls >= b.minCalls && float64(b.failures)/float64(b.calls) >= b.errorRate {
+		b.open(now)
+	}
+}
+
+// cancel gives up a call that allow let through without recording a result,
+// freeing the half-open probe if the call was it
+func (b *breaker) cancel(p permit) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if p.probe && p.generation == b.generation && b.state == StateHalfOpen {
+		b.probing = false
+	}
+}
+
+// open trips the breaker. Must be called with mu held.
+func (b *breaker) open(now time.Time) {
+	b.state = StateOpen
+	b.openedAt = now
+	b.trips++
+	b.generation++
+}
+
+// reset closes the breaker with an empty window. Must be called with mu held.
+func (b *breaker) reset() {
+	b.state = StateClosed
+	b.generation++
+	b.outcomes = make([]bool, b.window)
+	b.next, b.calls, b.failures = 0, 0, 0
+}
+
+// status describes the breaker for health checks
+func (b *breaker) status() (state string, failureRate float64, openedAt *time.Time, trips uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.calls > 0 {
+		failureRate = float64(b.failures) / float64(b.calls)
+	}
+	if b.state != StateClosed {
+		opened := b.openedAt
+		openedAt = &opened
+	}
+	return b.state, failureRate, openedAt, b.trips
+}
@@ -0,0 +1,125 @@
+package outbound
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// Each step lets a call through (or not) and records its result
+	type step struct {
+		after     time.Duration
+		failed    bool
+		wantAllow bool
+		wantState string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"stays closed below min calls", []step{
+			{0, true, true, StateClosed},
+			{0, true, true, StateClosed},
+		}},
+		{"opens at the error rate", []step{
+			{0, false, true, StateClosed},
+			{0, true, true, StateClosed},
+			{0, true, true, StateOpen},
+		}},
+		{"rejects during cooldown", []step{
+			{0, true, true, StateClosed},
+			{0, true, true, StateClosed},
+			{0, true, true, StateOpen},
+			{30 * time.Second, false, false, StateOpen},
+		}},
+		{"probe success closes", []step{
+			{0, true, true, StateClosed},
+			{0, true, true, StateClosed},
+			{0, true, true, StateOpen},
+			{time.Minute, false, true, StateClosed},
+			{time.Minute, true, true, StateClosed},
+		}},
+		{"probe failure reopens", []step{
+			{0, true, true, StateClosed},
+			{0, true, true, StateClosed},
+			{0, true, true, StateOpen},
+			{time.Minute, true, true, StateOpen},
+			{90 * time.Second, false, false, StateOpen},
+		}},
+		{"old failures leave the window", []step{
+			{0, true, true, StateClosed},
+			{0, false, true, StateClosed},
+			{0, false, true, StateClosed},
+			{0, false, true, StateClosed},
+			{0, true, true, StateClosed},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(3, 3, 0.6, time.Minute)
+			for i, s := range tt.steps {
+				now := start.Add(s.after)
+				p, ok := b.allow(now)
+				if ok != s.wantAllow {
+					t.Fatalf("step %d: allow = %v, want %v", i, ok, s.wantAllow)
+				}
+				if ok {
+					b.record(p, s.failed, now)
+				}
+				if state, _, _, _ := b.status(); state != s.wantState {
+					t.Fatalf("step %d: state %s, want %s", i, state, s.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerOnlyProbeDecidesHalfOpen(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	b := newBreaker(2, 2, 0.5, time.Minute)
+
+	// A slow call started while closed, then failures open the breaker
+	slow, _ := b.allow(start)
+	for i := 0; i < 2; i++ {
+		p, _ := b.allow(start)
+		b.record(p, true, start)
+	}
+
+	probe, ok := b.allow(start.Add(time.Minute))
+	if !ok || !probe.probe {
+		t.Fatal("no probe let through after the cooldown")
+	}
+	if _, ok := b.allow(start.Add(time.Minute)); ok {
+		t.Fatal("second call let through while probing")
+	}
+
+	// The slow call succeeding says nothing about the probe
+	b.record(slow, false, start.Add(time.Minute))
+	if state, _, _, _ := b.status(); state != StateHalfOpen {
+		t.Fatalf("state %s after a non-probe result, want half-open", state)
+	}
+
+	b.record(probe, true, start.Add(time.Minute))
+	if state, _, _, trips := b.status(); state != StateOpen || trips != 2 {
+		t.Fatalf("state %s after %d trips, want open after the failed probe", state, trips)
+	}
+}
+
+func TestBreakerCancelFreesProbe(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	b := newBreaker(1, 1, 1, time.Minute)
+	p, _ := b.allow(start)
+	b.record(p, true, start)
+
+	probe, _ := b.allow(start.Add(time.Minute))
+	b.cancel(probe)
+	if state, _, _, _ := b.status(); state != StateHalfOpen {
+		t.Fatalf("state %s after a cancelled probe, want half-open", state)
+	}
+	if _, ok := b.allow(start.Add(time.Minute)); !ok {
+		t.Error("no new probe let through after the probe was cancelled")
+	}
+}
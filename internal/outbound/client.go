@@ -0,0 +1,270 @@
+// Package outbound is the shared HTTP client for upstream providers. Each
+// provider gets a client with its own timeout, retries with jittered
+// exponential backoff for idempotent calls, a circuit breaker that stops
+// calling a provider whose recent calls mostly failed, and a rate limiter
+// that enforces the provider's quotas.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+)
+
+// ErrCircuitOpen is returned without calling the provider while its circuit
+// breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Client calls one upstream provider
+type Client struct {
+	name    string
+	http    *http.Client
+	retries int
+	backoff time.Duration
+	maxWait time.Duration
+	breaker *breaker
+	limiter *limiter
+
+	requests atomic.Uint64
+	failures atomic.Uint64
+	retried  atomic.Uint64
+	rejected atomic.Uint64
+}
+
+// Status is a provider client's circuit breaker state and call counts
+type Status struct {
+	State          string      `json:"state"`
+	FailureRate    float64     `json:"failure_rate"`
+	OpenedAt       *time.Time  `json:"opened_at,omitempty"`
+	Trips          uint64      `json:"trips"`
+	TimeoutSeconds float64     `json:"timeout_seconds"`
+	Requests       uint64      `json:"requests"`
+	Failures       uint64      `json:"failures"`
+	Retries        uint64      `json:"retries"`
+	Rejected       uint64      `json:"rejected"`
+	Quota          QuotaStatus `json:"quota"`
+}
+
+var (
+	clientsMu sync.Mutex
+	clients   = map[string]*Client{}
+)
+
+// For returns the client of a provider, created on first use with the
+// provider's OUTBOUND_TIMEOUTS entry or OUTBOUND_TIMEOUT_SECONDS
+func For(name string) *Client {
+	timeout, ok := config.AppConfig.OutboundTimeouts[name]
+	if !ok {
+		timeout = config.AppConfig.OutboundTimeout
+	}
+	return WithTimeout(name, timeout)
+}
+
+// WithTimeout returns the client of a provider whose timeout is set by its
+// own configuration, such as a model's MODEL_TIMEOUT_SECONDS. The timeout of
+// an existing client is kept.
+func WithTimeout(name string, timeout time.Duration) *Client {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	if c, ok := clients[name]; ok {
+		return c
+	}
+	cfg := config.AppConfig
+	c := &Client{
+		name:    name,
+		http:    &http.Client{Timeout: timeout},
+		retries: cfg.OutboundRetries,
+		backoff: cfg.OutboundBackoff,
+		maxWait: cfg.OutboundBackoffMax,
+		breaker: newBreaker(cfg.BreakerWindow, cfg.BreakerMinCalls, cfg.BreakerErrorRate, cfg.BreakerCooldown),
+		limiter: limiterFor(name),
+	}
+	clients[name] = c
+	return c
+}
+
+type idempotentKey struct{}
+
+// Idempotent marks a request with a non-idempotent method, such as a POST to
+// a side-effect-free model endpoint, as safe to retry. Its body must be
+// replayable (http.NewRequest sets GetBody for in-memory bodies).
+func Idempotent(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), idempotentKey{}, true))
+}
+
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	marked, _ := req.Context().Value(idempotentKey{}).(bool)
+	return marked && (req.Body == nil || req.GetBody != nil)
+}
+
+// failedStatus reports whether a response counts as a provider failure.
+// Other 4xx responses are the caller's fault and leave the breaker alone.
+func failedStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// Do sends a request, retrying idempotent requests that fail with a network
+// error, 429 or 5xx up to OUTBOUND_RETRIES times. Every attempt sent takes a
+// token from the provider's rate limiter and counts against its quotas. The
+// final response is returned whatever its status, as with http.Client.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	if retryable(req) {
+		attempts += c.retries
+	}
+
+	for attempt := 0; ; attempt++ {
+		// Calls the breaker rejects are not sent, so they must not count
+		// against the quotas; quota rejections in turn must neither count as
+		// provider failures nor take the breaker's half-open probe
+		if c.breaker.rejects(time.Now()) {
+			c.rejected.Add(1)
+			return nil, fmt.Errorf("%s: %w", c.name, ErrCircuitOpen)
+		}
+		if err := c.limiter.acquire(req.Context()); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.name, err)
+		}
+		permit, ok := c.breaker.allow(time.Now())
+		if !ok {
+			// Another call took the probe or opened the breaker meanwhile
+			c.limiter.refund()
+			c.rejected.Add(1)
+			return nil, fmt.Errorf("%s: %w", c.name, ErrCircuitOpen)
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				c.breaker.cancel(permit)
+				c.limiter.refund()
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		c.requests.Add(1)
+		resp, err := c.http.Do(req)
+		failed := err != nil || failedStatus(resp.StatusCode)
+		if err != nil && req.Context().Err() != nil {
+			// The caller gave up; that says nothing about the provider
+			c.breaker.cancel(permit)
+			return nil, err
+		}
+		c.breaker.record(permit, failed, time.Now())
+		if !failed {
+			return resp, nil
+		}
+		c.failures.Add(1)
+
+		if attempt+1 >= attempts {
+			return resp, err
+		}
+
+		wait := c.backoffFor(attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if after := retryAfter(resp); after > 0 && after <= c.maxWait {
+				wait = after
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		logger.Warn("Retrying upstream call",
+			"provider", c.name,
+			"attempt", attempt+1,
+			"reason", reason,
+			"wait_ms", wait.Milliseconds(),
+		)
+		c.retried.Add(1)
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// backoffFor returns a random wait up to the exponential backoff of the
+// attempt ("full jitter"), capped at OUTBOUND_BACKOFF_MAX_MS
+func (c *Client) backoffFor(attempt int) time.Duration {
+	ceiling := c.backoff << attempt
+	if ceiling <= 0 || ceiling > c.maxWait {
+		ceiling = c.maxWait
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)) + 1)
+}
+
+// retryAfter parses a Retry-After header given in seconds
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Status returns the client's breaker state and call counts
+func (c *Client) Status() Status {
+	state, rate, openedAt, trips := c.breaker.status()
+	return Status{
+		State:          state,
+		FailureRate:    rate,
+		OpenedAt:       openedAt,
+		Trips:          trips,
+		TimeoutSeconds: c.http.Timeout.Seconds(),
+		Requests:       c.requests.Load(),
+		Failures:       c.failures.Load(),
+		Retries:        c.retried.Load(),
+		Rejected:       c.rejected.Load(),
+		Quota:          c.limiter.status(),
+	}
+}
+
+// Statuses returns the status of every provider client used so far
+func Statuses() map[string]Status {
+	clientsMu.Lock()
+	snapshot := make(map[string]*Client, len(clients))
+	for name, c := range clients {
+		snapshot[name] = c
+	}
+	clientsMu.Unlock()
+
+	statuses := make(map[string]Status, len(snapshot))
+	for name, c := range snapshot {
+		statuses[name] = c.Status()
+	}
+	return statuses
+}
+
+// Healthy reports whether no provider's circuit breaker is open and no
+// provider is past its hard quota threshold
+func Healthy() bool {
+	for _, status := range Statuses() {
+		if status.State == StateOpen || status.Quota.State == QuotaHard {
+			return false
+		}
+	}
+	return true
+}
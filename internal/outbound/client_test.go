@@ -0,0 +1,56 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+)
+
+func TestOpenBreakerRejectionsUseNoQuota(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	l := &limiter{name: "test"}
+	l.configure(Quota{PerMinute: 60, Burst: 5, Daily: 3, SoftThreshold: 0.5, HardThreshold: 1, SoftAction: SoftActionAlert}, usage{})
+	c := &Client{name: "test", http: &http.Client{}, breaker: newBreaker(1, 1, 1, time.Minute), limiter: l}
+
+	p, _ := c.breaker.allow(time.Now())
+	c.breaker.record(p, true, time.Now())
+
+	for i := 0; i < 5; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:1/", nil)
+		if _, err := c.Do(req); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Do error %v, want ErrCircuitOpen", err)
+		}
+	}
+
+	status := c.Status()
+	if status.Quota.Daily != 0 || status.Quota.Monthly != 0 || status.Quota.State != QuotaOK {
+		t.Errorf("quota %+v after rejected calls, want no usage", status.Quota)
+	}
+	if status.Rejected != 5 || status.Requests != 0 {
+		t.Errorf("%d rejected and %d sent, want 5 rejected and none sent", status.Rejected, status.Requests)
+	}
+}
+
+func TestRefund(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	l := &limiter{name: "test"}
+	l.configure(Quota{PerMinute: 60, Burst: 1, Daily: 10, SoftThreshold: 0.8, HardThreshold: 1}, usage{})
+
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	l.refund()
+
+	status := l.status()
+	if status.Daily != 0 || status.Monthly != 0 {
+		t.Errorf("usage %d daily, %d monthly after a refund, want none", status.Daily, status.Monthly)
+	}
+	// The refunded token is free again
+	if err := l.acquire(context.Background()); err != nil {
+		t.Errorf("acquire after a refund: %v", err)
+	}
+}
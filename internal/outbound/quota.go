@@ -0,0 +1,584 @@
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrRateLimited is returned without calling the provider when no token
+	// of its rate limiter becomes free within the allowed wait
+	ErrRateLimited = errors.New("provider rate limit reached")
+	// ErrQuotaExceeded is returned without calling the provider once its
+	// daily or monthly usage reached the hard threshold
+	ErrQuotaExceeded = errors.New("provider quota exhausted")
+	// ErrQuotaSoftLimit is returned without calling a provider whose soft
+	// action is "fallback" once its usage reached the soft threshold
+	ErrQuotaSoftLimit = errors.New("provider quota soft threshold reached")
+)
+
+// Quota states reported in QuotaStatus
+const (
+	QuotaOK   = "ok"
+	QuotaSoft = "soft"
+	QuotaHard = "hard"
+)
+
+// Soft threshold actions
+const (
+	// SoftActionAlert keeps calling the provider until the hard threshold
+	SoftActionAlert = "alert"
+	// SoftActionFallback stops calling the provider, so lookups are answered
+	// from the lookup cache or the next provider of their chain
+	SoftActionFallback = "fallback"
+)
+
+// Quota is a provider's rate limit and usage quotas from QUOTAS_FILE. A zero
+// limit is not enforced.
+type Quota struct {
+	// PerMinute is the sustained rate of the provider's token bucket
+	PerMinute float64 `json:"per_minute" yaml:"per_minute"`
+	// Burst is the bucket size, 10 seconds' worth of PerMinute by default
+	Burst float64 `json:"burst" yaml:"burst"`
+	// MaxWaitMs is how long a call waits for a token before it is rejected,
+	// QUOTA_MAX_WAIT_MS by default
+	MaxWaitMs float64 `json:"max_wait_ms" yaml:"max_wait_ms"`
+	Daily     int64   `json:"daily" yaml:"daily"`
+	Monthly   int64   `json:"monthly" yaml:"monthly"`
+	// SoftThreshold and HardThreshold are fractions of the daily and monthly
+	// quotas, 0.8 and 1 by default
+	SoftThreshold float64 `json:"soft_threshold" yaml:"soft_threshold"`
+	HardThreshold float64 `json:"hard_threshold" yaml:"hard_threshold"`
+	// SoftAction is "alert" (the default) or "fallback"
+	SoftAction string `json:"soft_action" yaml:"soft_action"`
+}
+
+// QuotaStatus is a provider's usage in the current UTC day and month
+type QuotaStatus struct {
+	State        string  `json:"state"`
+	PerMinute    float64 `json:"per_minute,omitempty"`
+	Tokens       float64 `json:"tokens,omitempty"`
+	Daily        int64   `json:"daily"`
+	DailyLimit   int64   `json:"daily_limit,omitempty"`
+	Monthly      int64   `json:"monthly"`
+	MonthlyLimit int64   `json:"monthly_limit,omitempty"`
+	Rejected     uint64  `json:"rejected"`
+}
+
+// Alert is raised once per period when a provider's usage crosses a threshold
+type Alert struct {
+	Provider string    `json:"provider"`
+	Level    string    `json:"level"`
+	Period   string    `json:"period"`
+	Used     int64     `json:"used"`
+	Limit    int64     `json:"limit"`
+	Action   string    `json:"action"`
+	Time     time.Time `json:"time"`
+}
+
+// usage is a provider's persisted call counts
+type usage struct {
+	Day     string `json:"day"`
+	Daily   int64  `json:"daily"`
+	Month   string `json:"month"`
+	Monthly int64  `json:"monthly"`
+	// Alerts maps a period and level, such as "daily_soft", to the period in
+	// which it was last raised
+	Alerts map[string]string `json:"alerts,omitempty"`
+}
+
+// limiter enforces a provider's quota and counts its calls
+type limiter struct {
+	name string
+
+	mu       sync.Mutex
+	quota    Quota
+	tokens   float64
+	refilled time.Time
+	usage    usage
+	rejected uint64
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*limiter{}
+	quotas     = map[string]Quota{}
+	restored   = map[string]usage{}
+	usageDirty bool
+	// saveMu serializes usage saves, which share a temporary file
+	saveMu sync.Mutex
+
+	saveOnce sync.Once
+)
+
+// Init loads the provider quotas from QUOTAS_FILE and restores the usage
+// counts saved in QUOTA_USAGE_FILE, which are saved back every
+// QUOTA_USAGE_SAVE_SECONDS while they change, as soon as a threshold is
+// crossed and by Flush on shutdown
+func Init() error {
+	cfg := config.AppConfig
+
+	loaded := map[string]Quota{}
+	if cfg.QuotasFile != "" {
+		var err error
+		if loaded, err = loadQuotasFile(cfg.QuotasFile); err != nil {
+			return err
+		}
+	}
+
+	saved := map[string]usage{}
+	if path := cfg.QuotaUsageFile; path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return fmt.Errorf("error reading quota usage %s: %w", path, err)
+		default:
+			if err := json.Unmarshal(data, &saved); err != nil {
+				return fmt.Errorf("error parsing quota usage %s: %w", path, err)
+			}
+		}
+	}
+
+	limitersMu.Lock()
+	quotas = loaded
+	restored = saved
+	for name, l := range limiters {
+		l.configure(loaded[name], saved[name])
+	}
+	limitersMu.Unlock()
+
+	if path := cfg.QuotaUsageFile; path != "" {
+		saveOnce.Do(func() {
+			go func() {
+				ticker := time.NewTicker(cfg.QuotaUsageSaveInterval)
+				defer ticker.Stop()
+				for range ticker.C {
+					flushUsage()
+				}
+			}()
+		})
+	}
+
+	logger.Info("Provider quotas configured",
+		"providers", len(loaded),
+		"usage_file", cfg.QuotaUsageFile,
+		"restored_providers", len(saved),
+	)
+	return nil
+}
+
+func loadQuotasFile(path string) (map[string]Quota, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading quotas %s: %w", path, err)
+	}
+
+	var file struct {
+		Providers map[string]Quota `json:"providers" yaml:"providers"`
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yaml" || ext == ".yml" {
+		err = yaml.Unmarshal(data, &file)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing quotas %s: %w", path, err)
+	}
+
+	for name, q := range file.Providers {
+		if q.SoftThreshold == 0 {
+			q.SoftThreshold = 0.8
+		}
+		if q.HardThreshold == 0 {
+			q.HardThreshold = 1
+		}
+		if q.SoftThreshold > q.HardThreshold {
+			return nil, fmt.Errorf("quota %q: soft threshold %g is above hard threshold %g", name, q.SoftThreshold, q.HardThreshold)
+		}
+		switch q.SoftAction {
+		case "":
+			q.SoftAction = SoftActionAlert
+		case SoftActionAlert, SoftActionFallback:
+		default:
+			return nil, fmt.Errorf("quota %q: unknown soft action %q", name, q.SoftAction)
+		}
+		if q.PerMinute > 0 && q.Burst <= 0 {
+			q.Burst = q.PerMinute / 6
+			if q.Burst < 1 {
+				q.Burst = 1
+			}
+		}
+		file.Providers[name] = q
+	}
+	return file.Providers, nil
+}
+
+// limiterFor returns the limiter of a provider, created with its quota and
+// restored usage on first use
+func limiterFor(name string) *limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	if l, ok := limiters[name]; ok {
+		return l
+	}
+	l := &limiter{name: name}
+	l.configure(quotas[name], restored[name])
+	limiters[name] = l
+	return l
+}
+
+func (l *limiter) configure(q Quota, saved usage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.quota = q
+	l.tokens = q.Burst
+	l.refilled = time.Now()
+	if saved.Day != "" || saved.Month != "" {
+		l.usage = saved
+		l.usage.Alerts = make(map[string]string, len(saved.Alerts))
+		for key, period := range saved.Alerts {
+			l.usage.Alerts[key] = period
+		}
+	}
+}
+
+// acquire takes a token for one call, waiting up to the provider's maximum
+// wait, and counts the call. It fails without counting once the provider is
+// past its hard threshold, or past its soft threshold with the fallback
+// soft action.
+func (l *limiter) acquire(ctx context.Context) error {
+	for {
+		now := time.Now()
+		l.mu.Lock()
+		l.rollover(now)
+
+		if state := l.state(); state == QuotaHard {
+			l.rejected++
+			l.mu.Unlock()
+			return ErrQuotaExceeded
+		} else if state == QuotaSoft && l.quota.SoftAction == SoftActionFallback {
+			l.rejected++
+			l.mu.Unlock()
+			return ErrQuotaSoftLimit
+		}
+
+		wait := l.take(now)
+		if wait == 0 {
+			l.usage.Daily++
+			l.usage.Monthly++
+			alerts := l.crossed(now)
+			l.mu.Unlock()
+
+			limitersMu.Lock()
+			usageDirty = true
+			limitersMu.Unlock()
+			for _, alert := range alerts {
+				raise(alert)
+			}
+			if len(alerts) > 0 {
+				// A restart must not forget that a threshold was crossed
+				flushUsage()
+			}
+			return nil
+		}
+		if wait > l.maxWait() {
+			l.rejected++
+			l.mu.Unlock()
+			return ErrRateLimited
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// refund gives back the token and the count of a call acquire let through
+// but that was not sent
+func (l *limiter) refund() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rollover(time.Now())
+	if l.usage.Daily > 0 {
+		l.usage.Daily--
+	}
+	if l.usage.Monthly > 0 {
+		l.usage.Monthly--
+	}
+	if l.quota.PerMinute > 0 {
+		l.tokens = math.Min(l.tokens+1, l.quota.Burst)
+	}
+}
+
+// take refills the token bucket and takes a token, returning 0, or returns
+// how long until a token is free. Must be called with mu held.
+func (l *limiter) take(now time.Time) time.Duration {
+	if l.quota.PerMinute <= 0 {
+		return 0
+	}
+	rate := l.quota.PerMinute / 60
+	l.tokens += now.Sub(l.refilled).Seconds() * rate
+	if l.tokens > l.quota.Burst {
+		l.tokens = l.quota.Burst
+	}
+	l.refilled = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	wait := time.Duration((1 - l.tokens) / rate * float64(time.Second))
+	if wait <= 0 {
+		wait = time.Millisecond
+	}
+	return wait
+}
+
+func (l *limiter) maxWait() time.Duration {
+	if l.quota.MaxWaitMs > 0 {
+		return time.Duration(l.quota.MaxWaitMs * float64(time.Millisecond))
+	}
+	return config.AppConfig.QuotaMaxWait
+}
+
+// rollover resets the counts of a past UTC day or month. Must be called with
+// mu held.
+func (l *limiter) rollover(now time.Time) {
+	now = now.UTC()
+	if day := now.Format("2006-01-02"); l.usage.Day != day {
+		l.usage.Day = day
+		l.usage.Daily = 0
+	}
+	if month := now.Format("2006-01"); l.usage.Month != month {
+		l.usage.Month = month
+		l.usage.Monthly = 0
+	}
+}
+
+// level returns the threshold a count has reached against a limit
+func (l *limiter) level(used, limit int64) string {
+	if limit <= 0 {
+		return QuotaOK
+	}
+	fraction := float64(used) / float64(limit)
+	switch {
+	case fraction >= l.quota.HardThreshold:
+		return QuotaHard
+	case fraction >= l.quota.SoftThreshold:
+		return QuotaSoft
+	}
+	return QuotaOK
+}
+
+// state returns the highest threshold reached in the current day or month.
+// Must be called with mu held.
+func (l *limiter) state() string {
+	daily := l.level(l.usage.Daily, l.quota.Daily)
+	monthly := l.level(l.usage.Monthly, l.quota.Monthly)
+	if daily == QuotaHard || monthly == QuotaHard {
+		return QuotaHard
+	}
+	if daily == QuotaSoft || monthly == QuotaSoft {
+		return QuotaSoft
+	}
+	return QuotaOK
+}
+
+// crossed returns the alerts for thresholds reached for the first time in
+// the current period, marking them raised. Reaching the hard threshold also
+// marks the soft one. Must be called with mu held.
+func (l *limiter) crossed(now time.Time) []Alert {
+	var alerts []Alert
+	for _, period := range []struct {
+		name, id    string
+		used, limit int64
+	}{
+		{"daily", l.usage.Day, l.usage.Daily, l.quota.Daily},
+		{"monthly", l.usage.Month, l.usage.Monthly, l.quota.Monthly},
+	} {
+		level := l.level(period.used, period.limit)
+		if level == QuotaOK || l.usage.Alerts[period.name+"_"+level] == period.id {
+			continue
+		}
+		if l.usage.Alerts == nil {
+			l.usage.Alerts = map[string]string{}
+		}
+		l.usage.Alerts[period.name+"_"+level] = period.id
+		if level == QuotaHard {
+			l.usage.Alerts[period.name+"_"+QuotaSoft] = period.id
+		}
+
+		action := "rejecting calls"
+		if level == QuotaSoft {
+			action = "alert only"
+			if l.quota.SoftAction == SoftActionFallback {
+				action = "serving from cache and fallback providers"
+			}
+		}
+		alerts = append(alerts, Alert{
+			Provider: l.name,
+			Level:    level,
+			Period:   period.name,
+			Used:     period.used,
+			Limit:    period.limit,
+			Action:   action,
+			Time:     now.UTC(),
+		})
+	}
+	return alerts
+}
+
+// raise logs an alert and posts it to QUOTA_ALERT_WEBHOOK
+func raise(alert Alert) {
+	log := logger.Warn
+	if alert.Level == QuotaHard {
+		log = logger.Error
+	}
+	log("Provider quota threshold reached",
+		"provider", alert.Provider,
+		"level", alert.Level,
+		"period", alert.Period,
+		"used", alert.Used,
+		"limit", alert.Limit,
+		"action", alert.Action,
+	)
+
+	url := config.AppConfig.QuotaAlertWebhook
+	if url == "" {
+		return
+	}
+	go func() {
+		body, err := json.Marshal(alert)
+		if err != nil {
+			return
+		}
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			logger.Error("Failed to post quota alert", "provider", alert.Provider, "error", err.Error())
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			logger.Error("Quota alert webhook returned error status",
+				"provider", alert.Provider,
+				"status_code", resp.StatusCode,
+			)
+		}
+	}()
+}
+
+// status returns the limiter's usage and quota state
+func (l *limiter) status() QuotaStatus {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rollover(now)
+	tokens := l.tokens
+	if l.quota.PerMinute > 0 {
+		tokens += now.Sub(l.refilled).Seconds() * l.quota.PerMinute / 60
+		if tokens > l.quota.Burst {
+			tokens = l.quota.Burst
+		}
+	}
+	return QuotaStatus{
+		State:        l.state(),
+		PerMinute:    l.quota.PerMinute,
+		Tokens:       tokens,
+		Daily:        l.usage.Daily,
+		DailyLimit:   l.quota.Daily,
+		Monthly:      l.usage.Monthly,
+		MonthlyLimit: l.quota.Monthly,
+		Rejected:     l.rejected,
+	}
+}
+
+// Flush saves the usage counts to QUOTA_USAGE_FILE if they changed since
+// the last save
+func Flush() error {
+	path := config.AppConfig.QuotaUsageFile
+	if path == "" {
+		return nil
+	}
+	return SaveUsage(path)
+}
+
+// flushUsage saves the usage counts, logging a failure
+func flushUsage() {
+	if err := Flush(); err != nil {
+		logger.Error("Failed to persist quota usage",
+			"file", config.AppConfig.QuotaUsageFile,
+			"error", err.Error(),
+		)
+	}
+}
+
+// SaveUsage writes every provider's usage counts to path if any changed
+// since the last save
+func SaveUsage(path string) error {
+	saveMu.Lock()
+	defer saveMu.Unlock()
+
+	limitersMu.Lock()
+	if !usageDirty {
+		limitersMu.Unlock()
+		return nil
+	}
+	usageDirty = false
+	saved := make(map[string]usage, len(restored)+len(limiters))
+	for name, u := range restored {
+		saved[name] = u
+	}
+	snapshot := make([]*limiter, 0, len(limiters))
+	for _, l := range limiters {
+		snapshot = append(snapshot, l)
+	}
+	limitersMu.Unlock()
+
+	for _, l := range snapshot {
+		l.mu.Lock()
+		u := l.usage
+		u.Alerts = make(map[string]string, len(l.usage.Alerts))
+		for key, period := range l.usage.Alerts {
+			u.Alerts[key] = period
+		}
+		l.mu.Unlock()
+		saved[l.name] = u
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("error encoding quota usage: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing quota usage %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing quota usage %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package outbound
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+)
+
+func TestTake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	l := &limiter{quota: Quota{PerMinute: 60, Burst: 2}, tokens: 2, refilled: start}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  time.Duration
+	}{
+		{"first token of the burst", 0, 0},
+		{"second token of the burst", 0, 0},
+		{"empty bucket", 0, time.Second},
+		{"partly refilled", 500 * time.Millisecond, 500 * time.Millisecond},
+		{"refilled", time.Second, 0},
+		{"refill capped at the burst", time.Hour, 0},
+		{"one token left after the cap", time.Hour, 0},
+		{"empty again", time.Hour, time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := l.take(start.Add(tt.after)); got != tt.want {
+			t.Errorf("%s: take waited %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	unlimited := &limiter{}
+	if wait := unlimited.take(start); wait != 0 {
+		t.Errorf("take without a rate waited %s", wait)
+	}
+}
+
+func TestRollover(t *testing.T) {
+	tests := []struct {
+		name             string
+		now              time.Time
+		daily, monthly   int64
+		wantDay, wantMon string
+	}{
+		{"same day", time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC), 5, 50, "2024-01-15", "2024-01"},
+		{"next day", time.Date(2024, 1, 16, 1, 0, 0, 0, time.UTC), 0, 50, "2024-01-16", "2024-01"},
+		{"next month", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 0, 0, "2024-02-01", "2024-02"},
+		// Days are UTC whatever the zone of the clock
+		{"UTC day", time.Date(2024, 1, 16, 3, 0, 0, 0, time.FixedZone("IST", 5*3600+1800)), 5, 50, "2024-01-15", "2024-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &limiter{usage: usage{Day: "2024-01-15", Daily: 5, Month: "2024-01", Monthly: 50}}
+			l.rollover(tt.now)
+			if l.usage.Day != tt.wantDay || l.usage.Daily != tt.daily || l.usage.Month != tt.wantMon || l.usage.Monthly != tt.monthly {
+				t.Errorf("usage %+v, want day %s with %d calls and month %s with %d", l.usage, tt.wantDay, tt.daily, tt.wantMon, tt.monthly)
+			}
+		})
+	}
+}
+
+func TestAcquireEnforcesThresholds(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	tests := []struct {
+		name    string
+		quota   Quota
+		calls   int
+		wantErr error
+	}{
+		{"under quota", Quota{Daily: 10, SoftThreshold: 0.8, HardThreshold: 1, SoftAction: SoftActionAlert}, 10, nil},
+		{"hard threshold", Quota{Daily: 10, SoftThreshold: 0.8, HardThreshold: 1, SoftAction: SoftActionAlert}, 11, ErrQuotaExceeded},
+		{"soft fallback", Quota{Monthly: 10, SoftThreshold: 0.5, HardThreshold: 1, SoftAction: SoftActionFallback}, 6, ErrQuotaSoftLimit},
+		{"rate limit", Quota{PerMinute: 1, Burst: 1, MaxWaitMs: 10}, 2, ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &limiter{name: tt.name}
+			l.configure(tt.quota, usage{})
+			var err error
+			for i := 0; i < tt.calls && err == nil; i++ {
+				err = l.acquire(context.Background())
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("acquire error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCrossingThresholdSavesUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	config.AppConfig = &config.Config{QuotaUsageFile: path}
+
+	limitersMu.Lock()
+	limiters = map[string]*limiter{}
+	restored = map[string]usage{}
+	quotas = map[string]Quota{"waqi": {Daily: 4, SoftThreshold: 0.5, HardThreshold: 1, SoftAction: SoftActionAlert}}
+	limitersMu.Unlock()
+
+	l := limiterFor("waqi")
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("usage saved before a threshold was crossed")
+	}
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("usage not saved at the soft threshold: %v", err)
+	}
+	var saved map[string]usage
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("reading saved usage: %v", err)
+	}
+	if u := saved["waqi"]; u.Daily != 2 || u.Alerts["daily_soft"] != u.Day {
+		t.Errorf("saved usage %+v, want 2 calls with the soft alert raised", u)
+	}
+}
@@ -0,0 +1,105 @@
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+// linearModelFile is the on-disk format of a local model. Coefficients are
+// keyed by the feature names used in models.FeatureVector. The optional
+// residual_std is reported as the standard deviation of every prediction.
+//
+//	{"intercept": 4.2, "coefficients": {"IPM": 0.91, "FRH": 0.05}, "residual_std": 7.5}
+type linearModelFile struct {
+	Intercept    float64            `json:"intercept"`
+	Coefficients map[string]float64 `json:"coefficients"`
+	ResidualStd  float64            `json:"residual_std,omitempty"`
+}
+
+// LocalPredictor evaluates a linear model loaded from a local file
+type LocalPredictor struct {
+	name  string
+	model linearModelFile
+}
+
+// NewLocalPredictor loads a linear model from the given JSON file
+func NewLocalPredictor(name string, path string) (*LocalPredictor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading model file %s: %w", path, err)
+	}
+
+	var model linearModelFile
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("error parsing model file %s: %w", path, err)
+	}
+
+	for feature := range model.Coefficients {
+		if _, ok := featureValue(models.FeatureVector{}, feature); !ok {
+			return nil, fmt.Errorf("model file %s references unknown feature %q", path, feature)
+		}
+	}
+
+	return &LocalPredictor{name: name, model: model}, nil
+}
+
+// Name returns the registry name of the model
+func (p *LocalPredictor) Name() string {
+	return p.name
+}
+
+// Predict evaluates the linear model for every feature vector
+func (p *LocalPredictor) Predict(features []models.FeatureVector) (Output, error) {
+	predictions := make([]float64, len(features))
+	for i, fv := range features {
+		value := p.model.Intercept
+		for feature, coefficient := range p.model.Coefficients {
+			x, _ := featureValue(fv, feature)
+			value += coefficient * x
+		}
+		if value < 0 {
+			value = 0
+		}
+		predictions[i] = value
+	}
+
+	out := Output{Values: predictions}
+	if p.model.ResidualStd > 0 {
+		out.StdDev = make([]float64, len(predictions))
+		for i := range out.StdDev {
+			out.StdDev[i] = p.model.ResidualStd
+		}
+	}
+	return out, nil
+}
+
+// featureValue looks up a feature by its JSON name
+func featureValue(fv models.FeatureVector, feature string) (float64, bool) {
+	switch feature {
+	case "ITEMP":
+		return fv.ITEMP, true
+	case "IRH":
+		return fv.IRH, true
+	case "IWD":
+		return fv.IWD, true
+	case "IWS":
+		return fv.IWS, true
+	case "IPM":
+		return fv.IPM, true
+	case "FTEMP":
+		return fv.FTEMP, true
+	case "FRH":
+		return fv.FRH, true
+	case "FWD":
+		return fv.FWD, true
+	case "FWS":
+		return fv.FWS, true
+	case "delayCode":
+		return float64(fv.DelayCode), true
+	default:
+		return 0, false
+	}
+}
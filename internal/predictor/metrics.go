@@ -0,0 +1,90 @@
+package predictor
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// ModelMetrics is a point-in-time summary of a registered model's behaviour
+type ModelMetrics struct {
+	Name           string  `json:"name"`
+	Type           string  `json:"type"`
+	Mode           string  `json:"mode"`
+	TrafficShare   float64 `json:"traffic_share"`
+	Requests       int64   `json:"requests"`
+	Errors         int64   `json:"errors"`
+	ErrorRate      float64 `json:"error_rate"`
+	AvgLatencyMs   float64 `json:"avg_latency_ms"`
+	Predictions    int64   `json:"predictions"`
+	MeanPrediction float64 `json:"mean_prediction"`
+	// Shadow models are compared point by point against the served forecast
+	ComparedPoints int64   `json:"compared_points,omitempty"`
+	MeanAbsDiff    float64 `json:"mean_abs_diff_vs_served,omitempty"`
+	MeanBias       float64 `json:"mean_bias_vs_served,omitempty"`
+}
+
+// modelStats accumulates metrics for a single model
+type modelStats struct {
+	mu             sync.Mutex
+	requests       int64
+	errors         int64
+	totalLatency   time.Duration
+	predictions    int64
+	predictionSum  float64
+	comparedPoints int64
+	absDiffSum     float64
+	diffSum        float64
+}
+
+func (s *modelStats) record(latency time.Duration, values []float64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	s.totalLatency += latency
+	if err != nil {
+		s.errors++
+		return
+	}
+	for _, v := range values {
+		s.predictions++
+		s.predictionSum += v
+	}
+}
+
+func (s *modelStats) compare(values []float64, served []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < len(values) && i < len(served); i++ {
+		diff := values[i] - served[i]
+		s.comparedPoints++
+		s.diffSum += diff
+		s.absDiffSum += math.Abs(diff)
+	}
+}
+
+func (s *modelStats) snapshot() ModelMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := ModelMetrics{
+		Requests:       s.requests,
+		Errors:         s.errors,
+		Predictions:    s.predictions,
+		ComparedPoints: s.comparedPoints,
+	}
+	if s.requests > 0 {
+		m.ErrorRate = float64(s.errors) / float64(s.requests)
+		m.AvgLatencyMs = float64(s.totalLatency.Milliseconds()) / float64(s.requests)
+	}
+	if s.predictions > 0 {
+		m.MeanPrediction = s.predictionSum / float64(s.predictions)
+	}
+	if s.comparedPoints > 0 {
+		m.MeanAbsDiff = s.absDiffSum / float64(s.comparedPoints)
+		m.MeanBias = s.diffSum / float64(s.comparedPoints)
+	}
+	return m
+}
@@ -0,0 +1,21 @@
+package predictor
+
+import (
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+// Predictor produces PM2.5 forecasts for a batch of feature vectors
+type Predictor interface {
+	// Name returns the registry name of the model
+	Name() string
+	// Predict returns one forecast per feature vector, in the same order
+	Predict(features []models.FeatureVector) (Output, error)
+}
+
+// Output is the raw result of a model. Values is always set; StdDev and
+// Quantiles are optional and, when present, hold one entry per value.
+type Output struct {
+	Values    []float64
+	StdDev    []float64
+	Quantiles map[float64][]float64
+}
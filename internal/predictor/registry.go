@@ -0,0 +1,320 @@
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+const (
+	// ModeLive marks a model that serves a share of traffic
+	ModeLive = "live"
+	// ModeShadow marks a model whose outputs are logged and compared but never served
+	ModeShadow = "shadow"
+)
+
+// ModelSpec describes a model entry in the registry file
+type ModelSpec struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"` // "remote" or "local"
+	Endpoint string  `json:"endpoint,omitempty"`
+	Path     string  `json:"path,omitempty"`
+	Weight   float64 `json:"weight"`
+	Shadow   bool    `json:"shadow"`
+}
+
+// registryFile is the on-disk format of MODEL_REGISTRY_FILE
+type registryFile struct {
+	Models []ModelSpec `json:"models"`
+}
+
+// Forecast is the result of a registry prediction
+type Forecast struct {
+	Model     string            `json:"model"`
+	Values    []float64         `json:"values"`
+	Intervals []models.Interval `json:"intervals"`
+}
+
+type registeredModel struct {
+	spec      ModelSpec
+	predictor Predictor
+	stats     *modelStats
+}
+
+// Registry routes prediction requests across the registered models
+type Registry struct {
+	mu          sync.RWMutex
+	live        []*registeredModel
+	shadows     []*registeredModel
+	byName      map[string]*registeredModel
+	totalWeight float64
+}
+
+// Default is the registry used by the exposure pipeline and handlers
+var Default = &Registry{byName: map[string]*registeredModel{}}
+
+// Init builds the default registry from configuration. When no registry file
+// is configured, AWS_MODEL_ENDPOINT is registered as the single live model.
+func Init() error {
+	specs, err := loadSpecs()
+	if err != nil {
+		return err
+	}
+	return Default.Load(specs)
+}
+
+// Reload re-reads the registry file and swaps the registered models.
+// Metrics are kept for models whose name is unchanged.
+func Reload() error {
+	return Init()
+}
+
+func loadSpecs() ([]ModelSpec, error) {
+	path := config.AppConfig.ModelRegistryFile
+	if path == "" {
+		if config.AppConfig.AWSModelEndpoint == "" {
+			logger.Warn("No PM2.5 model configured, delayed-departure exposure is unavailable")
+			return nil, nil
+		}
+		return []ModelSpec{{
+			Name:     "default",
+			Type:     "remote",
+			Endpoint: config.AppConfig.AWSModelEndpoint,
+			Weight:   1,
+		}}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading model registry %s: %w", path, err)
+	}
+
+	var file registryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing model registry %s: %w", path, err)
+	}
+	return file.Models, nil
+}
+
+// Load replaces the registered models with the given specs
+func (r *Registry) Load(specs []ModelSpec) error {
+	live := []*registeredModel{}
+	shadows := []*registeredModel{}
+	byName := map[string]*registeredModel{}
+	var totalWeight float64
+
+	r.mu.RLock()
+	previous := r.byName
+	r.mu.RUnlock()
+
+	for _, spec := range specs {
+		if spec.Name == "" {
+			return fmt.Errorf("model registry entry is missing a name")
+		}
+		if _, exists := byName[spec.Name]; exists {
+			return fmt.Errorf("model %q is registered more than once", spec.Name)
+		}
+		if spec.Weight < 0 {
+			return fmt.Errorf("model %q has a negative weight", spec.Name)
+		}
+
+		var p Predictor
+		switch spec.Type {
+		case "remote":
+			if spec.Endpoint == "" {
+				return fmt.Errorf("remote model %q is missing an endpoint", spec.Name)
+			}
+			p = NewRemotePredictor(spec.Name, spec.Endpoint)
+		case "local":
+			local, err := NewLocalPredictor(spec.Name, spec.Path)
+			if err != nil {
+				return err
+			}
+			p = local
+		default:
+			return fmt.Errorf("model %q has unsupported type %q", spec.Name, spec.Type)
+		}
+
+		stats := &modelStats{}
+		if old, ok := previous[spec.Name]; ok {
+			stats = old.stats
+		}
+
+		m := &registeredModel{spec: spec, predictor: p, stats: stats}
+		byName[spec.Name] = m
+		if spec.Shadow {
+			shadows = append(shadows, m)
+		} else if spec.Weight > 0 {
+			live = append(live, m)
+			totalWeight += spec.Weight
+		}
+	}
+
+	if len(specs) > 0 && len(live) == 0 {
+		return fmt.Errorf("model registry has no live model with a positive weight")
+	}
+
+	r.mu.Lock()
+	r.live = live
+	r.shadows = shadows
+	r.byName = byName
+	r.totalWeight = totalWeight
+	r.mu.Unlock()
+
+	logger.Info("PM2.5 model registry loaded",
+		"live_models", len(live),
+		"shadow_models", len(shadows),
+	)
+	return nil
+}
+
+// Predict serves the features from a live model chosen by traffic weight and
+// evaluates every shadow model against the served forecast in the background
+func (r *Registry) Predict(features []models.FeatureVector) (Forecast, error) {
+	r.mu.RLock()
+	served := r.pick()
+	shadows := r.shadows
+	r.mu.RUnlock()
+
+	if served == nil {
+		return Forecast{}, fmt.Errorf("no PM2.5 model is configured")
+	}
+
+	out, err := run(served, features)
+	if err != nil {
+		return Forecast{}, err
+	}
+
+	if len(shadows) > 0 {
+		select {
+		case shadowSlots() <- struct{}{}:
+			go func() {
+				defer func() { <-shadowSlots() }()
+				runShadows(shadows, features, out.Values)
+			}()
+		default:
+			logger.Debug("Skipping shadow PM2.5 models, all shadow workers busy")
+		}
+	}
+
+	return newForecast(served.spec.Name, out, features), nil
+}
+
+// PredictWith serves the features from the named model, live or shadow
+func (r *Registry) PredictWith(name string, features []models.FeatureVector) (Forecast, error) {
+	r.mu.RLock()
+	m, ok := r.byName[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return Forecast{}, fmt.Errorf("unknown PM2.5 model: %s", name)
+	}
+
+	out, err := run(m, features)
+	if err != nil {
+		return Forecast{}, err
+	}
+	return newForecast(name, out, features), nil
+}
+
+// Metrics returns the metrics of every registered model
+func (r *Registry) Metrics() []ModelMetrics {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	metrics := []ModelMetrics{}
+	for _, group := range [][]*registeredModel{r.live, r.shadows} {
+		for _, m := range group {
+			snapshot := m.stats.snapshot()
+			snapshot.Name = m.spec.Name
+			snapshot.Type = m.spec.Type
+			snapshot.Mode = ModeLive
+			if m.spec.Shadow {
+				snapshot.Mode = ModeShadow
+			} else if r.totalWeight > 0 {
+				snapshot.TrafficShare = m.spec.Weight / r.totalWeight
+			}
+			metrics = append(metrics, snapshot)
+		}
+	}
+	return metrics
+}
+
+// pick selects a live model according to the configured weights.
+// The caller must hold r.mu.
+func (r *Registry) pick() *registeredModel {
+	if len(r.live) == 0 {
+		return nil
+	}
+
+	target := rand.Float64() * r.totalWeight
+	for _, m := range r.live {
+		target -= m.spec.Weight
+		if target < 0 {
+			return m
+		}
+	}
+	return r.live[len(r.live)-1]
+}
+
+func newForecast(name string, out Output, features []models.FeatureVector) Forecast {
+	return Forecast{
+		Model:     name,
+		Values:    out.Values,
+		Intervals: Intervals(out, features),
+	}
+}
+
+func run(m *registeredModel, features []models.FeatureVector) (Output, error) {
+	start := time.Now()
+	out, err := m.predictor.Predict(features)
+	m.stats.record(time.Since(start), out.Values, err)
+
+	if err != nil {
+		logger.Error("PM2.5 model prediction failed",
+			"model", m.spec.Name,
+			"error", err.Error(),
+		)
+	}
+	return out, err
+}
+
+var (
+	shadowSlotsOnce sync.Once
+	shadowSlotsChan chan struct{}
+)
+
+// shadowSlots bounds concurrent shadow evaluations to SHADOW_WORKERS
+func shadowSlots() chan struct{} {
+	shadowSlotsOnce.Do(func() {
+		n := config.AppConfig.ShadowWorkers
+		if n < 1 {
+			n = 1
+		}
+		shadowSlotsChan = make(chan struct{}, n)
+	})
+	return shadowSlotsChan
+}
+
+func runShadows(shadows []*registeredModel, features []models.FeatureVector, served []float64) {
+	for _, m := range shadows {
+		out, err := run(m, features)
+		if err != nil {
+			continue
+		}
+		m.stats.compare(out.Values, served)
+
+		logger.Info("Shadow PM2.5 model prediction",
+			"model", m.spec.Name,
+			"predictions", out.Values,
+			"served", served,
+		)
+	}
+}
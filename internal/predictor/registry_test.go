@@ -0,0 +1,91 @@
+package predictor
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+// fakePredictor returns a constant and optionally blocks until released
+type fakePredictor struct {
+	name    string
+	value   float64
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (p *fakePredictor) Name() string { return p.name }
+
+func (p *fakePredictor) Predict(features []models.FeatureVector) (Output, error) {
+	p.calls.Add(1)
+	if p.release != nil {
+		<-p.release
+	}
+	values := make([]float64, len(features))
+	for i := range values {
+		values[i] = p.value
+	}
+	return Output{Values: values}, nil
+}
+
+func newTestRegistry(live, shadow *fakePredictor) *Registry {
+	liveModel := &registeredModel{spec: ModelSpec{Name: live.name, Weight: 1}, predictor: live, stats: &modelStats{}}
+	shadowModel := &registeredModel{spec: ModelSpec{Name: shadow.name, Shadow: true}, predictor: shadow, stats: &modelStats{}}
+	return &Registry{
+		live:        []*registeredModel{liveModel},
+		shadows:     []*registeredModel{shadowModel},
+		byName:      map[string]*registeredModel{live.name: liveModel, shadow.name: shadowModel},
+		totalWeight: 1,
+	}
+}
+
+func TestPredictSkipsShadowsWhenWorkersBusy(t *testing.T) {
+	config.AppConfig = &config.Config{ShadowWorkers: 1}
+
+	live := &fakePredictor{name: "live", value: 40}
+	shadow := &fakePredictor{name: "shadow", value: 42, release: make(chan struct{})}
+	r := newTestRegistry(live, shadow)
+	features := []models.FeatureVector{{}}
+
+	for i := 0; i < 5; i++ {
+		forecast, err := r.Predict(features)
+		if err != nil {
+			t.Fatalf("Predict: %v", err)
+		}
+		if forecast.Model != "live" || forecast.Values[0] != 40 {
+			t.Fatalf("served %s %v, want live model", forecast.Model, forecast.Values)
+		}
+	}
+
+	// The first shadow run holds the only worker; the others are dropped
+	deadline := time.Now().Add(time.Second)
+	for shadow.calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(shadow.release)
+	time.Sleep(10 * time.Millisecond)
+
+	if calls := shadow.calls.Load(); calls != 1 {
+		t.Errorf("shadow model called %d times, want 1", calls)
+	}
+	if calls := live.calls.Load(); calls != 5 {
+		t.Errorf("live model called %d times, want 5", calls)
+	}
+}
+
+func TestPickFollowsWeights(t *testing.T) {
+	a := &registeredModel{spec: ModelSpec{Name: "a", Weight: 3}}
+	b := &registeredModel{spec: ModelSpec{Name: "b", Weight: 1}}
+	r := &Registry{live: []*registeredModel{a, b}, totalWeight: 4}
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		counts[r.pick().spec.Name]++
+	}
+	if counts["a"] < 2700 || counts["a"] > 3300 {
+		t.Errorf("model a picked %d of 4000 times, want about 3000", counts["a"])
+	}
+}
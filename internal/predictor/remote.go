@@ -0,0 +1,96 @@
+package predictor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/outbound"
+)
+
+// RemotePredictor calls a model served over HTTP that accepts a JSON array of
+// feature vectors and responds with {"fpm_vec": [...]}. Models may also report
+// uncertainty as "fpm_std" or as "fpm_quantiles" keyed by quantile, e.g.
+// {"0.05": [...], "0.95": [...]}.
+type RemotePredictor struct {
+	name     string
+	endpoint string
+}
+
+// NewRemotePredictor creates a predictor backed by an HTTP model endpoint
+func NewRemotePredictor(name string, endpoint string) *RemotePredictor {
+	return &RemotePredictor{name: name, endpoint: endpoint}
+}
+
+// Name returns the registry name of the model
+func (p *RemotePredictor) Name() string {
+	return p.name
+}
+
+// Predict sends the feature vectors to the model endpoint
+func (p *RemotePredictor) Predict(features []models.FeatureVector) (Output, error) {
+	logger.Debug("Calling remote PM2.5 model",
+		"model", p.name,
+		"endpoint", p.endpoint,
+		"features_count", len(features),
+	)
+
+	jsonData, err := json.Marshal(features)
+	if err != nil {
+		return Output{}, fmt.Errorf("error marshaling features: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", p.endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return Output{}, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	// Predictions have no side effects, so failed calls can be retried
+	client := outbound.WithTimeout(models.ProviderPM25Model+"/"+p.name, config.AppConfig.ModelTimeout)
+	resp, err := client.Do(outbound.Idempotent(req))
+	if err != nil {
+		return Output{}, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Output{}, fmt.Errorf("model %s returned status code: %d", p.name, resp.StatusCode)
+	}
+
+	var response struct {
+		FPMVec       []float64            `json:"fpm_vec"`
+		FPMStd       []float64            `json:"fpm_std"`
+		FPMQuantiles map[string][]float64 `json:"fpm_quantiles"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return Output{}, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	if len(response.FPMVec) != len(features) {
+		return Output{}, fmt.Errorf("model %s returned %d predictions for %d feature vectors", p.name, len(response.FPMVec), len(features))
+	}
+
+	out := Output{Values: response.FPMVec, StdDev: response.FPMStd}
+	for key, values := range response.FPMQuantiles {
+		q, err := strconv.ParseFloat(key, 64)
+		if err != nil || q <= 0 || q >= 1 {
+			logger.Warn("Ignoring invalid quantile from remote PM2.5 model",
+				"model", p.name,
+				"quantile", key,
+			)
+			continue
+		}
+		if out.Quantiles == nil {
+			out.Quantiles = map[float64][]float64{}
+		}
+		out.Quantiles[q] = values
+	}
+
+	return out, nil
+}
@@ -0,0 +1,98 @@
+package predictor
+
+import (
+	"math"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+const (
+	// IntervalConfidence is the coverage of intervals derived from a standard deviation
+	IntervalConfidence = 0.9
+	// z-score of a two-sided 90% normal interval
+	intervalZ = 1.6449
+
+	UncertaintyQuantile  = "quantile"
+	UncertaintyStdDev    = "std_dev"
+	UncertaintyEmpirical = "empirical"
+)
+
+// Intervals converts model output into one confidence interval per value.
+// Model quantiles are preferred, then a model standard deviation, and finally
+// the empirical error configured for the feature vector's delay code.
+func Intervals(out Output, features []models.FeatureVector) []models.Interval {
+	intervals := make([]models.Interval, len(out.Values))
+
+	lowQ, highQ := quantileBounds(out.Quantiles, len(out.Values))
+	hasStdDev := len(out.StdDev) == len(out.Values)
+
+	for i, value := range out.Values {
+		switch {
+		case lowQ >= 0:
+			intervals[i] = models.Interval{
+				Lower:      math.Min(value, out.Quantiles[lowQ][i]),
+				Upper:      math.Max(value, out.Quantiles[highQ][i]),
+				Confidence: highQ - lowQ,
+				Source:     UncertaintyQuantile,
+			}
+		case hasStdDev:
+			intervals[i] = normalInterval(value, out.StdDev[i], UncertaintyStdDev)
+		default:
+			var delayCode uint8
+			if i < len(features) {
+				delayCode = features[i].DelayCode
+			}
+			intervals[i] = EmpiricalInterval(value, delayCode)
+		}
+	}
+
+	return intervals
+}
+
+// EmpiricalInterval builds an interval from the configured error for the horizon
+func EmpiricalInterval(value float64, delayCode uint8) models.Interval {
+	return normalInterval(value, horizonError(delayCode), UncertaintyEmpirical)
+}
+
+func normalInterval(value float64, stdDev float64, source string) models.Interval {
+	return models.Interval{
+		Lower:      math.Max(0, value-intervalZ*stdDev),
+		Upper:      value + intervalZ*stdDev,
+		Confidence: IntervalConfidence,
+		Source:     source,
+	}
+}
+
+// horizonError returns the configured RMSE for a delay code, falling back to
+// the error of the longest configured horizon below it
+func horizonError(delayCode uint8) float64 {
+	errorsByHorizon := config.AppConfig.PredictionErrorByHorizon
+	for h := int(delayCode); h >= 0; h-- {
+		if rmse, ok := errorsByHorizon[uint8(h)]; ok {
+			return rmse
+		}
+	}
+	return 0
+}
+
+// quantileBounds picks the outermost quantiles on either side of the median.
+// It returns -1 when the model did not report a usable pair.
+func quantileBounds(quantiles map[float64][]float64, n int) (float64, float64) {
+	low, high := -1.0, -1.0
+	for q, values := range quantiles {
+		if len(values) != n {
+			continue
+		}
+		if q < 0.5 && (low < 0 || q < low) {
+			low = q
+		}
+		if q > 0.5 && q < 1 && (high < 0 || q > high) {
+			high = q
+		}
+	}
+	if low < 0 || high < 0 {
+		return -1, -1
+	}
+	return low, high
+}
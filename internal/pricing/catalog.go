@@ -0,0 +1,162 @@
+package pricing
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+)
+
+//go:embed prices.json
+var builtinPrices []byte
+
+// PriceTable is the on-disk and admin endpoint format of regional prices
+type PriceTable struct {
+	DefaultRegion string   `json:"default_region" yaml:"default_region"`
+	Regions       []Region `json:"regions" yaml:"regions"`
+}
+
+var (
+	mu     sync.RWMutex
+	prices PriceTable
+	zones  []Zone
+)
+
+// Init loads the regional prices from PRICING_FILE, or the built-in table
+// when it is not set, and the toll and congestion-charge zones from
+// TOLL_ZONES_FILE
+func Init() error {
+	var table PriceTable
+	if path := config.AppConfig.PricingFile; path != "" {
+		loaded, err := loadPriceFile(path)
+		if err != nil {
+			return err
+		}
+		table = loaded
+	} else if err := json.Unmarshal(builtinPrices, &table); err != nil {
+		return fmt.Errorf("error parsing built-in prices: %w", err)
+	}
+
+	if err := SetPrices(table); err != nil {
+		return err
+	}
+
+	var loadedZones []Zone
+	if path := config.AppConfig.TollZonesFile; path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("error opening toll zones %s: %w", path, err)
+		}
+		defer file.Close()
+
+		loadedZones, err = parseZonesGeoJSON(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	mu.Lock()
+	zones = loadedZones
+	mu.Unlock()
+
+	logger.Info("Prices loaded", "regions", len(table.Regions), "zones", len(loadedZones))
+	return nil
+}
+
+func loadPriceFile(path string) (PriceTable, error) {
+	var table PriceTable
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return table, fmt.Errorf("error reading prices %s: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &table)
+	default:
+		err = json.Unmarshal(data, &table)
+	}
+	if err != nil {
+		return table, fmt.Errorf("error parsing prices %s: %w", path, err)
+	}
+	return table, nil
+}
+
+// SetPrices validates and replaces the regional prices
+func SetPrices(table PriceTable) error {
+	if len(table.Regions) == 0 {
+		return fmt.Errorf("price table has no regions")
+	}
+
+	ids := map[string]bool{}
+	for _, region := range table.Regions {
+		if err := region.validate(); err != nil {
+			return err
+		}
+		if ids[region.ID] {
+			return fmt.Errorf("duplicate price region %q", region.ID)
+		}
+		ids[region.ID] = true
+	}
+	if table.DefaultRegion == "" {
+		table.DefaultRegion = table.Regions[0].ID
+	}
+	if !ids[table.DefaultRegion] {
+		return fmt.Errorf("default price region %q does not exist", table.DefaultRegion)
+	}
+
+	mu.Lock()
+	prices = table
+	mu.Unlock()
+	return nil
+}
+
+// Prices returns the current regional prices
+func Prices() PriceTable {
+	mu.RLock()
+	defer mu.RUnlock()
+	return prices
+}
+
+// Zones returns the loaded toll and congestion-charge zones
+func Zones() []Zone {
+	mu.RLock()
+	defer mu.RUnlock()
+	return zones
+}
+
+// resolveRegion returns the region a trip starting at point is priced in: the
+// first region whose polygon contains the point, else the region for the
+// country code, else the default region
+func resolveRegion(table PriceTable, country string, point [2]float64) Region {
+	for _, region := range table.Regions {
+		if region.Contains(point) {
+			return region
+		}
+	}
+
+	for _, region := range table.Regions {
+		if country != "" && len(region.Polygon) == 0 && strings.EqualFold(region.Country, country) {
+			return region
+		}
+	}
+	return defaultRegion(table)
+}
+
+func defaultRegion(table PriceTable) Region {
+	for _, region := range table.Regions {
+		if region.ID == table.DefaultRegion {
+			return region
+		}
+	}
+	return Region{}
+}
@@ -0,0 +1,113 @@
+package pricing
+
+import (
+	"math"
+
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+const (
+	// Share of the electricity drawn from the grid that ends up in the battery
+	charging_efficiency = 0.9
+	// Kilojoules per kilowatt-hour
+	kj_per_kwh = 3600
+)
+
+// fuelEnergyDensity is the energy in kJ per unit of fuel sold (litre or kg)
+var fuelEnergyDensity = map[string]float64{
+	"petrol": 34200,
+	"diesel": 38600,
+	"cng":    48000,
+}
+
+// fuelUnits is the unit fuel is sold in
+var fuelUnits = map[string]string{
+	"petrol": "l",
+	"diesel": "l",
+	"cng":    "kg",
+	"ev":     "kWh",
+}
+
+// Trip is what a route's cost is estimated from
+type Trip struct {
+	// EnergyKJ is the fuel (or battery) energy of the route
+	EnergyKJ     float64
+	FuelType     string
+	VehicleClass string
+	// Country is the ISO 3166-1 alpha-2 code of the start, if known
+	Country string
+	// Geometry is the route as [longitude, latitude] points
+	Geometry [][2]float64
+}
+
+// Estimate returns the cost of a trip: its energy bought at the prices of
+// the region it starts in plus the charge of every zone it enters. Prices a
+// region does not list are taken from the default region.
+func Estimate(trip Trip) models.TripCost {
+	table := Prices()
+	var start [2]float64
+	if len(trip.Geometry) > 0 {
+		start = trip.Geometry[0]
+	}
+	region := resolveRegion(table, trip.Country, start)
+
+	// Walking and cycling cost nothing
+	if trip.FuelType == "human" {
+		return models.TripCost{Currency: region.Currency, Region: region.ID}
+	}
+
+	fuelType := trip.FuelType
+	if _, ok := fuelUnits[fuelType]; !ok {
+		fuelType = "petrol"
+	}
+
+	unitPrice, ok := region.Prices[fuelType]
+	if !ok {
+		unitPrice = defaultRegion(table).Prices[fuelType]
+	}
+
+	// Regeneration can leave an electric vehicle with a net gain downhill,
+	// which nobody pays for
+	energyKJ := math.Max(trip.EnergyKJ, 0)
+	var quantity float64
+	if fuelType == "ev" {
+		quantity = energyKJ / kj_per_kwh / charging_efficiency
+	} else {
+		quantity = energyKJ / fuelEnergyDensity[fuelType]
+	}
+
+	cost := models.TripCost{
+		Currency:       region.Currency,
+		Region:         region.ID,
+		EnergyQuantity: quantity,
+		EnergyUnit:     fuelUnits[fuelType],
+		UnitPrice:      unitPrice,
+		Energy:         quantity * unitPrice,
+	}
+
+	for _, zone := range Zones() {
+		if !enters(zone, trip.Geometry) {
+			continue
+		}
+		charge := zone.ChargeFor(trip.VehicleClass, trip.FuelType)
+		cost.Tolls += charge
+		cost.Zones = append(cost.Zones, models.ZoneCharge{
+			ID:     zone.ID,
+			Name:   zone.Name,
+			Type:   zone.Type,
+			Charge: charge,
+		})
+	}
+
+	cost.Total = cost.Energy + cost.Tolls
+	return cost
+}
+
+func enters(zone Zone, geometry [][2]float64) bool {
+	for _, point := range geometry {
+		if zone.Contains(point) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	square := [][2]float64{{77.5, 12.9}, {77.7, 12.9}, {77.7, 13.1}, {77.5, 13.1}, {77.5, 12.9}}
+	if err := SetPrices(PriceTable{
+		DefaultRegion: "in",
+		Regions: []Region{
+			{ID: "in", Country: "IN", Currency: "INR", Prices: map[string]float64{"petrol": 100, "diesel": 90, "ev": 8}},
+			{ID: "blr", Currency: "INR", Polygon: square, Prices: map[string]float64{"petrol": 110}},
+		},
+	}); err != nil {
+		t.Fatalf("SetPrices: %v", err)
+	}
+	mu.Lock()
+	zones = []Zone{{ID: "toll", Type: ZoneTypeToll, Charge: 50, Charges: map[string]float64{"truck": 300}, ExemptFuelTypes: []string{"ev"},
+		polygons: [][][][2]float64{{{{77.8, 12.9}, {77.9, 12.9}, {77.9, 13.0}, {77.8, 13.0}, {77.8, 12.9}}}}}}
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		zones = nil
+		mu.Unlock()
+	}()
+
+	inCity := [][2]float64{{77.6, 13.0}}
+	throughToll := [][2]float64{{78.5, 12.0}, {77.85, 12.95}}
+
+	tests := []struct {
+		name       string
+		trip       Trip
+		wantRegion string
+		wantEnergy float64
+		wantTolls  float64
+	}{
+		{"petrol priced by the city polygon", Trip{EnergyKJ: 34200, FuelType: "petrol", Geometry: inCity}, "blr", 110, 0},
+		{"diesel falls back to the default region", Trip{EnergyKJ: 38600, FuelType: "diesel", Geometry: inCity}, "blr", 90, 0},
+		{"ev pays for charging losses", Trip{EnergyKJ: 3600 * 0.9, FuelType: "ev", Country: "IN"}, "in", 8, 0},
+		{"toll zone by vehicle class", Trip{EnergyKJ: 0, FuelType: "diesel", VehicleClass: "truck", Geometry: throughToll}, "in", 0, 300},
+		{"ev exempt from the toll", Trip{EnergyKJ: 0, FuelType: "ev", Geometry: throughToll}, "in", 0, 0},
+		{"walking is free", Trip{EnergyKJ: 500, FuelType: "human", Geometry: inCity}, "blr", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost := Estimate(tt.trip)
+			if cost.Region != tt.wantRegion {
+				t.Errorf("region %q, want %q", cost.Region, tt.wantRegion)
+			}
+			if math.Abs(cost.Energy-tt.wantEnergy) > 1e-9 || cost.Tolls != tt.wantTolls {
+				t.Errorf("energy %g and tolls %g, want %g and %g", cost.Energy, cost.Tolls, tt.wantEnergy, tt.wantTolls)
+			}
+			if cost.Total != cost.Energy+cost.Tolls {
+				t.Errorf("total %g is not energy plus tolls", cost.Total)
+			}
+		})
+	}
+}
+
+func TestSetPricesRejectsInvalidTables(t *testing.T) {
+	tests := []struct {
+		name  string
+		table PriceTable
+	}{
+		{"no regions", PriceTable{}},
+		{"missing currency", PriceTable{Regions: []Region{{ID: "in"}}}},
+		{"duplicate region", PriceTable{Regions: []Region{{ID: "in", Currency: "INR"}, {ID: "in", Currency: "INR"}}}},
+		{"unknown default", PriceTable{DefaultRegion: "us", Regions: []Region{{ID: "in", Currency: "INR"}}}},
+		{"negative price", PriceTable{Regions: []Region{{ID: "in", Currency: "INR", Prices: map[string]float64{"petrol": -1}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetPrices(tt.table); err == nil {
+				t.Error("SetPrices accepted an invalid table")
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package pricing
+
+import (
+	"fmt"
+
+	"github.com/clean-route/go-backend/internal/geo"
+)
+
+// Region is an area with its own fuel and electricity prices. A region is
+// matched by its polygon when it has one, otherwise by its ISO 3166-1 alpha-2
+// country code.
+type Region struct {
+	ID       string `json:"id" yaml:"id"`
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+	Country  string `json:"country,omitempty" yaml:"country,omitempty"`
+	Currency string `json:"currency" yaml:"currency"`
+	// Polygon is a ring of [longitude, latitude] points
+	Polygon [][2]float64 `json:"polygon,omitempty" yaml:"polygon,omitempty"`
+	// Prices per fuel type: petrol and diesel per litre, cng per kg and ev
+	// per kWh drawn from the grid
+	Prices map[string]float64 `json:"prices" yaml:"prices"`
+}
+
+// Contains reports whether the point lies inside the region's polygon
+func (r Region) Contains(point [2]float64) bool {
+	return geo.RingContains(r.Polygon, point)
+}
+
+func (r Region) validate() error {
+	if r.ID == "" {
+		return fmt.Errorf("price region is missing an id")
+	}
+	if r.Currency == "" {
+		return fmt.Errorf("price region %q is missing a currency", r.ID)
+	}
+	if len(r.Polygon) > 0 && len(r.Polygon) < 3 {
+		return fmt.Errorf("price region %q polygon needs at least 3 points", r.ID)
+	}
+	for fuel, price := range r.Prices {
+		if price < 0 {
+			return fmt.Errorf("price region %q has a negative %s price", r.ID, fuel)
+		}
+	}
+	return nil
+}
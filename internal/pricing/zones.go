@@ -0,0 +1,127 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/clean-route/go-backend/internal/geo"
+)
+
+const (
+	ZoneTypeToll       = "toll"
+	ZoneTypeCongestion = "congestion"
+)
+
+// Zone is a toll or congestion-charge area. A route pays its charge once if
+// any point of its geometry lies inside the zone.
+type Zone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Charge applies to every vehicle class not listed in Charges
+	Charge  float64            `json:"charge"`
+	Charges map[string]float64 `json:"charges,omitempty"`
+	// ExemptFuelTypes pay no charge, e.g. electric vehicles in a
+	// congestion-charge zone
+	ExemptFuelTypes []string `json:"exempt_fuel_types,omitempty"`
+
+	// polygons holds rings of [longitude, latitude] points; the first ring
+	// of each polygon is its outer boundary and the rest are holes
+	polygons [][][][2]float64
+}
+
+// Contains reports whether the point lies inside the zone
+func (z Zone) Contains(point [2]float64) bool {
+	for _, polygon := range z.polygons {
+		if len(polygon) == 0 || !geo.RingContains(polygon[0], point) {
+			continue
+		}
+		inHole := false
+		for _, hole := range polygon[1:] {
+			if geo.RingContains(hole, point) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// ChargeFor returns what a vehicle of the class and fuel type pays to enter
+// the zone
+func (z Zone) ChargeFor(vehicleClass string, fuelType string) float64 {
+	for _, exempt := range z.ExemptFuelTypes {
+		if strings.EqualFold(exempt, fuelType) {
+			return 0
+		}
+	}
+	if charge, ok := z.Charges[vehicleClass]; ok {
+		return charge
+	}
+	return z.Charge
+}
+
+// geoJSONFeatureCollection is the subset of GeoJSON read for zones
+type geoJSONFeatureCollection struct {
+	Features []struct {
+		ID         interface{} `json:"id"`
+		Properties Zone        `json:"properties"`
+		Geometry   struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+// parseZonesGeoJSON reads toll and congestion-charge zones from a GeoJSON
+// FeatureCollection of Polygon and MultiPolygon features. Charges are read
+// from the feature properties id, name, type, charge, charges and
+// exempt_fuel_types.
+func parseZonesGeoJSON(r io.Reader) ([]Zone, error) {
+	var collection geoJSONFeatureCollection
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("error parsing zones GeoJSON: %w", err)
+	}
+
+	zones := make([]Zone, 0, len(collection.Features))
+	for i, feature := range collection.Features {
+		zone := feature.Properties
+		if zone.ID == "" && feature.ID != nil {
+			zone.ID = fmt.Sprint(feature.ID)
+		}
+		if zone.ID == "" {
+			zone.ID = fmt.Sprintf("zone-%d", i+1)
+		}
+		if zone.Type == "" {
+			zone.Type = ZoneTypeToll
+		}
+		if zone.Type != ZoneTypeToll && zone.Type != ZoneTypeCongestion {
+			return nil, fmt.Errorf("zone %q has unknown type %q", zone.ID, zone.Type)
+		}
+		if zone.Charge < 0 {
+			return nil, fmt.Errorf("zone %q has a negative charge", zone.ID)
+		}
+
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var polygon [][][2]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &polygon); err != nil {
+				return nil, fmt.Errorf("error parsing zone %q geometry: %w", zone.ID, err)
+			}
+			zone.polygons = [][][][2]float64{polygon}
+		case "MultiPolygon":
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &zone.polygons); err != nil {
+				return nil, fmt.Errorf("error parsing zone %q geometry: %w", zone.ID, err)
+			}
+		default:
+			return nil, fmt.Errorf("zone %q has unsupported geometry %q", zone.ID, feature.Geometry.Type)
+		}
+		zones = append(zones, zone)
+	}
+	return zones, nil
+}
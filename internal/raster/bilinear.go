@@ -0,0 +1,44 @@
+package raster
+
+// Bilinear interpolates a value at fractional grid position (col, row).
+// value returns false for cells without data.
+func Bilinear(col, row float64, width, height int, value func(x, y int) (float64, bool)) (float64, bool) {
+	if col < 0 || row < 0 || col > float64(width-1) || row > float64(height-1) {
+		return 0, false
+	}
+
+	x0, y0 := int(col), int(row)
+	x1, y1 := x0+1, y0+1
+	if x1 > width-1 {
+		x1 = x0
+	}
+	if y1 > height-1 {
+		y1 = y0
+	}
+	dx, dy := col-float64(x0), row-float64(y0)
+
+	var sum, weights float64
+	for _, corner := range []struct {
+		x, y   int
+		weight float64
+	}{
+		{x0, y0, (1 - dx) * (1 - dy)},
+		{x1, y0, dx * (1 - dy)},
+		{x0, y1, (1 - dx) * dy},
+		{x1, y1, dx * dy},
+	} {
+		if corner.weight == 0 {
+			continue
+		}
+		if v, ok := value(corner.x, corner.y); ok {
+			sum += v * corner.weight
+			weights += corner.weight
+		}
+	}
+
+	// Renormalise around cells without data
+	if weights == 0 {
+		return 0, false
+	}
+	return sum / weights, true
+}
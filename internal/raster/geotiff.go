@@ -0,0 +1,363 @@
+// Package raster reads single-band GeoTIFF rasters in geographic coordinates
+// and interpolates values on regular grids.
+package raster
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TIFF tags used to decode single-band rasters
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPredictor       = 317
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagSampleFormat    = 339
+	tagPixelScale      = 33550
+	tagTiepoint        = 33922
+	tagGDALNoData      = 42113
+)
+
+// TIFF compression schemes supported by the reader
+const (
+	compressionNone       = 1
+	compressionDeflate    = 8
+	compressionDeflateOld = 32946
+)
+
+// GeoTIFF is a GeoTIFF in geographic (WGS84) coordinates whose header has
+// been read. Its raster is decoded by Load.
+type GeoTIFF struct {
+	Path      string
+	order     binary.ByteOrder
+	tags      map[uint16][]float64
+	NoData    *float64
+	Width     int
+	Height    int
+	OriginLon float64 // longitude of the left edge
+	OriginLat float64 // latitude of the top edge
+	ScaleLon  float64
+	ScaleLat  float64
+}
+
+// Raster is a decoded GeoTIFF raster
+type Raster struct {
+	File   *GeoTIFF
+	Pixels []float32
+}
+
+// OpenGeoTIFF reads the header and georeferencing of a GeoTIFF
+func OpenGeoTIFF(path string) (*GeoTIFF, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, fmt.Errorf("error reading TIFF header: %w", err)
+	}
+
+	var order binary.ByteOrder
+	switch string(header[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("not a TIFF file")
+	}
+	if order.Uint16(header[2:]) != 42 {
+		return nil, fmt.Errorf("BigTIFF files are not supported")
+	}
+
+	file := &GeoTIFF{Path: path, order: order}
+	var noData string
+	file.tags, noData, err = readIFD(f, order, int64(order.Uint32(header[4:])))
+	if err != nil {
+		return nil, err
+	}
+
+	file.Width = file.tag(tagImageWidth, 0)
+	file.Height = file.tag(tagImageLength, 0)
+	if file.Width == 0 || file.Height == 0 {
+		return nil, fmt.Errorf("missing image dimensions")
+	}
+	if file.tag(tagSamplesPerPixel, 1) != 1 {
+		return nil, fmt.Errorf("only single-band rasters are supported")
+	}
+
+	scale := file.tags[tagPixelScale]
+	tiepoint := file.tags[tagTiepoint]
+	if len(scale) < 2 || len(tiepoint) < 6 {
+		return nil, fmt.Errorf("missing GeoTIFF georeferencing")
+	}
+	file.ScaleLon = scale[0]
+	file.ScaleLat = scale[1]
+	file.OriginLon = tiepoint[3] - tiepoint[0]*file.ScaleLon
+	file.OriginLat = tiepoint[4] + tiepoint[1]*file.ScaleLat
+
+	if noData = strings.Trim(noData, "\x00 "); noData != "" {
+		if value, err := strconv.ParseFloat(noData, 64); err == nil {
+			file.NoData = &value
+		}
+	}
+
+	return file, nil
+}
+
+// readIFD reads the first image file directory as numeric values per tag,
+// returning the GDAL no-data string separately
+func readIFD(f io.ReaderAt, order binary.ByteOrder, offset int64) (map[uint16][]float64, string, error) {
+	countBytes := make([]byte, 2)
+	if _, err := f.ReadAt(countBytes, offset); err != nil {
+		return nil, "", fmt.Errorf("error reading TIFF directory: %w", err)
+	}
+	count := int(order.Uint16(countBytes))
+
+	entries := make([]byte, count*12)
+	if _, err := f.ReadAt(entries, offset+2); err != nil {
+		return nil, "", fmt.Errorf("error reading TIFF directory: %w", err)
+	}
+
+	tags := map[uint16][]float64{}
+	var noData string
+	for i := 0; i < count; i++ {
+		entry := entries[i*12 : i*12+12]
+		tag := order.Uint16(entry)
+		fieldType := order.Uint16(entry[2:])
+		valueCount := int(order.Uint32(entry[4:]))
+
+		size := map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 6: 1, 8: 2, 9: 4, 11: 4, 12: 8}[fieldType]
+		if size == 0 {
+			continue
+		}
+
+		data := entry[8:12]
+		if size*valueCount > 4 {
+			data = make([]byte, size*valueCount)
+			if _, err := f.ReadAt(data, int64(order.Uint32(entry[8:]))); err != nil {
+				return nil, "", fmt.Errorf("error reading TIFF tag %d: %w", tag, err)
+			}
+		}
+
+		if fieldType == 2 {
+			if tag == tagGDALNoData {
+				noData = string(data[:valueCount])
+			}
+			continue
+		}
+
+		values := make([]float64, valueCount)
+		for j := range values {
+			values[j] = decodeValue(data[j*size:], fieldType, order)
+		}
+		tags[tag] = values
+	}
+	return tags, noData, nil
+}
+
+func decodeValue(data []byte, fieldType uint16, order binary.ByteOrder) float64 {
+	switch fieldType {
+	case 1:
+		return float64(data[0])
+	case 6:
+		return float64(int8(data[0]))
+	case 3:
+		return float64(order.Uint16(data))
+	case 8:
+		return float64(int16(order.Uint16(data)))
+	case 4:
+		return float64(order.Uint32(data))
+	case 9:
+		return float64(int32(order.Uint32(data)))
+	case 11:
+		return float64(math.Float32frombits(order.Uint32(data)))
+	case 12:
+		return math.Float64frombits(order.Uint64(data))
+	}
+	return 0
+}
+
+func (g *GeoTIFF) tag(tag uint16, fallback int) int {
+	if values := g.tags[tag]; len(values) > 0 {
+		return int(values[0])
+	}
+	return fallback
+}
+
+// Contains reports whether a point lies inside the raster's extent
+func (g *GeoTIFF) Contains(lon, lat float64) bool {
+	return lon >= g.OriginLon && lon <= g.OriginLon+float64(g.Width)*g.ScaleLon &&
+		lat <= g.OriginLat && lat >= g.OriginLat-float64(g.Height)*g.ScaleLat
+}
+
+// Load decodes the raster from its strips or tiles
+func (g *GeoTIFF) Load() (*Raster, error) {
+	data, err := os.ReadFile(g.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", g.Path, err)
+	}
+
+	t := &Raster{File: g, Pixels: make([]float32, g.Width*g.Height)}
+
+	if offsets := g.tags[tagTileOffsets]; len(offsets) > 0 {
+		blockWidth := g.tag(tagTileWidth, 0)
+		blockHeight := g.tag(tagTileLength, 0)
+		if blockWidth == 0 || blockHeight == 0 {
+			return nil, fmt.Errorf("missing tile dimensions")
+		}
+		across := (g.Width + blockWidth - 1) / blockWidth
+		for i := range offsets {
+			x := (i % across) * blockWidth
+			y := (i / across) * blockHeight
+			if err := t.decodeBlock(data, i, offsets, g.tags[tagTileByteCounts], x, y, blockWidth, blockHeight); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	}
+
+	offsets := g.tags[tagStripOffsets]
+	if len(offsets) == 0 {
+		return nil, fmt.Errorf("missing strip offsets")
+	}
+	rowsPerStrip := g.tag(tagRowsPerStrip, g.Height)
+	for i := range offsets {
+		if err := t.decodeBlock(data, i, offsets, g.tags[tagStripByteCounts], 0, i*rowsPerStrip, g.Width, rowsPerStrip); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// decodeBlock decompresses a strip or tile of blockWidth x blockHeight pixels
+// whose top-left pixel is (x, y) and copies the part inside the image
+func (t *Raster) decodeBlock(data []byte, index int, offsets, byteCounts []float64, x, y, blockWidth, blockHeight int) error {
+	g := t.File
+	if index >= len(byteCounts) {
+		return fmt.Errorf("missing byte count for block %d", index)
+	}
+	start, length := int(offsets[index]), int(byteCounts[index])
+	if start < 0 || start+length > len(data) {
+		return fmt.Errorf("block %d is outside the file", index)
+	}
+	block := data[start : start+length]
+
+	switch g.tag(tagCompression, compressionNone) {
+	case compressionNone:
+	case compressionDeflate, compressionDeflateOld:
+		reader, err := zlib.NewReader(bytes.NewReader(block))
+		if err != nil {
+			return fmt.Errorf("error decompressing block %d: %w", index, err)
+		}
+		block, err = io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("error decompressing block %d: %w", index, err)
+		}
+	default:
+		return fmt.Errorf("unsupported TIFF compression %d", g.tag(tagCompression, 0))
+	}
+
+	bits := g.tag(tagBitsPerSample, 16)
+	format := g.tag(tagSampleFormat, 1)
+	size := bits / 8
+	if size == 0 || (format == 3 && bits != 32 && bits != 64) {
+		return fmt.Errorf("unsupported sample size %d bits", bits)
+	}
+
+	predictor := g.tag(tagPredictor, 1)
+	if predictor == 2 && format != 3 {
+		undoHorizontalDifferencing(block, blockWidth, size, g.order)
+	} else if predictor != 1 {
+		return fmt.Errorf("unsupported TIFF predictor %d", predictor)
+	}
+
+	for row := 0; row < blockHeight && y+row < g.Height; row++ {
+		for col := 0; col < blockWidth && x+col < g.Width; col++ {
+			offset := (row*blockWidth + col) * size
+			if offset+size > len(block) {
+				return nil
+			}
+			t.Pixels[(y+row)*g.Width+x+col] = float32(decodeSample(block[offset:], bits, format, g.order))
+		}
+	}
+	return nil
+}
+
+// undoHorizontalDifferencing reverses TIFF predictor 2 for integer samples
+func undoHorizontalDifferencing(block []byte, width int, size int, order binary.ByteOrder) {
+	rowBytes := width * size
+	for rowStart := 0; rowStart+rowBytes <= len(block); rowStart += rowBytes {
+		for i := rowStart + size; i < rowStart+rowBytes; i += size {
+			switch size {
+			case 1:
+				block[i] += block[i-1]
+			case 2:
+				order.PutUint16(block[i:], order.Uint16(block[i:])+order.Uint16(block[i-2:]))
+			case 4:
+				order.PutUint32(block[i:], order.Uint32(block[i:])+order.Uint32(block[i-4:]))
+			}
+		}
+	}
+}
+
+func decodeSample(data []byte, bits int, format int, order binary.ByteOrder) float64 {
+	switch {
+	case format == 3 && bits == 32:
+		return float64(math.Float32frombits(order.Uint32(data)))
+	case format == 3 && bits == 64:
+		return math.Float64frombits(order.Uint64(data))
+	case bits == 8 && format == 2:
+		return float64(int8(data[0]))
+	case bits == 8:
+		return float64(data[0])
+	case bits == 16 && format == 2:
+		return float64(int16(order.Uint16(data)))
+	case bits == 16:
+		return float64(order.Uint16(data))
+	case bits == 32 && format == 2:
+		return float64(int32(order.Uint32(data)))
+	case bits == 32:
+		return float64(order.Uint32(data))
+	}
+	return 0
+}
+
+// Value returns the bilinearly interpolated value at a point, or false if
+// the surrounding cells have no data
+func (t *Raster) Value(lon, lat float64) (float64, bool) {
+	g := t.File
+
+	// Pixel values describe the centre of each cell
+	col := (lon-g.OriginLon)/g.ScaleLon - 0.5
+	row := (g.OriginLat-lat)/g.ScaleLat - 0.5
+	col = math.Max(0, math.Min(col, float64(g.Width-1)))
+	row = math.Max(0, math.Min(row, float64(g.Height-1)))
+
+	return Bilinear(col, row, g.Width, g.Height, func(x, y int) (float64, bool) {
+		value := float64(t.Pixels[y*g.Width+x])
+		if math.IsNaN(value) || (g.NoData != nil && value == float64(float32(*g.NoData))) {
+			return 0, false
+		}
+		return value, true
+	})
+}
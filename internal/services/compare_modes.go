@@ -0,0 +1,238 @@
+package services
+
+import (
+	stderrors "errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/clean-route/go-backend/internal/errors"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+	graphhopperroutes "github.com/clean-route/go-backend/internal/models/graphhopper"
+	mapboxroutes "github.com/clean-route/go-backend/internal/models/mapbox"
+	"github.com/clean-route/go-backend/internal/outbound"
+)
+
+// defaultCompareModes are compared when a request does not list its modes
+var defaultCompareModes = []string{"driving-traffic", "scooter", "bike", "foot", "transit"}
+
+// compareModes are the modes a comparison can include
+var compareModes = map[string]bool{
+	"driving-traffic": true,
+	"scooter":         true,
+	"foot":            true,
+	"bike":            true,
+	"mtb":             true,
+	"e-bike":          true,
+	"transit":         true,
+}
+
+// compareMetrics are the metrics the recommendation weighs
+var compareMetrics = []string{"duration", "cost", "co2", "dose"}
+
+// CompareModes finds the best route of every requested mode concurrently and
+// recommends the mode with the lowest weighted score. Modes that fail are
+// reported as unavailable rather than failing the comparison.
+func (rs *RouteService) CompareModes(req models.CompareModesRequest) (*models.ModeComparison, error) {
+	modes := req.Modes
+	if len(modes) == 0 {
+		modes = defaultCompareModes
+	}
+	routePref := req.RoutePreference
+	if routePref == "" {
+		routePref = "balanced"
+	}
+
+	// Every mode starts its own routing goroutine, so duplicates are rejected
+	// and the list can be no longer than the set of supported modes
+	if len(modes) > len(compareModes) {
+		return nil, errors.NewValidationError(fmt.Sprintf("at most %d modes can be compared", len(compareModes)), nil)
+	}
+	seen := make(map[string]bool, len(modes))
+	for _, mode := range modes {
+		if !compareModes[mode] {
+			return nil, errors.NewValidationError(fmt.Sprintf("unsupported mode: %s", mode), nil)
+		}
+		if seen[mode] {
+			return nil, errors.NewValidationError(fmt.Sprintf("duplicate mode: %s", mode), nil)
+		}
+		seen[mode] = true
+	}
+	for metric, weight := range req.Weights {
+		if !contains(compareMetrics, metric) || weight < 0 {
+			return nil, errors.NewValidationError(fmt.Sprintf("invalid weight: %s", metric), nil)
+		}
+	}
+
+	// Every mode starts at the same place, so the weather is looked up once
+	weather := newEnergyWeather(req.Source)
+	summaries := make([]models.ModeSummary, len(modes))
+	var wg sync.WaitGroup
+	for i, mode := range modes {
+		wg.Add(1)
+		go func(i int, mode string) {
+			defer wg.Done()
+			summaries[i] = rs.summarizeMode(req, mode, routePref, weather)
+		}(i, mode)
+	}
+	wg.Wait()
+
+	comparison := &models.ModeComparison{
+		Source:      req.Source[:],
+		Destination: req.Destination[:],
+		DelayCode:   req.DelayCode,
+		RoutePref:   routePref,
+		Modes:       summaries,
+	}
+	comparison.RecommendedMode = recommendMode(comparison.Modes, req.Weights)
+
+	logger.Debug("Compared modes",
+		"modes", len(modes),
+		"recommended_mode", comparison.RecommendedMode,
+	)
+	return comparison, nil
+}
+
+// summarizeMode finds the best route of one mode and reduces it to a row of
+// the comparison
+func (rs *RouteService) summarizeMode(req models.CompareModesRequest, mode string, routePref string, weather energyWeather) models.ModeSummary {
+	summary := models.ModeSummary{Mode: mode}
+
+	// No transit routing provider is integrated yet
+	if mode == "transit" {
+		summary.Reason = "transit routing is not available"
+		return summary
+	}
+
+	routeReq := models.RouteRequest{
+		Source:          req.Source,
+		Destination:     req.Destination,
+		DelayCode:       req.DelayCode,
+		Mode:            mode,
+		RoutePreference: routePref,
+		Condition:       req.Condition,
+		VehicleProfile:  req.VehicleProfiles[mode],
+	}
+
+	result, err := rs.findSingleRoute(routeReq, weather)
+	if err != nil {
+		logger.Warn("Failed to find route for mode comparison",
+			"error", err.Error(),
+			"mode", mode,
+		)
+		summary.Reason = unavailableReason(err)
+		return summary
+	}
+
+	summary.Available = true
+	switch route := result.(type) {
+	case mapboxroutes.Route:
+		summary.Distance = route.Distance
+		summary.Duration = route.Duration
+		summary.Cost = route.Cost.Total
+		summary.Currency = route.Cost.Currency
+		summary.CO2Grams = route.Emissions.CO2Grams
+		summary.Exposure = route.TotalExposure
+		summary.InhaledDose = route.InhaledDose
+	case graphhopperroutes.Path:
+		// findSingleRoute has already converted the time to seconds
+		summary.Distance = route.Distance
+		summary.Duration = float64(route.Time)
+		summary.Cost = route.Cost.Total
+		summary.Currency = route.Cost.Currency
+		summary.CO2Grams = route.Emissions.CO2Grams
+		summary.Exposure = route.TotalExposure
+		summary.InhaledDose = route.InhaledDose
+		if route.Effort != nil {
+			kcal := route.Effort.KCal
+			summary.KCal = &kcal
+		}
+	default:
+		summary.Available = false
+		summary.Reason = fmt.Sprintf("unexpected route type %T", result)
+	}
+	return summary
+}
+
+// unavailableReason explains why a mode has no route. The error itself is
+// only logged: its text can carry provider URLs with their API keys.
+func unavailableReason(err error) string {
+	switch {
+	case stderrors.Is(err, outbound.ErrCircuitOpen):
+		return "routing provider is temporarily unavailable"
+	case stderrors.Is(err, outbound.ErrRateLimited),
+		stderrors.Is(err, outbound.ErrQuotaExceeded),
+		stderrors.Is(err, outbound.ErrQuotaSoftLimit):
+		return "routing provider rate limit reached"
+	}
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) {
+		return appErr.Message
+	}
+	return "route not available"
+}
+
+// recommendMode scores every available mode by the weighted sum of its
+// duration, cost, CO2 and inhaled dose, each divided by the largest value
+// among the modes, and returns the mode with the lowest score
+func recommendMode(summaries []models.ModeSummary, weights map[string]float64) string {
+	metric := func(summary models.ModeSummary, name string) float64 {
+		switch name {
+		case "duration":
+			return summary.Duration
+		case "cost":
+			return summary.Cost
+		case "co2":
+			return summary.CO2Grams
+		default:
+			return summary.InhaledDose
+		}
+	}
+
+	maxima := map[string]float64{}
+	for _, summary := range summaries {
+		if !summary.Available {
+			continue
+		}
+		for _, name := range compareMetrics {
+			maxima[name] = math.Max(maxima[name], metric(summary, name))
+		}
+	}
+
+	recommended := -1
+	for i := range summaries {
+		if !summaries[i].Available {
+			continue
+		}
+		var score float64
+		for _, name := range compareMetrics {
+			if maxima[name] == 0 {
+				continue
+			}
+			weight, ok := weights[name]
+			if !ok {
+				weight = 1
+			}
+			score += weight * metric(summaries[i], name) / maxima[name]
+		}
+		summaries[i].Score = score
+		if recommended < 0 || score < summaries[recommended].Score {
+			recommended = i
+		}
+	}
+
+	if recommended < 0 {
+		return ""
+	}
+	return summaries[recommended].Mode
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/errors"
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/outbound"
+	"github.com/clean-route/go-backend/internal/vehicles"
+)
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("connection refused")
+}
+
+func TestCompareModesRejectsInvalidModes(t *testing.T) {
+	tests := []struct {
+		name  string
+		modes []string
+	}{
+		{"unsupported mode", []string{"bike", "hovercraft"}},
+		{"duplicate mode", []string{"bike", "foot", "bike"}},
+		{"more modes than supported", []string{
+			"driving-traffic", "scooter", "foot", "bike", "mtb", "e-bike", "transit", "bike",
+		}},
+	}
+
+	rs := NewRouteService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rs.CompareModes(models.CompareModesRequest{Modes: tt.modes})
+			appErr := errors.GetAppError(err)
+			if appErr == nil || appErr.Type != errors.ErrorTypeValidation {
+				t.Fatalf("error %v, want a validation error", err)
+			}
+		})
+	}
+}
+
+func TestRecommendMode(t *testing.T) {
+	summaries := func() []models.ModeSummary {
+		return []models.ModeSummary{
+			{Mode: "driving-traffic", Available: true, Duration: 600, Cost: 100, CO2Grams: 2000, InhaledDose: 10},
+			{Mode: "bike", Available: true, Duration: 1800, Cost: 0, CO2Grams: 0, InhaledDose: 40},
+			{Mode: "transit", Reason: "transit routing is not available"},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		summaries []models.ModeSummary
+		weights   map[string]float64
+		want      string
+	}{
+		{"equal weights", summaries(), nil, "bike"},
+		{"duration only", summaries(), map[string]float64{"cost": 0, "co2": 0, "dose": 0}, "driving-traffic"},
+		{"dose dominates", summaries(), map[string]float64{"dose": 10}, "driving-traffic"},
+		{"no available mode", []models.ModeSummary{{Mode: "transit"}}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recommendMode(tt.summaries, tt.weights); got != tt.want {
+				t.Errorf("recommendMode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareModesHidesProviderErrors(t *testing.T) {
+	config.AppConfig = &config.Config{MapboxAPIKey: "mapbox-secret", GraphhopperAPIKey: "graphhopper-secret"}
+	if err := vehicles.Init(); err != nil {
+		t.Fatalf("vehicles.Init: %v", err)
+	}
+	// Failed calls return a *url.Error carrying the full request URL
+	transport := http.DefaultTransport
+	http.DefaultTransport = failingTransport{}
+	defer func() { http.DefaultTransport = transport }()
+
+	req := models.CompareModesRequest{
+		Source:      [2]float64{77.59, 12.97},
+		Destination: [2]float64{77.62, 12.93},
+		Modes:       []string{"driving-traffic", "bike"},
+	}
+	comparison, err := NewRouteService().CompareModes(req)
+	if err != nil {
+		t.Fatalf("CompareModes: %v", err)
+	}
+	body, _ := json.Marshal(comparison)
+	for _, leaked := range []string{"secret", "api.mapbox.com", "graphhopper.com", "access_token", "caused by"} {
+		if strings.Contains(string(body), leaked) {
+			t.Errorf("response contains %q: %s", leaked, body)
+		}
+	}
+	for _, summary := range comparison.Modes {
+		if summary.Available || summary.Reason == "" {
+			t.Errorf("mode %s: available %v with reason %q, want unavailable with a reason", summary.Mode, summary.Available, summary.Reason)
+		}
+	}
+}
+
+func TestUnavailableReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"circuit open", errors.NewExternalError("error calling Mapbox API", fmt.Errorf("mapbox: %w", outbound.ErrCircuitOpen)), "routing provider is temporarily unavailable"},
+		{"rate limited", errors.Wrap(fmt.Errorf("graphhopper: %w", outbound.ErrRateLimited), "failed to find route"), "routing provider rate limit reached"},
+		{"quota exhausted", fmt.Errorf("mapbox: %w", outbound.ErrQuotaExceeded), "routing provider rate limit reached"},
+		{"no route", errors.Wrap(errors.NewNotFoundError("No routes found for the given coordinates", nil), "failed"), "No routes found for the given coordinates"},
+		{"other error", fmt.Errorf(`Get "https://example.com/?key=secret": timeout`), "route not available"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unavailableReason(tt.err); got != tt.want {
+				t.Errorf("unavailableReason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
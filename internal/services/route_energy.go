@@ -0,0 +1,194 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+	graphhopperroutes "github.com/clean-route/go-backend/internal/models/graphhopper"
+	mapboxroutes "github.com/clean-route/go-backend/internal/models/mapbox"
+	"github.com/clean-route/go-backend/internal/pricing"
+	"github.com/clean-route/go-backend/internal/utils"
+	"github.com/clean-route/go-backend/internal/vehicles"
+)
+
+// routeEnergy holds what is needed to compute the energy of every route in a
+// request: the resolved vehicle, the weather at the start of the trip and,
+// for electric vehicles, the battery state
+type routeEnergy struct {
+	rs          *RouteService
+	routingMode string
+	vehicle     vehicles.Profile
+	condition   string
+	weather     *utils.EnergyWeather
+	startSoC    float64
+	reserveSoC  float64
+}
+
+// energyWeather looks up the weather at the start of a trip. One lookup is
+// shared by every route, and every mode, of a request.
+type energyWeather func() *utils.EnergyWeather
+
+// newEnergyWeather returns a lookup of the weather at source that asks the
+// weather providers on first use only
+func newEnergyWeather(source [2]float64) energyWeather {
+	var once sync.Once
+	var conditions *utils.EnergyWeather
+	return func() *utils.EnergyWeather {
+		once.Do(func() { conditions = utils.GetEnergyWeather(source[:]) })
+		return conditions
+	}
+}
+
+func (rs *RouteService) newRouteEnergy(req models.RouteRequest, weather energyWeather) (routeEnergy, error) {
+	vehicle, err := vehicles.Resolve(req)
+	if err != nil {
+		return routeEnergy{}, err
+	}
+
+	// driving-traffic is a Mapbox profile; charging stops are routed by
+	// GraphHopper, which calls it car
+	routingMode := req.Mode
+	if routingMode == "driving-traffic" {
+		routingMode = "car"
+	}
+
+	energy := routeEnergy{
+		rs:          rs,
+		routingMode: routingMode,
+		vehicle:     vehicle,
+		condition:   req.Condition,
+		startSoC:    100,
+		reserveSoC:  config.AppConfig.EVReserveSoC,
+	}
+	if req.StartSoC != nil {
+		energy.startSoC = *req.StartSoC
+	}
+	if req.ReserveSoC != nil {
+		energy.reserveSoC = *req.ReserveSoC
+	}
+	energy.weather = weather()
+	return energy, nil
+}
+
+// applyToPath sets the energy, battery balance (electric vehicles only),
+// effort (walking and cycling only), emissions, cost and inhaled dose of a
+// GraphHopper path whose exposure is already known. Missing weather is
+// reported in the path's data quality.
+func (e routeEnergy) applyToPath(path *graphhopperroutes.Path) {
+	if e.weather == nil {
+		path.DataQuality.Degrade(models.ProviderOpenWeather)
+	}
+	segments := utils.GraphhopperSegments(*path)
+	path.TotalEnergy, path.EVEnergy, path.Emissions = e.forSegments(segments)
+
+	if e.vehicle.IsHumanPowered() {
+		effort := utils.CalculateHumanEffort(segments, path.Ascend, path.Descend, e.vehicle, e.weather)
+		path.Effort = &effort
+	}
+	path.VentilationRate = utils.GetVentilationRate(e.vehicle, path.Effort)
+	path.InhaledDose = path.TotalExposure * path.VentilationRate
+
+	geometry := make([][2]float64, 0, len(path.Points.Coordinates))
+	for _, coordinate := range path.Points.Coordinates {
+		geometry = append(geometry, [2]float64{coordinate[0], coordinate[1]})
+	}
+	path.Cost = e.cost(path.TotalEnergy, "", geometry)
+}
+
+// applyToMapboxRoute sets the energy, battery balance, emissions, cost and
+// inhaled dose of a Mapbox route from its own geometry. Missing elevation or weather data is
+// reported in the route's data quality.
+func (e routeEnergy) applyToMapboxRoute(route *mapboxroutes.Route) {
+	if e.weather == nil {
+		route.DataQuality.Degrade(models.ProviderOpenWeather)
+	}
+	segments, complete := utils.MapboxSegments(*route)
+	if !complete {
+		route.DataQuality.Degrade(models.ProviderElevation)
+	}
+	route.TotalEnergy, route.EVEnergy, route.Emissions = e.forSegments(segments)
+
+	// Mapbox reports the countries a leg passes through; the trip is priced
+	// where it starts
+	var country string
+	if len(route.Legs) > 0 && len(route.Legs[0].Admins) > 0 {
+		country = route.Legs[0].Admins[0].Iso31661
+	}
+	geometry := make([][2]float64, 0, len(route.Geometry.Coordinates))
+	for _, coordinate := range route.Geometry.Coordinates {
+		if len(coordinate) >= 2 {
+			geometry = append(geometry, [2]float64{coordinate[0], coordinate[1]})
+		}
+	}
+	route.Cost = e.cost(route.TotalEnergy, country, geometry)
+
+	route.VentilationRate = utils.GetVentilationRate(e.vehicle, nil)
+	route.InhaledDose = route.TotalExposure * route.VentilationRate
+}
+
+// cost prices the energy of a route and the zones its geometry enters
+func (e routeEnergy) cost(energyKJ float64, country string, geometry [][2]float64) models.TripCost {
+	return pricing.Estimate(pricing.Trip{
+		EnergyKJ:     energyKJ,
+		FuelType:     e.vehicle.FuelType,
+		VehicleClass: e.vehicle.Class,
+		Country:      country,
+		Geometry:     geometry,
+	})
+}
+
+// forSegments returns the energy in kJ, the battery balance and the emissions
+// of driving the route segments
+func (e routeEnergy) forSegments(segments []utils.EnergySegment) (float64, *models.EVEnergy, models.Emissions) {
+	// Walking and cycling burn no fuel
+	if e.vehicle.FuelType == "human" {
+		return 0, nil, models.Emissions{}
+	}
+	if !e.vehicle.IsElectric() {
+		energyKJ := utils.CalculateRouteEnergy(segments, e.vehicle, e.condition, e.weather)
+		return energyKJ, nil, utils.CalculateRouteEmissions(energyKJ, e.vehicle.FuelType, e.condition)
+	}
+
+	ev := utils.CalculateEVRouteEnergy(segments, e.vehicle, e.condition, e.weather, e.startSoC, e.reserveSoC)
+	if ev.BelowReserve {
+		ev.ChargingPlan = e.planCharging(segments)
+	}
+	energyKJ := ev.ConsumedKWh * 3600
+	return energyKJ, &ev, utils.CalculateRouteEmissions(energyKJ, e.vehicle.FuelType, e.condition)
+}
+
+// planCharging plans charging stops for a route the battery cannot finish and
+// fetches the route through them
+func (e routeEnergy) planCharging(segments []utils.EnergySegment) *models.ChargingPlan {
+	plan := utils.PlanChargingStops(segments, e.vehicle, e.condition, e.weather, e.startSoC, e.reserveSoC)
+	if !plan.Feasible || len(plan.Stops) == 0 {
+		return plan
+	}
+
+	points := [][2]float64{segments[0].Start}
+	points = append(points, plan.ViaPoints...)
+	points = append(points, segments[len(segments)-1].End)
+
+	// The stops are still useful without the exact route through them
+	viaRoute, err := e.rs.FindGraphhopperRouteVia(points, e.routingMode)
+	if err != nil {
+		logger.Warn("Failed to fetch route through charging stops",
+			"error", err.Error(),
+			"stops", len(plan.Stops),
+		)
+		return plan
+	}
+	if len(viaRoute.Paths) == 0 {
+		return plan
+	}
+
+	viaPath := viaRoute.Paths[0]
+	plan.Distance = viaPath.Distance
+	plan.DrivingTime = float64(viaPath.Time) / 1000
+	for _, coordinate := range viaPath.Points.Coordinates {
+		plan.Geometry = append(plan.Geometry, []float64{coordinate[0], coordinate[1]})
+	}
+	return plan
+}
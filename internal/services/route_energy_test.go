@@ -0,0 +1,78 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/models"
+	graphhopperroutes "github.com/clean-route/go-backend/internal/models/graphhopper"
+	"github.com/clean-route/go-backend/internal/vehicles"
+	"github.com/clean-route/go-backend/internal/weather"
+)
+
+func TestEnergyWeatherLooksUpOnce(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"current": {"time": 1704103200, "temperature_2m": 31, "wind_speed_10m": 4, "wind_direction_10m": 90}}`))
+	}))
+	defer server.Close()
+
+	// The weather cache is disabled, so every lookup would reach the provider
+	config.AppConfig = &config.Config{WeatherProviders: []string{models.ProviderOpenMeteo}, OpenMeteoURL: server.URL}
+	if err := weather.Init(); err != nil {
+		t.Fatalf("weather.Init: %v", err)
+	}
+
+	lookup := newEnergyWeather([2]float64{77.59, 12.97})
+	first, second := lookup(), lookup()
+	if first == nil || first != second || first.TempC != 31 {
+		t.Fatalf("lookups returned %+v and %+v, want the same conditions", first, second)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("weather provider called %d times, want once", n)
+	}
+}
+
+func TestApplyToPathInhaledDose(t *testing.T) {
+	car := vehicles.Profile{Class: "car", MassKg: 1200, DragCoefficient: 0.3, FrontalAreaM2: 2.2, RollingResistance: 0.01, FuelType: "petrol"}
+	pedestrian := vehicles.Profile{Class: "pedestrian", MassKg: 70, BodyMassKg: 70, DragCoefficient: 1.0, FrontalAreaM2: 0.6, FuelType: "human"}
+	bike := vehicles.Profile{Class: "bicycle", MassKg: 85, BodyMassKg: 70, DragCoefficient: 0.9, FrontalAreaM2: 0.5, RollingResistance: 0.006, FuelType: "human"}
+
+	tests := []struct {
+		name    string
+		vehicle vehicles.Profile
+		timeMs  int
+		effort  bool
+	}{
+		{"car", car, 100000, false},
+		{"walking", pedestrian, 720000, true},
+		{"cycling", bike, 200000, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 1 km heading north with an exposure of 100
+			path := graphhopperroutes.Path{
+				Points:        graphhopperroutes.Waypoint{Coordinates: []graphhopperroutes.Coordinates{{77.59, 12.97, 900}, {77.59, 12.979, 900}}},
+				Instructions:  []graphhopperroutes.Instruction{{Distance: 1000, Time: tt.timeMs, Interval: []int{0, 1}}},
+				TotalExposure: 100,
+			}
+			routeEnergy{vehicle: tt.vehicle, condition: "new"}.applyToPath(&path)
+
+			if (path.Effort != nil) != tt.effort {
+				t.Fatalf("effort %+v, want effort %v", path.Effort, tt.effort)
+			}
+			// Occupants of a car breathe at rest; walkers and riders harder
+			if (tt.effort && path.VentilationRate <= 0.6) || (!tt.effort && path.VentilationRate != 0.6) {
+				t.Errorf("ventilation %g m³/h", path.VentilationRate)
+			}
+			if path.InhaledDose != path.TotalExposure*path.VentilationRate {
+				t.Errorf("inhaled dose %g, want exposure %g times ventilation %g", path.InhaledDose, path.TotalExposure, path.VentilationRate)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/clean-route/go-backend/internal/models"
 	graphhopperroutes "github.com/clean-route/go-backend/internal/models/graphhopper"
 	mapboxroutes "github.com/clean-route/go-backend/internal/models/mapbox"
+	"github.com/clean-route/go-backend/internal/outbound"
 	"github.com/clean-route/go-backend/internal/utils"
 )
 
@@ -38,6 +39,8 @@ func (rs *RouteService) FindMapboxRoute(source [2]float64, destination [2]float6
 	params.Add("geometries", "geojson")
 	params.Add("alternatives", "true")
 	params.Add("waypoints_per_route", "true")
+	params.Add("annotations", "distance,duration,speed,congestion")
+	params.Add("overview", "full")
 	params.Add("access_token", config.AppConfig.MapboxAPIKey)
 	params.Add("depart_at", departureTime)
 
@@ -49,7 +52,11 @@ func (rs *RouteService) FindMapboxRoute(source [2]float64, destination [2]float6
 		"delay_code", delayCode,
 	)
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return mapboxroutes.RouteData{}, errors.NewInternalError("error creating Mapbox request", err)
+	}
+	resp, err := outbound.For(models.ProviderMapbox).Do(req)
 	if err != nil {
 		logger.Error("Failed to call Mapbox API",
 			"error", err.Error(),
@@ -106,20 +113,46 @@ func (rs *RouteService) FindMapboxRoute(source [2]float64, destination [2]float6
 
 // FindGraphhopperRoute finds routes using GraphHopper API
 func (rs *RouteService) FindGraphhopperRoute(source [2]float64, destination [2]float64, mode string) (graphhopperroutes.RouteData, error) {
+	return rs.findGraphhopperRoute([][2]float64{source, destination}, mode)
+}
+
+// FindGraphhopperRouteVia finds a single GraphHopper route passing through
+// every point in order. GraphHopper does not return alternatives for routes
+// with via points.
+func (rs *RouteService) FindGraphhopperRouteVia(points [][2]float64, mode string) (graphhopperroutes.RouteData, error) {
+	return rs.findGraphhopperRoute(points, mode)
+}
+
+// graphhopperVehicles maps request modes to GraphHopper vehicles where they
+// differ. GraphHopper has no e-bike vehicle, so e-bikes are routed as bicycles.
+var graphhopperVehicles = map[string]string{
+	"e-bike": "bike",
+}
+
+func (rs *RouteService) findGraphhopperRoute(points [][2]float64, mode string) (graphhopperroutes.RouteData, error) {
 	baseUrl := "https://graphhopper.com/api/1/route?"
+	source := points[0]
+	destination := points[len(points)-1]
 
 	params := url.Values{}
-	params.Add("point", fmt.Sprintf("%f,%f", source[1], source[0]))
-	params.Add("point", fmt.Sprintf("%f,%f", destination[1], destination[0]))
-	params.Add("vehicle", mode)
+	for _, point := range points {
+		params.Add("point", fmt.Sprintf("%f,%f", point[1], point[0]))
+	}
+	vehicle := mode
+	if mapped, ok := graphhopperVehicles[mode]; ok {
+		vehicle = mapped
+	}
+	params.Add("vehicle", vehicle)
 	params.Add("debug", "true")
 	params.Add("key", config.AppConfig.GraphhopperAPIKey)
 	params.Add("type", "json")
 	params.Add("points_encoded", "false")
-	params.Add("algorithm", "alternative_route")
-	params.Add("alternative_route.max_paths", "4")
-	params.Add("alternative_route.max_weight_factor", "1.4")
-	params.Add("alternative_route.max_share_factor", "0.6")
+	if len(points) == 2 {
+		params.Add("algorithm", "alternative_route")
+		params.Add("alternative_route.max_paths", "4")
+		params.Add("alternative_route.max_weight_factor", "1.4")
+		params.Add("alternative_route.max_share_factor", "0.6")
+	}
 	params.Add("elevation", "true")
 
 	url := baseUrl + params.Encode()
@@ -129,7 +162,11 @@ func (rs *RouteService) FindGraphhopperRoute(source [2]float64, destination [2]f
 		"mode", mode,
 	)
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return graphhopperroutes.RouteData{}, errors.NewInternalError("error creating GraphHopper request", err)
+	}
+	resp, err := outbound.For(models.ProviderGraphHopper).Do(req)
 	if err != nil {
 		logger.Error("Failed to call GraphHopper API",
 			"error", err.Error(),
@@ -182,28 +219,37 @@ func (rs *RouteService) FindGraphhopperRoute(source [2]float64, destination [2]f
 
 // FindSingleRoute finds a single route based on preferences
 func (rs *RouteService) FindSingleRoute(req models.RouteRequest) (interface{}, error) {
+	return rs.findSingleRoute(req, newEnergyWeather(req.Source))
+}
+
+// findSingleRoute finds a single route with the weather lookup of the request
+func (rs *RouteService) findSingleRoute(req models.RouteRequest, weather energyWeather) (interface{}, error) {
 	source := req.Source
 	destination := req.Destination
 	delayCode := req.DelayCode
 	mode := req.Mode
 	routePref := req.RoutePreference
-	vehicleMass := req.VehicleMass
 	condition := req.Condition
-	engineType := req.EngineType
+
+	energy, err := rs.newRouteEnergy(req, weather)
+	if err != nil {
+		return nil, err
+	}
 
 	logger.Debug("Finding single route",
 		"mode", mode,
 		"route_preference", routePref,
 		"delay_code", delayCode,
-		"vehicle_mass", vehicleMass,
+		"vehicle_profile", energy.vehicle.ID,
+		"vehicle_mass", energy.vehicle.MassKg,
 		"condition", condition,
-		"engine_type", engineType,
+		"fuel_type", energy.vehicle.FuelType,
 		"source", source,
 		"destination", destination,
 	)
 
-	if mode == "driving-traffic" && (routePref == "fastest" || routePref == "balanced") {
-		// Use Mapbox for car routes with fastest/balanced preference
+	if mode == "driving-traffic" && (routePref == "fastest" || routePref == "balanced" || routePref == "cheapest") {
+		// Use Mapbox for car routes with fastest/balanced/cheapest preference
 		logger.Debug("Using Mapbox API for car route",
 			"route_preference", routePref,
 		)
@@ -229,52 +275,18 @@ func (rs *RouteService) FindSingleRoute(req models.RouteRequest) (interface{}, e
 			return nil, errors.NewNotFoundError("No routes found for the given coordinates", nil)
 		}
 
-		// Get energy data from GraphHopper
-		energyMode := "car"
-		logger.Debug("Getting energy data from GraphHopper",
-			"energy_mode", energyMode,
-		)
-
-		energyRoute, err := rs.FindGraphhopperRoute(source, destination, energyMode)
-		if err != nil {
-			logger.Error("Failed to find GraphHopper energy route",
-				"error", err.Error(),
-				"source", source,
-				"destination", destination,
-				"energy_mode", energyMode,
-			)
-			return nil, errors.Wrap(err, "failed to find GraphHopper energy route")
-		}
-
-		// Check if energy routes are available
-		if len(energyRoute.Paths) == 0 {
-			logger.Error("No GraphHopper energy routes found",
-				"source", source,
-				"destination", destination,
-				"mode", energyMode,
-			)
-			return nil, errors.NewNotFoundError("No energy data available for the route", nil)
-		}
-
 		logger.Debug("Calculating route exposure and energy",
 			"mapbox_routes_count", len(routes.Routes),
-			"energy_routes_count", len(energyRoute.Paths),
 		)
 
-		// Align routes by distance
-		sort.SliceStable(energyRoute.Paths, func(i, j int) bool {
-			return energyRoute.Paths[i].Distance < energyRoute.Paths[j].Distance
-		})
-
-		sort.SliceStable(routes.Routes, func(i, j int) bool {
-			return routes.Routes[i].Distance < routes.Routes[j].Distance
-		})
-
 		// Calculate exposure and energy
-		for i := 0; i < len(routes.Routes) && i < len(energyRoute.Paths); i++ {
-			routes.Routes[i] = utils.CalculateRouteExposureMapbox(routes.Routes[i], delayCode)
+		routes.Routes, err = utils.CalculateRoutesExposureMapbox(routes.Routes, delayCode)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to calculate route exposure")
+		}
+		for i := 0; i < len(routes.Routes); i++ {
 			// Keep Mapbox duration in seconds (no conversion needed)
-			routes.Routes[i].TotalEnergy = utils.CalculateRouteEnergy(energyRoute.Paths[i], mode, vehicleMass, condition, engineType)
+			energy.applyToMapboxRoute(&routes.Routes[i])
 		}
 
 		// Return based on preference
@@ -293,6 +305,13 @@ func (rs *RouteService) FindSingleRoute(req models.RouteRequest) (interface{}, e
 		} else if routePref == "balanced" {
 			logger.Debug("Selecting balanced route")
 			return rs.selectBalancedRoute(routes.Routes), nil
+		} else if routePref == "cheapest" {
+			cheapest := rs.findBestMapboxRoute(routes.Routes, "cost")
+			logger.Debug("Selected cheapest route",
+				"cost", cheapest.Cost.Total,
+				"currency", cheapest.Cost.Currency,
+			)
+			return cheapest, nil
 		}
 	} else {
 		// Use GraphHopper for other modes
@@ -331,9 +350,12 @@ func (rs *RouteService) FindSingleRoute(req models.RouteRequest) (interface{}, e
 		)
 
 		// Calculate exposure and energy
+		routes.Paths, err = utils.CalculateRoutesExposureGraphhopper(routes.Paths, delayCode)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to calculate route exposure")
+		}
 		for i := 0; i < len(routes.Paths); i++ {
-			routes.Paths[i] = utils.CalculateRouteExposureGraphhopper(routes.Paths[i], delayCode)
-			routes.Paths[i].TotalEnergy = utils.CalculateRouteEnergy(routes.Paths[i], mode, vehicleMass, condition, engineType)
+			energy.applyToPath(&routes.Paths[i])
 			// Convert GraphHopper time from milliseconds to seconds
 			routes.Paths[i].Time = routes.Paths[i].Time / 1000
 		}
@@ -357,24 +379,33 @@ func (rs *RouteService) FindSingleRoute(req models.RouteRequest) (interface{}, e
 			)
 			return routes.Paths[0], nil
 		case "leap":
-			sort.SliceStable(routes.Paths, func(i, j int) bool {
-				return routes.Paths[i].TotalExposure < routes.Paths[j].TotalExposure
-			})
+			leap, tied := rs.selectLeapGraphhopperRoute(routes.Paths)
 			logger.Debug("Selected lowest exposure route",
-				"exposure", routes.Paths[0].TotalExposure,
+				"exposure", leap.TotalExposure,
+				"exposure_lower", leap.ExposureInterval.Lower,
+				"exposure_upper", leap.ExposureInterval.Upper,
+				"tied", tied,
 			)
-			return routes.Paths[0], nil
+			return leap, nil
 		case "emission":
 			sort.SliceStable(routes.Paths, func(i, j int) bool {
-				return routes.Paths[i].TotalEnergy < routes.Paths[j].TotalEnergy
+				return routes.Paths[i].Emissions.CO2Grams < routes.Paths[j].Emissions.CO2Grams
 			})
 			logger.Debug("Selected lowest emission route",
+				"co2_g", routes.Paths[0].Emissions.CO2Grams,
 				"energy", routes.Paths[0].TotalEnergy,
 			)
 			return routes.Paths[0], nil
 		case "balanced":
 			logger.Debug("Selecting balanced route")
 			return rs.selectBalancedGraphhopperRoute(routes.Paths), nil
+		case "cheapest":
+			cheapest := rs.findBestRoute(routes.Paths, "cost")
+			logger.Debug("Selected cheapest route",
+				"cost", cheapest.Cost.Total,
+				"currency", cheapest.Cost.Currency,
+			)
+			return cheapest, nil
 		}
 	}
 
@@ -387,17 +418,24 @@ func (rs *RouteService) FindSingleRoute(req models.RouteRequest) (interface{}, e
 
 // FindAllRoutes finds all route types for a given request
 func (rs *RouteService) FindAllRoutes(req models.RouteRequest) (interface{}, error) {
-	if req.Mode == "scooter" {
-		return rs.findAllScooterRoutes(req)
-	} else if req.Mode == "driving-traffic" {
+	switch req.Mode {
+	case "driving-traffic":
 		return rs.findAllCarRoutes(req)
+	case "scooter", "foot", "bike", "mtb", "e-bike":
+		return rs.findAllGraphhopperRoutes(req)
 	}
 
 	return nil, fmt.Errorf("unsupported mode: %s", req.Mode)
 }
 
-// findAllScooterRoutes finds all routes for scooter mode
-func (rs *RouteService) findAllScooterRoutes(req models.RouteRequest) (*graphhopperroutes.RouteList, error) {
+// findAllGraphhopperRoutes finds all routes for the scooter, walking and
+// cycling modes
+func (rs *RouteService) findAllGraphhopperRoutes(req models.RouteRequest) (*graphhopperroutes.RouteList, error) {
+	energy, err := rs.newRouteEnergy(req, newEnergyWeather(req.Source))
+	if err != nil {
+		return nil, err
+	}
+
 	routes, err := rs.FindGraphhopperRoute(req.Source, req.Destination, req.Mode)
 	if err != nil {
 		return nil, err
@@ -405,7 +443,7 @@ func (rs *RouteService) findAllScooterRoutes(req models.RouteRequest) (*graphhop
 
 	// Check if routes are available
 	if len(routes.Paths) == 0 {
-		logger.Error("No GraphHopper routes found for scooter",
+		logger.Error("No GraphHopper routes found",
 			"source", req.Source,
 			"destination", req.Destination,
 			"mode", req.Mode,
@@ -414,9 +452,12 @@ func (rs *RouteService) findAllScooterRoutes(req models.RouteRequest) (*graphhop
 	}
 
 	// Calculate exposure and energy
+	routes.Paths, err = utils.CalculateRoutesExposureGraphhopper(routes.Paths, req.DelayCode)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to calculate route exposure")
+	}
 	for i := 0; i < len(routes.Paths); i++ {
-		routes.Paths[i] = utils.CalculateRouteExposureGraphhopper(routes.Paths[i], req.DelayCode)
-		routes.Paths[i].TotalEnergy = utils.CalculateRouteEnergy(routes.Paths[i], req.Mode, req.VehicleMass, req.Condition, req.EngineType)
+		energy.applyToPath(&routes.Paths[i])
 		// Convert GraphHopper time from milliseconds to seconds
 		routes.Paths[i].Time = routes.Paths[i].Time / 1000
 	}
@@ -432,12 +473,14 @@ func (rs *RouteService) findAllScooterRoutes(req models.RouteRequest) (*graphhop
 	// Find best routes for each preference
 	routeList.Fastest = rs.findBestRoute(routes.Paths, "time")
 	routeList.Shortest = rs.findBestRoute(routes.Paths, "distance")
-	routeList.LeapG = rs.findBestRoute(routes.Paths, "exposure")
-	routeList.Lco2G = rs.findBestRoute(routes.Paths, "energy")
+	routeList.LeapG, routeList.LeapTied = rs.selectLeapGraphhopperRoute(routes.Paths)
+	routeList.Lco2G = rs.findBestRoute(routes.Paths, "co2")
+	routeList.Cheapest = rs.findBestRoute(routes.Paths, "cost")
 	routeList.Balanced = rs.selectBalancedGraphhopperRoute(routes.Paths)
 
 	// Debug logging for route selection
-	logger.Debug("Route selection results for scooter routes",
+	logger.Debug("Route selection results for GraphHopper routes",
+		"mode", req.Mode,
 		"shortest_distance", routeList.Shortest.Distance,
 		"shortest_exposure", routeList.Shortest.TotalExposure,
 		"leap_distance", routeList.LeapG.Distance,
@@ -458,6 +501,11 @@ func (rs *RouteService) findAllCarRoutes(req models.RouteRequest) (*mapboxroutes
 		"delay_code", req.DelayCode,
 	)
 
+	energy, err := rs.newRouteEnergy(req, newEnergyWeather(req.Source))
+	if err != nil {
+		return nil, err
+	}
+
 	mapboxRoute, err := rs.FindMapboxRoute(req.Source, req.Destination, req.DelayCode)
 	if err != nil {
 		logger.Error("Failed to find Mapbox routes",
@@ -482,40 +530,13 @@ func (rs *RouteService) findAllCarRoutes(req models.RouteRequest) (*mapboxroutes
 		"routes_count", len(mapboxRoute.Routes),
 	)
 
-	graphhopperRoute, err := rs.FindGraphhopperRoute(req.Source, req.Destination, "car")
+	// Calculate exposure and energy
+	mapboxRoute.Routes, err = utils.CalculateRoutesExposureMapbox(mapboxRoute.Routes, req.DelayCode)
 	if err != nil {
-		logger.Error("Failed to find GraphHopper routes",
-			"error", err.Error(),
-			"source", req.Source,
-			"destination", req.Destination,
-		)
-		return nil, err
+		return nil, errors.Wrap(err, "failed to calculate route exposure")
 	}
-
-	// Check if GraphHopper routes are available
-	if len(graphhopperRoute.Paths) == 0 {
-		logger.Error("No GraphHopper routes found for car energy calculation",
-			"source", req.Source,
-			"destination", req.Destination,
-			"mode", "car",
-		)
-		return nil, errors.NewNotFoundError("No energy data available for the route", nil)
-	}
-
-	logger.Debug("Successfully retrieved GraphHopper routes",
-		"paths_count", len(graphhopperRoute.Paths),
-	)
-
-	// Calculate exposure and energy
-	for i := 0; i < len(mapboxRoute.Routes) && i < len(graphhopperRoute.Paths); i++ {
-		mapboxRoute.Routes[i] = utils.CalculateRouteExposureMapbox(mapboxRoute.Routes[i], req.DelayCode)
-		// Calculate energy for Mapbox route using corresponding GraphHopper path
-		mapboxRoute.Routes[i].TotalEnergy = utils.CalculateRouteEnergy(graphhopperRoute.Paths[i], req.Mode, req.VehicleMass, req.Condition, req.EngineType)
-		// Convert GraphHopper time from milliseconds to seconds
-		graphhopperRoute.Paths[i].Time = graphhopperRoute.Paths[i].Time / 1000
-		// Calculate exposure and energy for GraphHopper route
-		graphhopperRoute.Paths[i] = utils.CalculateRouteExposureGraphhopper(graphhopperRoute.Paths[i], req.DelayCode)
-		graphhopperRoute.Paths[i].TotalEnergy = utils.CalculateRouteEnergy(graphhopperRoute.Paths[i], req.Mode, req.VehicleMass, req.Condition, req.EngineType)
+	for i := 0; i < len(mapboxRoute.Routes); i++ {
+		energy.applyToMapboxRoute(&mapboxRoute.Routes[i])
 
 		// Debug logging for each route
 		logger.Debug("Route calculation results",
@@ -523,9 +544,7 @@ func (rs *RouteService) findAllCarRoutes(req models.RouteRequest) (*mapboxroutes
 			"mapbox_distance", mapboxRoute.Routes[i].Distance,
 			"mapbox_exposure", mapboxRoute.Routes[i].TotalExposure,
 			"mapbox_energy", mapboxRoute.Routes[i].TotalEnergy,
-			"graphhopper_distance", graphhopperRoute.Paths[i].Distance,
-			"graphhopper_exposure", graphhopperRoute.Paths[i].TotalExposure,
-			"graphhopper_energy", graphhopperRoute.Paths[i].TotalEnergy,
+			"mapbox_co2_g", mapboxRoute.Routes[i].Emissions.CO2Grams,
 		)
 	}
 
@@ -540,12 +559,10 @@ func (rs *RouteService) findAllCarRoutes(req models.RouteRequest) (*mapboxroutes
 	// Find best routes for each preference
 	routeList.Fastest = rs.findBestMapboxRoute(mapboxRoute.Routes, "duration")
 	routeList.Shortest = rs.findBestMapboxRoute(mapboxRoute.Routes, "distance")
-	routeList.Leap = rs.findBestMapboxRoute(mapboxRoute.Routes, "exposure")
-	routeList.Lco2 = rs.findBestMapboxRoute(mapboxRoute.Routes, "energy")
+	routeList.Leap, routeList.LeapTied = rs.selectLeapMapboxRoute(mapboxRoute.Routes)
+	routeList.Lco2 = rs.findBestMapboxRoute(mapboxRoute.Routes, "co2")
+	routeList.Cheapest = rs.findBestMapboxRoute(mapboxRoute.Routes, "cost")
 	routeList.Balanced = rs.selectBalancedMapboxRoute(mapboxRoute.Routes)
-	// Keep GraphHopper routes for energy calculation only
-	routeList.LeapG = rs.findBestGraphhopperRoute(graphhopperRoute.Paths, "exposure")
-	routeList.Lco2G = rs.findBestGraphhopperRoute(graphhopperRoute.Paths, "energy")
 
 	// Validate that we have non-zero values for exposure and energy
 	// If all routes have zero exposure, use the shortest route for LEAP
@@ -598,6 +615,17 @@ func (rs *RouteService) findBestRoute(routes []graphhopperroutes.Path, criteria
 			if routes[i].TotalEnergy < routes[index].TotalEnergy {
 				index = i
 			}
+		case "co2":
+			if routes[i].Emissions.CO2Grams < routes[index].Emissions.CO2Grams ||
+				(routes[i].Emissions.CO2Grams == routes[index].Emissions.CO2Grams && routes[i].TotalEnergy < routes[index].TotalEnergy) {
+				index = i
+			}
+		case "cost":
+			// Equal costs go to the faster route
+			if routes[i].Cost.Total < routes[index].Cost.Total ||
+				(routes[i].Cost.Total == routes[index].Cost.Total && routes[i].Time < routes[index].Time) {
+				index = i
+			}
 		}
 	}
 	return routes[index]
@@ -646,6 +674,33 @@ func (rs *RouteService) findBestMapboxRoute(routes []mapboxroutes.Route, criteri
 			} else if routes[i].TotalEnergy < routes[index].TotalEnergy && routes[index].TotalEnergy > 0 {
 				index = i
 			}
+		case "co2":
+			logger.Debug("Comparing CO2 values",
+				"current_index", index,
+				"current_co2_g", routes[index].Emissions.CO2Grams,
+				"comparing_index", i,
+				"comparing_co2_g", routes[i].Emissions.CO2Grams,
+			)
+			// Routes without energy data are only selected if no route has it.
+			// Electric vehicles on a zero-carbon grid tie on CO2, so fall back to energy.
+			if routes[i].TotalEnergy > 0 && routes[index].TotalEnergy == 0 {
+				index = i
+			} else if routes[i].TotalEnergy > 0 && (routes[i].Emissions.CO2Grams < routes[index].Emissions.CO2Grams ||
+				(routes[i].Emissions.CO2Grams == routes[index].Emissions.CO2Grams && routes[i].TotalEnergy < routes[index].TotalEnergy)) {
+				index = i
+			}
+		case "cost":
+			logger.Debug("Comparing cost values",
+				"current_index", index,
+				"current_cost", routes[index].Cost.Total,
+				"comparing_index", i,
+				"comparing_cost", routes[i].Cost.Total,
+			)
+			// Equal costs go to the faster route
+			if routes[i].Cost.Total < routes[index].Cost.Total ||
+				(routes[i].Cost.Total == routes[index].Cost.Total && routes[i].Duration < routes[index].Duration) {
+				index = i
+			}
 		}
 	}
 
@@ -656,31 +711,66 @@ func (rs *RouteService) findBestMapboxRoute(routes []mapboxroutes.Route, criteri
 		"selected_distance", routes[index].Distance,
 		"selected_exposure", routes[index].TotalExposure,
 		"selected_energy", routes[index].TotalEnergy,
+		"selected_co2_g", routes[index].Emissions.CO2Grams,
 		"total_routes", len(routes),
 	)
 
 	return routes[index]
 }
 
-func (rs *RouteService) findBestGraphhopperRoute(routes []graphhopperroutes.Path, criteria string) graphhopperroutes.Path {
-	if len(routes) == 0 {
-		return graphhopperroutes.Path{}
+// selectLeapGraphhopperRoute selects the least-exposure route. Routes whose
+// exposure intervals overlap the best one cannot be told apart, so when
+// LEAP_OVERLAP_AS_TIE is enabled the fastest of them is chosen and the
+// selection is reported as a tie.
+func (rs *RouteService) selectLeapGraphhopperRoute(routes []graphhopperroutes.Path) (graphhopperroutes.Path, bool) {
+	best := rs.findBestRoute(routes, "exposure")
+	if !config.AppConfig.LeapOverlapAsTie || best.ExposureInterval.Upper == 0 {
+		return best, false
+	}
+
+	tied := 0
+	selected := best
+	for _, route := range routes {
+		if !route.ExposureInterval.Overlaps(best.ExposureInterval) {
+			continue
+		}
+		tied++
+		if route.Time < selected.Time {
+			selected = route
+		}
 	}
 
-	index := 0
-	for i := 1; i < len(routes); i++ {
-		switch criteria {
-		case "exposure":
-			if routes[i].TotalExposure < routes[index].TotalExposure {
-				index = i
-			}
-		case "energy":
-			if routes[i].TotalEnergy < routes[index].TotalEnergy {
-				index = i
-			}
+	return selected, tied > 1
+}
+
+// selectLeapMapboxRoute is the Mapbox counterpart of selectLeapGraphhopperRoute
+func (rs *RouteService) selectLeapMapboxRoute(routes []mapboxroutes.Route) (mapboxroutes.Route, bool) {
+	best := rs.findBestMapboxRoute(routes, "exposure")
+	if !config.AppConfig.LeapOverlapAsTie || best.ExposureInterval.Upper == 0 {
+		return best, false
+	}
+
+	tied := 0
+	selected := best
+	for _, route := range routes {
+		if !route.ExposureInterval.Overlaps(best.ExposureInterval) {
+			continue
+		}
+		tied++
+		if route.Duration < selected.Duration {
+			selected = route
 		}
 	}
-	return routes[index]
+
+	if tied > 1 {
+		logger.Debug("LEAP candidates have overlapping exposure intervals",
+			"tied_routes", tied,
+			"best_exposure", best.TotalExposure,
+			"selected_exposure", selected.TotalExposure,
+		)
+	}
+
+	return selected, tied > 1
 }
 
 // selectBalancedRoute selects the best balanced route
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/errors"
+	"github.com/clean-route/go-backend/internal/models"
+	mapboxroutes "github.com/clean-route/go-backend/internal/models/mapbox"
+	"github.com/clean-route/go-backend/internal/vehicles"
+)
+
+func TestSelectLeapMapboxRoute(t *testing.T) {
+	routes := []mapboxroutes.Route{
+		{Duration: 900, TotalExposure: 120, ExposureInterval: models.Interval{Lower: 80, Upper: 160}},
+		{Duration: 600, TotalExposure: 150, ExposureInterval: models.Interval{Lower: 100, Upper: 200}},
+		{Duration: 500, TotalExposure: 300, ExposureInterval: models.Interval{Lower: 250, Upper: 350}},
+	}
+
+	tests := []struct {
+		name         string
+		overlapAsTie bool
+		wantDuration float64
+		wantTied     bool
+	}{
+		{"least exposure by default", false, 900, false},
+		{"fastest overlapping route when ties are enabled", true, 600, true},
+	}
+
+	rs := NewRouteService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.AppConfig = &config.Config{LeapOverlapAsTie: tt.overlapAsTie}
+			selected, tied := rs.selectLeapMapboxRoute(routes)
+			if selected.Duration != tt.wantDuration || tied != tt.wantTied {
+				t.Errorf("selected route of %gs (tied %v), want %gs (tied %v)",
+					selected.Duration, tied, tt.wantDuration, tt.wantTied)
+			}
+		})
+	}
+}
+
+func TestFindSingleRouteValidatesVehicleBeforeRouting(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	if err := vehicles.Init(); err != nil {
+		t.Fatalf("vehicles.Init: %v", err)
+	}
+
+	req := models.RouteRequest{Mode: "driving-traffic", VehicleProfile: "hovercraft", RoutePreference: "fastest"}
+	_, err := NewRouteService().FindSingleRoute(req)
+	appErr := errors.GetAppError(err)
+	if appErr == nil || appErr.Type != errors.ErrorTypeValidation {
+		t.Fatalf("error %v, want a validation error", err)
+	}
+}
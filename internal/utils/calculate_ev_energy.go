@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/vehicles"
+)
+
+const (
+	// Cabin temperatures that need no heating or cooling (°C)
+	hvac_comfort_min = 18.0
+	hvac_comfort_max = 24.0
+
+	joules_per_kwh = 3.6e6
+)
+
+// CalculateEVRouteEnergy runs the electric vehicle model over route segments:
+// traction is drawn through the drivetrain, descents and braking are recovered
+// up to the profile's regen efficiency, and auxiliary and HVAC loads are drawn
+// for the whole trip. weather may be nil when it is unavailable, in which case
+// no HVAC load is assumed.
+func CalculateEVRouteEnergy(segments []EnergySegment, vehicle vehicles.Profile, condition string, weather *EnergyWeather, startSoC float64, reserveSoC float64) models.EVEnergy {
+	var tractionEnergy float64    // Joules drawn from the battery to move
+	var regeneratedEnergy float64 // Joules returned to the battery
+	var duration float64          // seconds
+
+	for _, segment := range segments {
+		if segment.Time == 0 {
+			continue
+		}
+		duration += segment.Time
+
+		traction, potential, braking := getSegmentForces(segment, vehicle, weather)
+		speedKmh := segment.Distance / segment.Time * 3.6
+		drivetrainEfficiency := getDrivetrainEfficiency(vehicle, speedKmh, condition)
+
+		// Braking in stop-and-go traffic is partly recovered
+		regeneratedEnergy += braking * vehicle.RegenEfficiency * drivetrainEfficiency
+
+		if net := traction + potential; net >= 0 {
+			// A pedal-assist motor only supplies its share
+			tractionEnergy += net * vehicle.MotorShare() / drivetrainEfficiency
+		} else {
+			// Only part of the surplus energy on a descent makes it back
+			// through the motor into the battery
+			regeneratedEnergy += -net * vehicle.RegenEfficiency * drivetrainEfficiency
+		}
+	}
+
+	var ambientTemp *float64
+	if weather != nil {
+		ambientTemp = &weather.TempC
+	}
+	auxiliaryPower := vehicle.AuxiliaryPowerKW + getHVACPower(vehicle, ambientTemp) // kW
+	auxiliaryEnergy := auxiliaryPower * 1000 * duration
+
+	result := models.EVEnergy{
+		TractionKWh:        tractionEnergy / joules_per_kwh,
+		RegeneratedKWh:     regeneratedEnergy / joules_per_kwh,
+		AuxiliaryKWh:       auxiliaryEnergy / joules_per_kwh,
+		BatteryCapacityKWh: vehicle.BatteryCapacityKWh,
+		StartSoC:           startSoC,
+		ReserveSoC:         reserveSoC,
+		AmbientTempC:       ambientTemp,
+	}
+	result.ConsumedKWh = result.TractionKWh - result.RegeneratedKWh + result.AuxiliaryKWh
+
+	// A battery cannot be charged past full by regeneration, nor drained
+	// below empty
+	arrivalSoC := startSoC - result.ConsumedKWh/vehicle.BatteryCapacityKWh*100
+	result.ArrivalSoC = math.Max(math.Min(arrivalSoC, 100), 0)
+	result.BelowReserve = result.ArrivalSoC < reserveSoC
+
+	return result
+}
+
+// getHVACPower returns the cabin heating or cooling load in kW for the
+// ambient temperature
+func getHVACPower(vehicle vehicles.Profile, ambientTemp *float64) float64 {
+	if ambientTemp == nil {
+		return 0
+	}
+
+	var deviation float64
+	if *ambientTemp < hvac_comfort_min {
+		deviation = hvac_comfort_min - *ambientTemp
+	} else if *ambientTemp > hvac_comfort_max {
+		deviation = *ambientTemp - hvac_comfort_max
+	}
+
+	power := deviation * vehicle.HVACPowerPerDegree
+	if vehicle.HVACMaxPowerKW > 0 {
+		power = math.Min(power, vehicle.HVACMaxPowerKW)
+	}
+	return power
+}
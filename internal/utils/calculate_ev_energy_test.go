@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/clean-route/go-backend/internal/vehicles"
+)
+
+func testEV() vehicles.Profile {
+	return vehicles.Profile{
+		MassKg:             1600,
+		DragCoefficient:    0.3,
+		FrontalAreaM2:      2.2,
+		RollingResistance:  0.01,
+		FuelType:           "ev",
+		BatteryCapacityKWh: 60,
+		RegenEfficiency:    0.6,
+		HVACPowerPerDegree: 0.2,
+		HVACMaxPowerKW:     3,
+	}
+}
+
+func TestCalculateEVRouteEnergy(t *testing.T) {
+	// 1 km at 36 km/h
+	flat := EnergySegment{Distance: 1000, Time: 100}
+	descent := flat
+	descent.HeightGain = -100
+
+	smallBattery := testEV()
+	smallBattery.BatteryCapacityKWh = 1
+	// 10 kW for 100 s is 0.28 kWh, more than a quarter of the battery
+	heavyAuxiliary := smallBattery
+	heavyAuxiliary.AuxiliaryPowerKW = 10
+
+	tests := []struct {
+		name         string
+		segments     []EnergySegment
+		vehicle      vehicles.Profile
+		startSoC     float64
+		regenerates  bool
+		arrivalSoC   func(float64) bool
+		belowReserve bool
+	}{
+		{
+			name:       "flat road draws traction only",
+			segments:   []EnergySegment{flat},
+			vehicle:    testEV(),
+			startSoC:   80,
+			arrivalSoC: func(soc float64) bool { return soc < 80 && soc > 79 },
+		},
+		{
+			name:        "descent regenerates and charges",
+			segments:    []EnergySegment{descent},
+			vehicle:     testEV(),
+			startSoC:    80,
+			regenerates: true,
+			arrivalSoC:  func(soc float64) bool { return soc > 80 && soc < 100 },
+		},
+		{
+			name:        "regeneration stops at a full battery",
+			segments:    []EnergySegment{descent},
+			vehicle:     smallBattery,
+			startSoC:    99,
+			regenerates: true,
+			arrivalSoC:  func(soc float64) bool { return soc == 100 },
+		},
+		{
+			name:         "drained battery stops at empty",
+			segments:     []EnergySegment{flat, flat, flat, flat},
+			vehicle:      heavyAuxiliary,
+			startSoC:     10,
+			arrivalSoC:   func(soc float64) bool { return soc == 0 },
+			belowReserve: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateEVRouteEnergy(tt.segments, tt.vehicle, "new", nil, tt.startSoC, 10)
+			if tt.regenerates && (got.RegeneratedKWh <= 0 || got.TractionKWh != 0) {
+				t.Errorf("traction %g kWh, regenerated %g kWh, want only regeneration", got.TractionKWh, got.RegeneratedKWh)
+			}
+			if !tt.regenerates && (got.RegeneratedKWh != 0 || got.TractionKWh <= 0) {
+				t.Errorf("traction %g kWh, regenerated %g kWh, want only traction", got.TractionKWh, got.RegeneratedKWh)
+			}
+			if !tt.arrivalSoC(got.ArrivalSoC) {
+				t.Errorf("arrival SoC %g%% from %g%%", got.ArrivalSoC, tt.startSoC)
+			}
+			if got.BelowReserve != tt.belowReserve {
+				t.Errorf("below reserve = %v, want %v", got.BelowReserve, tt.belowReserve)
+			}
+			consumed := got.TractionKWh - got.RegeneratedKWh + got.AuxiliaryKWh
+			if math.Abs(got.ConsumedKWh-consumed) > 1e-9 {
+				t.Errorf("consumed %g kWh, want %g kWh", got.ConsumedKWh, consumed)
+			}
+		})
+	}
+}
+
+func TestCalculateEVRouteEnergyAuxiliary(t *testing.T) {
+	vehicle := testEV()
+	vehicle.AuxiliaryPowerKW = 0.5
+	// An hour at 36 km/h
+	segments := []EnergySegment{{Distance: 36000, Time: 3600}}
+	cold := &EnergyWeather{TempC: 8}
+
+	got := CalculateEVRouteEnergy(segments, vehicle, "new", cold, 100, 10)
+	// 0.5 kW base load plus 10 degrees of heating at 0.2 kW each
+	if math.Abs(got.AuxiliaryKWh-2.5) > 1e-9 {
+		t.Errorf("auxiliary %g kWh, want 2.5 kWh", got.AuxiliaryKWh)
+	}
+	if got.AmbientTempC == nil || *got.AmbientTempC != 8 {
+		t.Errorf("ambient temperature %v, want 8", got.AmbientTempC)
+	}
+}
+
+func TestGetHVACPower(t *testing.T) {
+	vehicle := testEV()
+
+	if got := getHVACPower(vehicle, nil); got != 0 {
+		t.Errorf("HVAC power without weather %g kW, want 0", got)
+	}
+
+	tests := []struct {
+		name  string
+		temp  float64
+		power float64
+	}{
+		{"comfort band low edge", 18.0, 0},
+		{"comfort band", 21.0, 0},
+		{"comfort band high edge", 24.0, 0},
+		{"heating", 13.0, 1},
+		{"cooling", 29.0, 1},
+		{"heating capped", -20.0, 3},
+		{"cooling capped", 45.0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getHVACPower(vehicle, &tt.temp); math.Abs(got-tt.power) > 1e-9 {
+				t.Errorf("HVAC power %g kW, want %g kW", got, tt.power)
+			}
+		})
+	}
+
+	uncapped := vehicle
+	uncapped.HVACMaxPowerKW = 0
+	freezing := -20.0
+	if got := getHVACPower(uncapped, &freezing); math.Abs(got-7.6) > 1e-9 {
+		t.Errorf("uncapped HVAC power %g kW, want 7.6 kW", got)
+	}
+}
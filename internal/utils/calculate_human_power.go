@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/vehicles"
+)
+
+const (
+	// Share of metabolic energy the muscles turn into mechanical work
+	muscle_efficiency = 0.24
+	// Net metabolic cost of level walking (J per kg body mass per meter)
+	walking_cost_of_transport = 2.0
+	// Share of a descent's potential energy that replaces pedalling or
+	// walking effort; the rest is lost to braking and drag
+	descent_recovery = 0.5
+	joules_per_kcal  = 4184.0
+	// Net metabolic power of one MET above rest (W per kg body mass)
+	watts_per_kg_per_met = 1.163
+
+	// Ventilation at rest and per watt of net metabolic power (m³/h), from an
+	// oxygen energy equivalent of 20.1 kJ/L and a ventilatory equivalent of 27
+	resting_ventilation_rate       = 0.5
+	ventilation_per_metabolic_watt = 0.0048
+)
+
+// ventilationRates are the breathing rates (m³/h) of occupants of motorised
+// vehicle classes, who sit or stand at rest
+var ventilationRates = map[string]float64{
+	"car":       0.6,
+	"bus":       0.6,
+	"truck":     0.6,
+	"2-wheeler": 0.75,
+}
+
+// CalculateHumanEffort returns the effort of walking or cycling the route
+// segments. Climbing is taken from the path's total ascend and descend in
+// meters, since instruction end points miss the climbs within an instruction.
+// Level effort follows a fixed cost of transport for walking and rolling and
+// air resistance for cycling; a pedal-assist motor takes its share of the
+// mechanical work. weather may be nil.
+func CalculateHumanEffort(segments []EnergySegment, ascend float64, descend float64, vehicle vehicles.Profile, weather *EnergyWeather) models.HumanEffort {
+	var levelEnergy float64 // metabolic Joules on the level
+	var duration float64    // seconds
+
+	for _, segment := range segments {
+		if segment.Time == 0 {
+			continue
+		}
+		duration += segment.Time
+
+		if vehicle.Class == "pedestrian" {
+			levelEnergy += walking_cost_of_transport * vehicle.BodyMassKg * segment.Distance
+			continue
+		}
+		segment.HeightGain = 0
+		traction, _, _ := getSegmentForces(segment, vehicle, weather)
+		levelEnergy += traction / muscle_efficiency
+	}
+
+	climbEnergy := vehicle.MassKg * acceleration_of_gravity * ascend / muscle_efficiency
+	// Descents can at most halve the level effort
+	descentSaving := math.Min(vehicle.MassKg*acceleration_of_gravity*descend*descent_recovery/muscle_efficiency, levelEnergy/2)
+
+	metabolicEnergy := (levelEnergy + climbEnergy - descentSaving) * (1 - vehicle.AssistRatio)
+
+	effort := models.HumanEffort{
+		KCal:        metabolicEnergy / joules_per_kcal,
+		Ascend:      ascend,
+		Descend:     descend,
+		AssistRatio: vehicle.AssistRatio,
+		MET:         1,
+	}
+	if duration > 0 {
+		effort.AveragePowerW = metabolicEnergy / duration
+	}
+	if vehicle.BodyMassKg > 0 {
+		effort.MET += effort.AveragePowerW / vehicle.BodyMassKg / watts_per_kg_per_met
+	}
+	effort.Grade = getEffortGrade(effort.MET)
+	return effort
+}
+
+// getEffortGrade classifies an intensity in METs using the usual light (<3),
+// moderate (3-6) and vigorous (6+) activity bands, splitting vigorous at 9
+func getEffortGrade(met float64) string {
+	switch {
+	case met < 3:
+		return models.EffortGradeEasy
+	case met < 6:
+		return models.EffortGradeModerate
+	case met < 9:
+		return models.EffortGradeHard
+	default:
+		return models.EffortGradeVeryHard
+	}
+}
+
+// GetVentilationRate returns the breathing rate in m³/h of someone travelling
+// with the vehicle. Walkers and riders breathe harder the more effort the
+// route takes; effort is nil for motorised vehicles.
+func GetVentilationRate(vehicle vehicles.Profile, effort *models.HumanEffort) float64 {
+	if effort != nil {
+		return resting_ventilation_rate + effort.AveragePowerW*ventilation_per_metabolic_watt
+	}
+	if rate, ok := ventilationRates[vehicle.Class]; ok {
+		return rate
+	}
+	return ventilationRates["car"]
+}
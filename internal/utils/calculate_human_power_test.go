@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/vehicles"
+)
+
+func testPedestrian() vehicles.Profile {
+	return vehicles.Profile{Class: "pedestrian", MassKg: 70, BodyMassKg: 70, DragCoefficient: 1.0, FrontalAreaM2: 0.6, FuelType: "human"}
+}
+
+func testBike() vehicles.Profile {
+	return vehicles.Profile{Class: "bicycle", MassKg: 85, BodyMassKg: 70, DragCoefficient: 0.9, FrontalAreaM2: 0.5, RollingResistance: 0.006, FuelType: "human"}
+}
+
+func TestCalculateHumanEffort(t *testing.T) {
+	// 1 km at walking and cycling pace
+	walk := []EnergySegment{{Distance: 1000, Time: 720}}
+	ride := []EnergySegment{{Distance: 1000, Time: 200}}
+
+	tests := []struct {
+		name    string
+		vehicle vehicles.Profile
+		route   []EnergySegment
+		ascend  float64
+		descend float64
+		kcal    float64 // 0 when only the grade is checked
+		grade   string
+	}{
+		// 2 J/kg/m for 70 kg over 1 km
+		{"walking on the level", testPedestrian(), walk, 0, 0, 140000 / joules_per_kcal, models.EffortGradeModerate},
+		{"walking uphill", testPedestrian(), walk, 100, 0, (140000 + 70*acceleration_of_gravity*100/muscle_efficiency) / joules_per_kcal, models.EffortGradeHard},
+		// A long descent at most halves the level effort
+		{"walking downhill", testPedestrian(), walk, 0, 1000, 70000 / joules_per_kcal, models.EffortGradeEasy},
+		{"cycling on the level", testBike(), ride, 0, 0, 0, models.EffortGradeModerate},
+		{"cycling uphill", testBike(), ride, 100, 0, 0, models.EffortGradeVeryHard},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateHumanEffort(tt.route, tt.ascend, tt.descend, tt.vehicle, nil)
+			if tt.kcal > 0 && math.Abs(got.KCal-tt.kcal) > 1e-6 {
+				t.Errorf("effort %g kcal, want %g kcal", got.KCal, tt.kcal)
+			}
+			if got.Grade != tt.grade {
+				t.Errorf("grade %q at %.1f MET, want %q", got.Grade, got.MET, tt.grade)
+			}
+			if got.Ascend != tt.ascend || got.Descend != tt.descend {
+				t.Errorf("ascend %g m, descend %g m, want %g m and %g m", got.Ascend, got.Descend, tt.ascend, tt.descend)
+			}
+		})
+	}
+}
+
+func TestCalculateHumanEffortAssist(t *testing.T) {
+	route := []EnergySegment{{Distance: 1000, Time: 200}}
+	bike := testBike()
+	eBike := testBike()
+	eBike.AssistRatio = 0.5
+
+	pedalled := CalculateHumanEffort(route, 50, 0, bike, nil)
+	assisted := CalculateHumanEffort(route, 50, 0, eBike, nil)
+	if math.Abs(assisted.KCal-pedalled.KCal/2) > 1e-6 {
+		t.Errorf("assisted effort %g kcal, want half of %g kcal", assisted.KCal, pedalled.KCal)
+	}
+	if assisted.AssistRatio != 0.5 || assisted.MET >= pedalled.MET {
+		t.Errorf("assisted %+v, pedalled %+v, want a lower intensity with assist", assisted, pedalled)
+	}
+}
+
+func TestGetEffortGrade(t *testing.T) {
+	tests := []struct {
+		met   float64
+		grade string
+	}{
+		{1, models.EffortGradeEasy},
+		{2.9, models.EffortGradeEasy},
+		{3, models.EffortGradeModerate},
+		{5.9, models.EffortGradeModerate},
+		{6, models.EffortGradeHard},
+		{8.9, models.EffortGradeHard},
+		{9, models.EffortGradeVeryHard},
+		{15, models.EffortGradeVeryHard},
+	}
+
+	for _, tt := range tests {
+		if got := getEffortGrade(tt.met); got != tt.grade {
+			t.Errorf("getEffortGrade(%g) = %q, want %q", tt.met, got, tt.grade)
+		}
+	}
+}
+
+func TestGetVentilationRate(t *testing.T) {
+	tests := []struct {
+		name   string
+		class  string
+		effort *models.HumanEffort
+		rate   float64
+	}{
+		{"car", "car", nil, 0.6},
+		{"bus", "bus", nil, 0.6},
+		{"two-wheeler", "2-wheeler", nil, 0.75},
+		{"unknown class", "tractor", nil, 0.6},
+		{"walker at rest", "pedestrian", &models.HumanEffort{}, 0.5},
+		{"rider at 250 W", "bicycle", &models.HumanEffort{AveragePowerW: 250}, 1.7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetVentilationRate(vehicles.Profile{Class: tt.class}, tt.effort)
+			if math.Abs(got-tt.rate) > 1e-9 {
+				t.Errorf("ventilation %g m³/h, want %g m³/h", got, tt.rate)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+const (
+	// Megajoules per kilowatt-hour
+	mj_per_kwh = 3.6
+	// Default grid carbon intensity (g CO2 per kWh delivered), roughly the
+	// Indian grid average
+	default_grid_carbon_intensity = 710.0
+)
+
+// CalculateRouteEmissions converts the fuel (or battery) energy of a route in
+// kJ into grams of CO2, NOx and PM for the given engine type and condition
+func CalculateRouteEmissions(energyKJ float64, engineType string, condition string) models.Emissions {
+	energyMJ := energyKJ / 1000
+
+	nox, pm := getPollutantFactors(engineType)
+	conditionFactor := getConditionFactor(condition)
+
+	return models.Emissions{
+		CO2Grams: energyMJ * getCarbonIntensity(engineType) * 1000,
+		NOxGrams: energyMJ * nox * conditionFactor,
+		PMGrams:  energyMJ * pm * conditionFactor,
+	}
+}
+
+// getCarbonIntensity returns kg CO2 per MJ of energy for the engine type. For
+// electric vehicles this is the configured grid intensity.
+func getCarbonIntensity(engineType string) float64 {
+	if engineType == "ev" {
+		// EMISSION_FACTOR_EV keeps precedence for deployments that already set it
+		if factor := getEnvFloat("EMISSION_FACTOR_EV", 0); factor > 0 {
+			return factor
+		}
+		return getEnvFloat("GRID_CARBON_INTENSITY", default_grid_carbon_intensity) / 1000 / mj_per_kwh
+	}
+
+	// Get engine type factors from environment variables with fallback defaults
+	engineFactors := map[string]float64{
+		"petrol": getEnvFloat("EMISSION_FACTOR_PETROL", 0.069),
+		"diesel": getEnvFloat("EMISSION_FACTOR_DIESEL", 0.074),
+		"cng":    getEnvFloat("EMISSION_FACTOR_CNG", 0.056),
+	}
+
+	engineFactor, ok := engineFactors[engineType]
+	if !ok {
+		engineFactor = engineFactors["petrol"] // Default to petrol if unknown
+	}
+	return engineFactor
+}
+
+// getPollutantFactors returns tailpipe NOx and PM in grams per MJ of fuel for
+// a vehicle in new condition. Electric vehicles have no tailpipe emissions.
+func getPollutantFactors(engineType string) (float64, float64) {
+	noxFactors := map[string]float64{
+		"petrol": getEnvFloat("NOX_FACTOR_PETROL", 0.03),
+		"diesel": getEnvFloat("NOX_FACTOR_DIESEL", 0.15),
+		"cng":    getEnvFloat("NOX_FACTOR_CNG", 0.02),
+		"ev":     0,
+	}
+	pmFactors := map[string]float64{
+		"petrol": getEnvFloat("PM_FACTOR_PETROL", 0.002),
+		"diesel": getEnvFloat("PM_FACTOR_DIESEL", 0.003),
+		"cng":    getEnvFloat("PM_FACTOR_CNG", 0.001),
+		"ev":     0,
+	}
+
+	if _, ok := noxFactors[engineType]; !ok {
+		engineType = "petrol" // Default to petrol if unknown
+	}
+	return noxFactors[engineType], pmFactors[engineType]
+}
+
+// getConditionFactor returns how much more NOx and PM a vehicle in the given
+// condition emits than a new one. CO2 is not scaled: worse condition already
+// lowers engine efficiency and so raises the energy burned.
+func getConditionFactor(condition string) float64 {
+	// Get condition factors from environment variables with fallback defaults
+	conditionFactors := map[string]float64{
+		"new":     getEnvFloat("CONDITION_FACTOR_NEW", 1.0),
+		"good":    getEnvFloat("CONDITION_FACTOR_GOOD", 1.1),
+		"average": getEnvFloat("CONDITION_FACTOR_AVERAGE", 1.25),
+		"okay":    getEnvFloat("CONDITION_FACTOR_OKAY", 1.5),
+	}
+
+	conditionFactor := conditionFactors[condition]
+	if conditionFactor == 0 {
+		conditionFactor = conditionFactors["average"] // Default to average if unknown
+	}
+	return conditionFactor
+}
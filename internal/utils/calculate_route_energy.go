@@ -1,68 +1,113 @@
 package utils
 
 import (
+	"math"
 	"os"
 	"strconv"
 
 	graphhopper "github.com/clean-route/go-backend/internal/models/graphhopper"
+	"github.com/clean-route/go-backend/internal/vehicles"
 )
 
 const (
 	acceleration_of_gravity = 9.8
-	// Rolling resistance coefficient (typical for cars)
-	rolling_resistance_coefficient = 0.01
 	// Air density at sea level (kg/m³)
 	air_density = 1.225
-	// Drag coefficient (typical for cars)
-	drag_coefficient = 0.3
-	// Frontal area (m²) - typical for cars
-	frontal_area = 2.0
+	// Speed reached between stops in stop-and-go traffic (m/s)
+	stop_go_peak_speed = 25 / 3.6
 )
 
-func CalculateRouteEnergy(route graphhopper.Path, mode string, vehicleMass int, condition string, engineType string) float64 {
-	// Use provided vehicle mass if available, otherwise fall back to default
-	mass := uint32(vehicleMass)
-	if mass == 0 {
-		mass = GetMassFromMode(mode)
+// EnergySegment is a stretch of route driven at a constant average speed.
+// Start and End are [longitude, latitude].
+type EnergySegment struct {
+	Distance   float64 // meters
+	Time       float64 // seconds
+	HeightGain float64 // meters
+	Elevation  float64 // mean elevation above sea level, meters
+	Start      [2]float64
+	End        [2]float64
+
+	// AccelerationCycles is how many times the vehicle accelerates to the
+	// segment speed, StopGoCycles how many stop-and-go cycles congestion adds
+	AccelerationCycles float64
+	StopGoCycles       float64
+}
+
+// GraphhopperSegments splits a GraphHopper path into its instructions, using
+// the elevations GraphHopper returns with the geometry
+func GraphhopperSegments(route graphhopper.Path) []EnergySegment {
+	var segments []EnergySegment
+	for _, instruction := range route.Instructions {
+		start := route.Points.Coordinates[instruction.Interval[0]]
+		end := route.Points.Coordinates[instruction.Interval[1]]
+
+		segments = append(segments, EnergySegment{
+			Distance:   instruction.Distance,
+			Time:       float64(instruction.Time) / float64(1000),
+			HeightGain: end[2] - start[2],
+			Elevation:  (start[2] + end[2]) / 2,
+			Start:      [2]float64{start[0], start[1]},
+			End:        [2]float64{end[0], end[1]},
+
+			AccelerationCycles: 1,
+		})
 	}
+	return segments
+}
 
-	segments := route.Instructions
+// getSegmentForces returns the energy in Joules needed to overcome rolling
+// and air resistance and to accelerate (traction), the change in potential
+// energy over the segment, and the kinetic energy lost braking in stop-and-go
+// traffic. weather may be nil, in which case still air at standard density on
+// a dry road is assumed.
+func getSegmentForces(segment EnergySegment, vehicle vehicles.Profile, weather *EnergyWeather) (traction float64, potential float64, braking float64) {
+	mass := vehicle.MassKg
+	averageVelocity := segment.Distance / segment.Time // m/s
+
+	// 1. Potential Energy (climbing/descending)
+	potential = mass * acceleration_of_gravity * segment.HeightGain
+
+	// 2. Rolling Resistance Energy
+	// Wet roads raise rolling resistance
+	rollingResistance := vehicle.RollingResistance * getRollingResistanceFactor(weather)
+	rollingResistanceEnergy := rollingResistance * mass * acceleration_of_gravity * segment.Distance
+
+	// 3. Air Resistance Energy
+	// Drag depends on the speed relative to the air; a strong tailwind pushes
+	airVelocity := averageVelocity + getHeadwind(weather, segment)
+	airResistanceEnergy := 0.5 * getAirDensity(weather, segment.Elevation) * vehicle.DragCoefficient * vehicle.FrontalAreaM2 * airVelocity * math.Abs(airVelocity) * segment.Distance
+
+	// 4. Kinetic Energy (acceleration/deceleration) - simplified
+	// Assume average acceleration/deceleration pattern
+	kineticEnergy := segment.AccelerationCycles * 0.5 * mass * averageVelocity * averageVelocity
+
+	// 5. Stop-and-go cycles in congestion, each braking to a stop
+	stopGoVelocity := math.Max(averageVelocity, stop_go_peak_speed)
+	braking = segment.StopGoCycles * 0.5 * mass * stopGoVelocity * stopGoVelocity
+
+	return rollingResistanceEnergy + airResistanceEnergy + kineticEnergy + braking, potential, braking
+}
 
+// CalculateRouteEnergy returns the fuel (or battery) energy in kJ needed to
+// drive the route segments with the given vehicle profile
+func CalculateRouteEnergy(segments []EnergySegment, vehicle vehicles.Profile, condition string, weather *EnergyWeather) float64 {
 	var totalEnergy float64 // in Joules
 
-	for j := 0; j < len(segments); j++ {
-		startIndex := segments[j].Interval[0]
-		endIndex := segments[j].Interval[1]
-
-		heightGain := route.Points.Coordinates[endIndex][2] - route.Points.Coordinates[startIndex][2]
-
-		distance := segments[j].Distance                  // in meters
-		time := float64(segments[j].Time) / float64(1000) // now its in seconds
-
-		if time == 0 && distance == 0 {
+	for _, segment := range segments {
+		if segment.Time == 0 {
 			continue
 		}
 
-		averageVelocity := distance / time // m/s
-
-		// 1. Potential Energy (climbing/descending)
-		potentialEnergy := float64(mass) * acceleration_of_gravity * heightGain
-
-		// 2. Rolling Resistance Energy
-		rollingResistanceEnergy := rolling_resistance_coefficient * float64(mass) * acceleration_of_gravity * distance
+		traction, potential, _ := getSegmentForces(segment, vehicle, weather)
 
-		// 3. Air Resistance Energy
-		airResistanceEnergy := 0.5 * air_density * drag_coefficient * frontal_area * averageVelocity * averageVelocity * distance
-
-		// 4. Kinetic Energy (acceleration/deceleration) - simplified
-		// Assume average acceleration/deceleration pattern
-		kineticEnergy := 0.5 * float64(mass) * averageVelocity * averageVelocity
-
-		// Total mechanical energy for this segment
-		segmentEnergy := potentialEnergy + rollingResistanceEnergy + airResistanceEnergy + kineticEnergy
+		// Total mechanical energy for this segment. An engine cannot store
+		// the energy of a descent or a tailwind, so they at most spare it the
+		// segment; regeneration is left to the EV model.
+		segmentEnergy := math.Max(traction+potential, 0)
 
 		// Convert to fuel energy (accounting for engine efficiency)
-		engineEfficiency := getEngineEfficiency(engineType, condition)
+		speedKmh := segment.Distance / segment.Time * 3.6
+		engineEfficiency := getDrivetrainEfficiency(vehicle, speedKmh, condition)
 		fuelEnergy := segmentEnergy / engineEfficiency
 
 		totalEnergy += fuelEnergy
@@ -75,6 +120,16 @@ func CalculateRouteEnergy(route graphhopper.Path, mode string, vehicleMass int,
 	return energyKJ
 }
 
+// getDrivetrainEfficiency returns the profile's efficiency at the given speed,
+// falling back to the generic engine efficiency when it has no curve
+func getDrivetrainEfficiency(vehicle vehicles.Profile, speedKmh float64, condition string) float64 {
+	efficiency, ok := vehicle.DrivetrainEfficiency(speedKmh)
+	if !ok {
+		return getEngineEfficiency(vehicle.FuelType, condition)
+	}
+	return efficiency * getEngineConditionFactor(condition)
+}
+
 // getEngineEfficiency returns engine efficiency based on type and condition
 func getEngineEfficiency(engineType string, condition string) float64 {
 	// Base engine efficiencies
@@ -85,56 +140,29 @@ func getEngineEfficiency(engineType string, condition string) float64 {
 		"ev":     0.85, // 85% efficiency for electric vehicles
 	}
 
-	// Condition factors (worse condition = lower efficiency)
-	conditionFactors := map[string]float64{
-		"new":     1.0,  // 100% efficiency
-		"good":    0.95, // 95% efficiency
-		"average": 0.90, // 90% efficiency
-		"okay":    0.80, // 80% efficiency
-	}
-
 	engineEfficiency := engineEfficiencies[engineType]
 	if engineEfficiency == 0 && engineType != "ev" {
 		engineEfficiency = engineEfficiencies["petrol"] // Default to petrol
 	}
 
-	conditionFactor := conditionFactors[condition]
-	if conditionFactor == 0 {
-		conditionFactor = conditionFactors["average"] // Default to average
-	}
-
-	return engineEfficiency * conditionFactor
+	return engineEfficiency * getEngineConditionFactor(condition)
 }
 
-// getEmissionFactor calculates emission factor based on vehicle condition and engine type
-func getEmissionFactor(condition string, engineType string) float64 {
-	// Get engine type factors from environment variables with fallback defaults
-	engineFactors := map[string]float64{
-		"petrol": getEnvFloat("EMISSION_FACTOR_PETROL", 0.069),
-		"diesel": getEnvFloat("EMISSION_FACTOR_DIESEL", 0.074),
-		"cng":    getEnvFloat("EMISSION_FACTOR_CNG", 0.056),
-		"ev":     getEnvFloat("EMISSION_FACTOR_EV", 0.0),
-	}
-
-	// Get condition factors from environment variables with fallback defaults
+// getEngineConditionFactor returns the efficiency loss of an engine in the
+// given condition (worse condition = lower efficiency)
+func getEngineConditionFactor(condition string) float64 {
 	conditionFactors := map[string]float64{
-		"new":     getEnvFloat("CONDITION_FACTOR_NEW", 1.0),
-		"good":    getEnvFloat("CONDITION_FACTOR_GOOD", 1.1),
-		"average": getEnvFloat("CONDITION_FACTOR_AVERAGE", 1.25),
-		"okay":    getEnvFloat("CONDITION_FACTOR_OKAY", 1.5),
-	}
-
-	engineFactor := engineFactors[engineType]
-	if engineFactor == 0 && engineType != "ev" {
-		engineFactor = engineFactors["petrol"] // Default to petrol if unknown
+		"new":     1.0,  // 100% efficiency
+		"good":    0.95, // 95% efficiency
+		"average": 0.90, // 90% efficiency
+		"okay":    0.80, // 80% efficiency
 	}
 
 	conditionFactor := conditionFactors[condition]
 	if conditionFactor == 0 {
-		conditionFactor = conditionFactors["average"] // Default to average if unknown
+		conditionFactor = conditionFactors["average"] // Default to average
 	}
-
-	return engineFactor * conditionFactor
+	return conditionFactor
 }
 
 // getEnvFloat gets a float value from environment variable with fallback
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/clean-route/go-backend/internal/vehicles"
+)
+
+func testCar() vehicles.Profile {
+	return vehicles.Profile{
+		MassKg:            1200,
+		DragCoefficient:   0.3,
+		FrontalAreaM2:     2.2,
+		RollingResistance: 0.01,
+		FuelType:          "petrol",
+	}
+}
+
+func TestCalculateRouteEmissions(t *testing.T) {
+	tests := []struct {
+		name         string
+		energyKJ     float64
+		engine       string
+		condition    string
+		co2, nox, pm float64
+	}{
+		{"petrol new", 1000, "petrol", "new", 69, 0.03, 0.002},
+		{"diesel okay", 2000, "diesel", "okay", 148, 0.45, 0.009},
+		{"cng good", 1000, "cng", "good", 56, 0.022, 0.0011},
+		{"unknown engine and condition", 1000, "hydrogen", "", 69, 0.0375, 0.0025},
+		// 1 kWh at the default grid intensity
+		{"electric", 3600, "ev", "new", 710, 0, 0},
+		{"no energy", 0, "petrol", "new", 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateRouteEmissions(tt.energyKJ, tt.engine, tt.condition)
+			if math.Abs(got.CO2Grams-tt.co2) > 1e-6 || math.Abs(got.NOxGrams-tt.nox) > 1e-9 || math.Abs(got.PMGrams-tt.pm) > 1e-9 {
+				t.Errorf("emissions %+v, want CO2 %g g, NOx %g g, PM %g g", got, tt.co2, tt.nox, tt.pm)
+			}
+		})
+	}
+}
+
+func TestCalculateRouteEnergyNeverNegative(t *testing.T) {
+	// 1 km at 36 km/h, heading north
+	flat := EnergySegment{Distance: 1000, Time: 100, Start: [2]float64{77.59, 12.97}, End: [2]float64{77.59, 12.979}}
+	descent := flat
+	descent.HeightGain = -100
+	gentleDescent := flat
+	gentleDescent.HeightGain = -5
+	// Wind from the south at four times the car's speed pushes it along
+	tailwind := &EnergyWeather{TempC: 15, WindSpeed: 40, WindDeg: 180}
+
+	tests := []struct {
+		name     string
+		segment  EnergySegment
+		weather  *EnergyWeather
+		wantZero bool
+	}{
+		{"flat road", flat, nil, false},
+		{"gentle descent", gentleDescent, nil, false},
+		{"steep descent", descent, nil, true},
+		{"gentle descent with a strong tailwind", gentleDescent, tailwind, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			energy := CalculateRouteEnergy([]EnergySegment{tt.segment}, testCar(), "new", tt.weather)
+			if energy < 0 || (energy == 0) != tt.wantZero {
+				t.Errorf("energy %g kJ, want zero %v", energy, tt.wantZero)
+			}
+		})
+	}
+
+	// A descent spares the engine but does not pay for the next segment
+	flatEnergy := CalculateRouteEnergy([]EnergySegment{flat}, testCar(), "new", nil)
+	if energy := CalculateRouteEnergy([]EnergySegment{descent, flat}, testCar(), "new", nil); energy != flatEnergy {
+		t.Errorf("descent then flat road took %g kJ, want the %g kJ of the flat road", energy, flatEnergy)
+	}
+}
@@ -0,0 +1,414 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/airquality"
+	"github.com/clean-route/go-backend/internal/background"
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/errors"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/predictor"
+	"github.com/clean-route/go-backend/internal/weather"
+	"github.com/clean-route/go-backend/internal/workers"
+)
+
+// exposureSample is an air-quality reading taken at a sampled route point
+type exposureSample struct {
+	point        []float64
+	seconds      float64
+	reading      airquality.Reading
+	pm25         float64
+	weight       float64
+	ok           bool
+	interpolated bool
+	diag         models.SampleDiagnostic
+}
+
+// GetRouteExposureFromRoutePoints computes the PM2.5 exposure along the sampled
+// route points. Failed air-quality lookups are interpolated from neighbouring
+// samples (or skipped, per FAILED_SAMPLE_POLICY); an error is returned only when
+// fewer than MIN_SAMPLE_COVERAGE of the samples succeeded.
+func GetRouteExposureFromRoutePoints(routePoints [][]float64, routePointTime []float64, delayCode uint8) (models.RouteExposure, error) {
+	return routeExposure(newSampleLookup(), routePoints, routePointTime, delayCode)
+}
+
+// routeExposure computes a route's exposure with lookups shared with the other
+// routes of the request
+func routeExposure(lookup *sampleLookup, routePoints [][]float64, routePointTime []float64, delayCode uint8) (models.RouteExposure, error) {
+	samples, quality := fetchExposureSamples(lookup, routePoints, routePointTime)
+
+	// A route without sample points (no geometry) has nothing to be exposed to
+	if quality.SamplesRequested == 0 {
+		quality.Coverage = 1
+		return models.RouteExposure{Source: models.ExposureSourceObserved, Quality: quality}, nil
+	}
+
+	if quality.SamplesSucceeded == 0 || quality.Coverage < config.AppConfig.MinSampleCoverage {
+		logger.Error("Insufficient air-quality coverage for route",
+			"samples_requested", quality.SamplesRequested,
+			"samples_succeeded", quality.SamplesSucceeded,
+			"coverage", quality.Coverage,
+			"min_coverage", config.AppConfig.MinSampleCoverage,
+		)
+		return models.RouteExposure{}, errors.NewExternalError("insufficient air-quality data coverage for route", nil).
+			WithContext("samples_requested", quality.SamplesRequested).
+			WithContext("samples_succeeded", quality.SamplesSucceeded).
+			WithContext("min_coverage", config.AppConfig.MinSampleCoverage)
+	}
+
+	samples = fillFailedSamples(samples, &quality)
+	applySampleWeights(samples)
+
+	for _, sample := range samples {
+		sample.diag.PM25 = sample.pm25
+		sample.diag.Interpolated = sample.interpolated
+		quality.Samples = append(quality.Samples, sample.diag)
+	}
+
+	if delayCode == 0 {
+		exposure := getObservedExposure(samples)
+		exposure.Quality = quality
+		return exposure, nil
+	}
+
+	exposure, err := getModelExposure(lookup, samples, delayCode, &quality)
+	if err != nil {
+		logger.Warn("PM2.5 model unavailable, using WAQI daily forecast as fallback",
+			"error", err.Error(),
+			"delay_code", delayCode,
+			"points", len(samples),
+		)
+		quality.Degrade(models.ProviderPM25Model)
+		quality.FallbackUsed = true
+
+		departure := time.Now().Add(time.Duration(delayCode) * time.Hour)
+		exposure = getForecastExposure(samples, departure, delayCode)
+	}
+
+	exposure.Quality = quality
+	return exposure, nil
+}
+
+// fetchExposureSamples fetches the nearest station reading for every route
+// point from the air-quality provider chain, EXPOSURE_WORKERS points at a
+// time, and validates its age and distance from the point. Points without a
+// usable reading fall back to the background concentration grids when they
+// cover the point.
+func fetchExposureSamples(lookup *sampleLookup, routePoints [][]float64, routePointTime []float64) ([]exposureSample, models.DataQuality) {
+	var samples []exposureSample
+	var quality models.DataQuality
+	now := time.Now()
+
+	results := make([]readingResult, len(routePoints))
+	workers.ForEach(len(routePoints), config.AppConfig.ExposureWorkers, func(j int) {
+		if routePoints[j] != nil {
+			results[j] = lookup.nearest([2]float64{routePoints[j][0], routePoints[j][1]})
+		}
+	})
+
+	for j := 0; j < len(routePoints); j++ {
+		if routePoints[j] == nil {
+			continue
+		}
+		quality.SamplesRequested++
+
+		sample := exposureSample{point: routePoints[j], seconds: routePointTime[j]}
+		reading, failed, err := results[j].reading, results[j].failed, results[j].err
+		for _, provider := range failed {
+			quality.Degrade(provider)
+		}
+		if err != nil {
+			logger.Warn("Failed to fetch air-quality sample",
+				"error", err.Error(),
+				"location", routePoints[j],
+			)
+			sample.diag = models.SampleDiagnostic{Location: routePoints[j], Status: models.SampleStatusFailed}
+			if useBackground(&sample, now) {
+				quality.SamplesBackground++
+				quality.SamplesSucceeded++
+			}
+			samples = append(samples, sample)
+			continue
+		}
+
+		diag, weight := ValidateStationReading(reading, routePoints[j], now)
+		sample.reading = reading
+		sample.pm25 = reading.Concentration
+		sample.weight = weight
+		sample.diag = diag
+
+		if weight < 1 && config.AppConfig.StationLimitPolicy == "reject" {
+			if useBackground(&sample, now) {
+				quality.SamplesBackground++
+				quality.SamplesSucceeded++
+				samples = append(samples, sample)
+				continue
+			}
+			logger.Debug("Rejected air-quality sample outside station limits",
+				"status", diag.Status,
+				"station", diag.Station,
+				"location", routePoints[j],
+			)
+			quality.SamplesRejected++
+		} else {
+			if weight < 1 {
+				sample.diag.Status = models.SampleStatusDownweighted
+			}
+			sample.ok = true
+			quality.SamplesSucceeded++
+		}
+		samples = append(samples, sample)
+	}
+
+	if quality.SamplesRequested > 0 {
+		quality.Coverage = float64(quality.SamplesSucceeded) / float64(quality.SamplesRequested)
+	}
+	return samples, quality
+}
+
+// useBackground replaces a failed or rejected sample with the background
+// concentration at its point, reporting whether a grid covered the point
+func useBackground(sample *exposureSample, now time.Time) bool {
+	pm25, ok := background.Concentration(sample.point[0], sample.point[1], now, airquality.PollutantPM25)
+	if !ok {
+		return false
+	}
+
+	sample.reading = airquality.Reading{
+		Pollutant:     airquality.PollutantPM25,
+		Concentration: pm25,
+		Unit:          airquality.UnitMicrogramsPerCubicMeter,
+		Source:        models.ProviderBackground,
+		Timestamp:     now,
+	}
+	sample.pm25 = pm25
+	sample.weight = 1
+	sample.ok = true
+	sample.diag.Source = models.ProviderBackground
+	sample.diag.Station = ""
+	sample.diag.PM25 = pm25
+	sample.diag.Weight = 1
+	sample.diag.Status = models.SampleStatusBackground
+	return true
+}
+
+// applySampleWeights shrinks down-weighted readings toward the mean of the
+// fully trusted readings on the route. Routes without any trusted reading are
+// left unchanged.
+func applySampleWeights(samples []exposureSample) {
+	var sum float64
+	var count int
+	for _, sample := range samples {
+		if sample.ok && sample.weight >= 1 {
+			sum += sample.pm25
+			count++
+		}
+	}
+	if count == 0 {
+		return
+	}
+	consensus := sum / float64(count)
+
+	for j := range samples {
+		if samples[j].ok && samples[j].weight < 1 {
+			samples[j].pm25 = samples[j].weight*samples[j].pm25 + (1-samples[j].weight)*consensus
+		}
+	}
+}
+
+// fillFailedSamples interpolates failed samples linearly (by travel time)
+// between the nearest successful samples on either side, or drops them when
+// the policy is "skip". Interpolated samples borrow the nearest station's
+// forecast for the daily forecast fallback.
+func fillFailedSamples(samples []exposureSample, quality *models.DataQuality) []exposureSample {
+	if config.AppConfig.FailedSamplePolicy == "skip" {
+		kept := samples[:0]
+		for _, sample := range samples {
+			if sample.ok {
+				kept = append(kept, sample)
+			} else {
+				quality.SamplesSkipped++
+			}
+		}
+		return kept
+	}
+
+	// travel time to the end of each sample's segment
+	elapsed := make([]float64, len(samples))
+	var total float64
+	for j, sample := range samples {
+		total += sample.seconds
+		elapsed[j] = total
+	}
+
+	for j := range samples {
+		if samples[j].ok {
+			continue
+		}
+
+		prev, next := -1, -1
+		for k := j - 1; k >= 0; k-- {
+			if samples[k].ok {
+				prev = k
+				break
+			}
+		}
+		for k := j + 1; k < len(samples); k++ {
+			if samples[k].ok {
+				next = k
+				break
+			}
+		}
+
+		switch {
+		case prev >= 0 && next >= 0:
+			weight := (elapsed[j] - elapsed[prev]) / (elapsed[next] - elapsed[prev])
+			samples[j].pm25 = samples[prev].pm25 + weight*(samples[next].pm25-samples[prev].pm25)
+			if weight < 0.5 {
+				samples[j].reading = samples[prev].reading
+			} else {
+				samples[j].reading = samples[next].reading
+			}
+		case prev >= 0:
+			samples[j].pm25 = samples[prev].pm25
+			samples[j].reading = samples[prev].reading
+		default:
+			samples[j].pm25 = samples[next].pm25
+			samples[j].reading = samples[next].reading
+		}
+		samples[j].interpolated = true
+		quality.SamplesInterpolated++
+	}
+
+	return samples
+}
+
+// getObservedExposure sums the current readings along the route
+func getObservedExposure(samples []exposureSample) models.RouteExposure {
+	exposure := models.RouteExposure{
+		Interval: models.Interval{Confidence: predictor.IntervalConfidence},
+		Source:   models.ExposureSourceObserved,
+	}
+	for _, sample := range samples {
+		exposure.Total += sample.pm25 * sample.seconds / 3600 // converting time to hours
+		addExposureInterval(&exposure.Interval, predictor.EmpiricalInterval(sample.pm25, 0), sample.seconds)
+	}
+	return exposure
+}
+
+// modelFeatures constructs the dataframe (input features along the entire
+// route). The models were trained on WAQI's US AQI sub-index as IPM, so the
+// µg/m³ reading of every sample is converted back to that index.
+func modelFeatures(inputFeatures models.FeatureVector, samples []exposureSample) []models.FeatureVector {
+	df := make([]models.FeatureVector, len(samples))
+	for j, sample := range samples {
+		inputFeatures.IPM, _ = airquality.ConcentrationToAQI(airquality.PollutantPM25, sample.pm25)
+		df[j] = inputFeatures
+	}
+	return df
+}
+
+// getModelExposure predicts the PM2.5 level at every sample with the model registry
+func getModelExposure(lookup *sampleLookup, samples []exposureSample, delayCode uint8, quality *models.DataQuality) (models.RouteExposure, error) {
+	// Fetch the weather data for source and destination and we will use the average of the both for any point in route to get the weather measurement
+	sourceWeather, err := fetchForecast(lookup, samples[0].point, quality)
+	if err != nil {
+		return models.RouteExposure{}, err
+	}
+	destinationWeather, err := fetchForecast(lookup, samples[len(samples)-1].point, quality)
+	if err != nil {
+		return models.RouteExposure{}, err
+	}
+
+	inputFeatures, err := GetInputFeatures(sourceWeather, destinationWeather, delayCode) // except IPM
+	if err != nil {
+		quality.Degrade(sourceWeather.Source)
+		return models.RouteExposure{}, fmt.Errorf("hourly weather forecast unavailable for delay code %d: %w", delayCode, err)
+	}
+	inputFeatures.DelayCode = delayCode
+
+	forecast, err := predictor.Default.Predict(modelFeatures(inputFeatures, samples))
+	if err != nil {
+		return models.RouteExposure{}, err
+	}
+
+	exposure := models.RouteExposure{
+		Interval: models.Interval{Confidence: predictor.IntervalConfidence},
+		Source:   models.ExposureSourceModel,
+	}
+	for j, sample := range samples {
+		exposure.Total += forecast.Values[j] * sample.seconds / 3600 // converting time to hours
+		addExposureInterval(&exposure.Interval, forecast.Intervals[j], sample.seconds)
+	}
+
+	logger.Debug("Computed route exposure from PM2.5 model",
+		"model", forecast.Model,
+		"exposure", exposure.Total,
+	)
+
+	return exposure, nil
+}
+
+// getForecastExposure estimates exposure from each station's WAQI daily
+// forecast, down-weighted like the station's reading. Samples taken from the
+// background grids use the grid valid when the point is reached instead.
+func getForecastExposure(samples []exposureSample, departure time.Time, delayCode uint8) models.RouteExposure {
+	exposure := models.RouteExposure{
+		Interval: models.Interval{Confidence: predictor.IntervalConfidence},
+		Source:   models.ExposureSourceWAQIForecast,
+	}
+
+	elapsed := 0.0
+	for _, sample := range samples {
+		// estimate the level at the time the point is reached
+		elapsed += sample.seconds
+		at := departure.Add(time.Duration(elapsed) * time.Second)
+
+		pm25, interval := EstimatePM25FromForecast(sample.reading, at, delayCode)
+		// A stale or distant reading was shrunk toward the route's trusted
+		// readings; its station's forecast is scaled alike
+		if sample.ok && sample.weight < 1 && !sample.interpolated && sample.reading.Concentration > 0 {
+			scale := sample.pm25 / sample.reading.Concentration
+			pm25 *= scale
+			interval.Lower *= scale
+			interval.Upper *= scale
+		}
+		if sample.reading.Source == models.ProviderBackground {
+			if value, ok := background.Concentration(sample.point[0], sample.point[1], at, airquality.PollutantPM25); ok {
+				pm25, interval = value, predictor.EmpiricalInterval(value, delayCode)
+			}
+		}
+		exposure.Total += pm25 * sample.seconds / 3600 // converting time to hours
+		addExposureInterval(&exposure.Interval, interval, sample.seconds)
+	}
+
+	return exposure
+}
+
+// fetchForecast fetches the weather at a sample point, marking the providers
+// that failed as degraded
+func fetchForecast(lookup *sampleLookup, point []float64, quality *models.DataQuality) (weather.Forecast, error) {
+	result := lookup.forecast([2]float64{point[0], point[1]})
+	for _, provider := range result.failed {
+		quality.Degrade(provider)
+	}
+	return result.forecast, result.err
+}
+
+// addExposureInterval accumulates a point's concentration interval into the
+// route exposure interval. Prediction errors along a route share the same
+// weather inputs and model, so bounds are summed rather than combined in
+// quadrature, which keeps the route interval conservative.
+func addExposureInterval(total *models.Interval, point models.Interval, seconds float64) {
+	total.Lower += point.Lower * seconds / 3600
+	total.Upper += point.Upper * seconds / 3600
+	if point.Confidence < total.Confidence || total.Confidence == 0 {
+		total.Confidence = point.Confidence
+	}
+	if total.Source == "" {
+		total.Source = point.Source
+	}
+}
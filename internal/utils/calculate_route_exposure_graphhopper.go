@@ -1,13 +1,38 @@
 package utils
 
 import (
-	"fmt"
-
-	api "github.com/clean-route/go-backend/api"
 	graphhopper "github.com/clean-route/go-backend/internal/models/graphhopper"
 )
 
-func CalculateRouteExposureGraphhopper(route graphhopper.Path, delayCode uint8) graphhopper.Path {
+func CalculateRouteExposureGraphhopper(route graphhopper.Path, delayCode uint8) (graphhopper.Path, error) {
+	return graphhopperRouteExposure(newSampleLookup(), route, delayCode)
+}
+
+// CalculateRoutesExposureGraphhopper computes the exposure of candidate paths
+// concurrently. Sample points shared by the paths are looked up once. Paths
+// whose exposure cannot be computed are dropped; an error is returned only
+// when every path failed.
+func CalculateRoutesExposureGraphhopper(routes []graphhopper.Path, delayCode uint8) ([]graphhopper.Path, error) {
+	lookup := newSampleLookup()
+	succeeded, err := calculateConcurrently(len(routes), func(i int) error {
+		var err error
+		routes[i], err = graphhopperRouteExposure(lookup, routes[i], delayCode)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]graphhopper.Path, 0, len(routes))
+	for i, route := range routes {
+		if succeeded[i] {
+			kept = append(kept, route)
+		}
+	}
+	return kept, nil
+}
+
+func graphhopperRouteExposure(lookup *sampleLookup, route graphhopper.Path, delayCode uint8) (graphhopper.Path, error) {
 	var routePoints [][]float64
 	var routePointTime []float64
 
@@ -55,61 +80,20 @@ func CalculateRouteExposureGraphhopper(route graphhopper.Path, delayCode uint8)
 		}
 	}
 
-	// for each route the points are adding in this array
-
-	// fmt.Println(routePoints)
-	// fmt.Println("The total points taken in the route is: ", len(routePoints))
-	// fmt.Println(routePointTime)
-
-	if delayCode == 0 {
-		var totalRouteExposure float64
-
-		for j := 0; j < len(routePoints); j++ {
-			// fetch the aqi values for the points in the routes
-			if routePoints[j] == nil /* || routePointTime[j] == nil */ {
-				continue
-			}
-			pm25, err := api.FetchAQIData(routePoints[j], delayCode)
-			checkErrNil(err)
-			totalRouteExposure += pm25 * routePointTime[j] / 3600 // converting time to hours
-		}
-		route.TotalExposure = totalRouteExposure
-		// fmt.Println("************The total exposure for the route is**********: ", totalRouteExposure)
-		return route
+	// Paths made only of short steps are sampled at the destination
+	if len(routePoints) == 0 && len(routeCoordinates) > 0 {
+		routePoints = append(routePoints, routeCoordinates[len(routeCoordinates)-1][:])
+		routePointTime = append(routePointTime, skippedTime)
 	}
 
-	// // Fetch the weather data for source and destination and we will use the
-	// // average of the both for any point in route to get the weather measurement
-	// sourceWeatherData := api.FetchWeatherData(routePoints[0])
-	// sourceWeatherData.Current.RelativeHumidity = GetRelativeHumidity(sourceWeatherData.Current.DewPoint, sourceWeatherData.Current.Temp)
-	// destinationWeatherData := api.FetchWeatherData(routePoints[len(routePoints)-1])
-	// destinationWeatherData.Current.RelativeHumidity = GetRelativeHumidity(destinationWeatherData.Current.DewPoint, destinationWeatherData.Current.Temp)
-
-	// inputFeatures := GetInputFeatures(sourceWeatherData, destinationWeatherData, delayCode) // except IPM
-
-	// // fetching the aqi values for the points in the route
-	// var totalRouteExposure float64
-	// for j := 0; j < len(routePoints); j++ {
-	// 	// fetch the aqi values for the points in the routes
-	// 	if routePoints[j] == nil /* || routePointTime[j] == nil */ {
-	// 		continue
-	// 	}
-	// 	pm25, err := api.FetchAQIData(routePoints[j], delayCode)
-	// 	checkErrNil(err)
-	// 	inputFeatures.IPM = pm25
-
-	// 	// call the aws sagemaker and get the predicted value of pm25 concentration.
-	// 	fpm, err := api.GetPredictedPm25(inputFeatures, delayCode)
-	// 	checkErrNil(err)
-
-	// 	// calculate the total exposure based on that.
-	// 	fmt.Println("\n\nPartial Exposure: ", totalRouteExposure, " # ", fpm, " # ", routePointTime[j])
-	// 	totalRouteExposure += fpm * routePointTime[j] / 3600 // converting time to hours
-	// }
-
-	var totalRouteExposure float64 = GetRouteExposureFromRoutePoints(routePoints, routePointTime, delayCode)
+	exposure, err := routeExposure(lookup, routePoints, routePointTime, delayCode)
+	if err != nil {
+		return route, err
+	}
 
-	route.TotalExposure = totalRouteExposure
-	fmt.Println("&&&&&&&&&&&&&&&&The total exposure for the route is&&&&&&&&&&&&&&&: ", totalRouteExposure)
-	return route
+	route.TotalExposure = exposure.Total
+	route.ExposureInterval = exposure.Interval
+	route.ExposureSource = exposure.Source
+	route.DataQuality = exposure.Quality
+	return route, nil
 }
@@ -1,14 +1,39 @@
 package utils
 
 import (
-	"log"
-
-	"github.com/clean-route/go-backend/api"
-	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/config"
 	mapbox "github.com/clean-route/go-backend/internal/models/mapbox"
 )
 
-func CalculateRouteExposureMapbox(route mapbox.Route, delayCode uint8) mapbox.Route {
+func CalculateRouteExposureMapbox(route mapbox.Route, delayCode uint8) (mapbox.Route, error) {
+	return mapboxRouteExposure(newSampleLookup(), route, delayCode)
+}
+
+// CalculateRoutesExposureMapbox computes the exposure of candidate routes
+// concurrently. Sample points shared by the routes are looked up once. Routes
+// whose exposure cannot be computed are dropped; an error is returned only
+// when every route failed.
+func CalculateRoutesExposureMapbox(routes []mapbox.Route, delayCode uint8) ([]mapbox.Route, error) {
+	lookup := newSampleLookup()
+	succeeded, err := calculateConcurrently(len(routes), func(i int) error {
+		var err error
+		routes[i], err = mapboxRouteExposure(lookup, routes[i], delayCode)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]mapbox.Route, 0, len(routes))
+	for i, route := range routes {
+		if succeeded[i] {
+			kept = append(kept, route)
+		}
+	}
+	return kept, nil
+}
+
+func mapboxRouteExposure(lookup *sampleLookup, route mapbox.Route, delayCode uint8) (mapbox.Route, error) {
 	var routePoints [][]float64
 	var routePointTime []float64
 
@@ -17,16 +42,26 @@ func CalculateRouteExposureMapbox(route mapbox.Route, delayCode uint8) mapbox.Ro
 
 	steps := route.Legs[0].Steps
 
+	// Idling in queues raises exposure, so idle seconds count extra
+	stepIdleTimes, idleTime := mapboxStepIdleTimes(route)
+	route.IdleTime = idleTime
+	stepTime := func(j int) float64 {
+		if stepIdleTimes == nil {
+			return steps[j].Duration
+		}
+		return steps[j].Duration + stepIdleTimes[j]*(config.AppConfig.IdlingExposureFactor-1)
+	}
+
 	for j := 0; j < len(steps); j++ {
 		if steps[j].Distance < 1000 {
 			// if the distance is less than 1 KM, we skip the distance
 			if skippedDistance >= 2 {
 				index := len(steps[j].Geometry.Coordinates) / 2
 				routePoints = append(routePoints, steps[j].Geometry.Coordinates[index])
-				routePointTime = append(routePointTime, steps[j].Duration+skippedTime)
+				routePointTime = append(routePointTime, stepTime(j)+skippedTime)
 			} else {
 				skippedDistance += steps[j].Distance * 0.001
-				skippedTime += steps[j].Duration
+				skippedTime += stepTime(j)
 				continue
 			}
 		} else if steps[j].Distance < 2000 {
@@ -37,13 +72,13 @@ func CalculateRouteExposureMapbox(route mapbox.Route, delayCode uint8) mapbox.Ro
 			// taking the middle coordinate of the step
 			index := len(steps[j].Geometry.Coordinates) / 2
 			routePoints = append(routePoints, steps[j].Geometry.Coordinates[index])
-			routePointTime = append(routePointTime, steps[j].Duration)
+			routePointTime = append(routePointTime, stepTime(j))
 		} else if steps[j].Distance >= 2000 {
 			skippedDistance = 0
 			skippedTime = 0
 
-			chunks := int(steps[j].Distance / 2000)          // number of chunks
-			timeChunk := steps[j].Duration / float64(chunks) // time for each chunk
+			chunks := int(steps[j].Distance / 2000)    // number of chunks
+			timeChunk := stepTime(j) / float64(chunks) // time for each chunk
 
 			chunkLength := len(steps[j].Geometry.Coordinates) / chunks // number of coordinates in each chunk
 
@@ -57,116 +92,39 @@ func CalculateRouteExposureMapbox(route mapbox.Route, delayCode uint8) mapbox.Ro
 		}
 	}
 
-	// for each route the points are adding in this array
-
-	// fmt.Println(routePoints)
-	// fmt.Println("The total points taken in the route is: ", len(routePoints))
-	// fmt.Println(routePointTime)
-
-	// fetching the aqi values for the points in the route
-
-	if delayCode == 0 {
-		var totalRouteExposure float64
-
-		for j := 0; j < len(routePoints); j++ {
-			// fetch the aqi values for the points in the routes
-			if routePoints[j] == nil {
-				continue
-			}
-			pm25, err := api.FetchAQIData(routePoints[j], delayCode)
-			checkErrNil(err)
-			totalRouteExposure += pm25 * routePointTime[j] / 3600 // converting time to hours
-		}
-		route.TotalExposure = totalRouteExposure
-		return route
-	}
-
-	// // Fetch the weather data for source and destination and we will use the average of the both for any point in route to get the weather measurement
-
-	// sourceWeatherData := api.FetchWeatherData(routePoints[0])
-	// sourceWeatherData.Current.RelativeHumidity = GetRelativeHumidity(sourceWeatherData.Current.DewPoint, sourceWeatherData.Current.Temp)
-	// destinationWeatherData := api.FetchWeatherData(routePoints[len(routePoints)-1])
-	// destinationWeatherData.Current.RelativeHumidity = GetRelativeHumidity(destinationWeatherData.Current.DewPoint, destinationWeatherData.Current.Temp)
-
-	// inputFeatures := GetInputFeatures(sourceWeatherData, destinationWeatherData, delayCode) // except IPM
-
-	// // fetching the aqi values for the points in the route
-
-	// var totalRouteExposure float64
-
-	// for j := 0; j < len(routePoints); j++ {
-	// 	if routePoints[j] == nil {
-	// 		continue
-	// 	}
-	// 	pm25, err := api.FetchAQIData(routePoints[j], delayCode)
-	// 	checkErrNil(err)
-	// 	inputFeatures.IPM = pm25
-
-	// 	// call the aws ec2 instance and get the predicted value of pm25 concentration.
-	// 	fpm, err := api.GetPredictedPm25(inputFeatures, delayCode)
-	// 	checkErrNil(err)
-
-	// 	// Calculate the total exposure based on that.
-	// 	fmt.Println("\n\nPartial Exposure: ", totalRouteExposure, " # ", fpm, " # ", routePointTime[j])
-	// 	totalRouteExposure += fpm * routePointTime[j] / 3600  // converting time to hours
-	// }
-
-	var totalRouteExposure float64 = GetRouteExposureFromRoutePoints(routePoints, routePointTime, delayCode)
-	route.TotalExposure = totalRouteExposure
-	// fmt.Println("&&&&&&&&&&&&&&&&The total exposure for the route is&&&&&&&&&&&&&&&: ", totalRouteExposure)
-	return route
-}
-
-func GetRouteExposureFromRoutePoints(routePoints [][]float64, routePointTime []float64, delayCode uint8) float64 {
-	// Fetch the weather data for source and destination and we will use the average of the both for any point in route to get the weather measurement
-	sourceWeatherData := api.FetchWeatherData(routePoints[0])
-	sourceWeatherData.Current.RelativeHumidity = GetRelativeHumidity(sourceWeatherData.Current.DewPoint, sourceWeatherData.Current.Temp)
-	destinationWeatherData := api.FetchWeatherData(routePoints[len(routePoints)-1])
-	destinationWeatherData.Current.RelativeHumidity = GetRelativeHumidity(destinationWeatherData.Current.DewPoint, destinationWeatherData.Current.Temp)
-
-	inputFeatures := GetInputFeatures(sourceWeatherData, destinationWeatherData, delayCode) // except IPM
-	inputFeatures.DelayCode = delayCode
-
-	// fetching the aqi values for the points in the route
-
-	var totalRouteExposure float64
-	var df []models.FeatureVector
-
-	// constructing the dataframe (input features along the entire route)
-	for j := 0; j < len(routePoints); j++ {
-		if routePoints[j] == nil {
-			continue
+	// Routes made only of short steps are sampled at the destination
+	if len(routePoints) == 0 {
+		if destination := mapboxDestination(route); destination != nil {
+			routePoints = append(routePoints, destination)
+			routePointTime = append(routePointTime, skippedTime)
 		}
-		pm25, err := api.FetchAQIData(routePoints[j], delayCode)
-		checkErrNil(err)
-		inputFeatures.IPM = pm25
-
-		// call the aws ec2 instance and get the predicted value of pm25 concentration.
-		df = append(df, inputFeatures)
-		// fpm, err := api.GetPredictedPm25(inputFeatures, delayCode)
-		// checkErrNil(err)
-
-		// // Calculate the total exposure based on that.
-		// fmt.Println("\n\nPartial Exposure: ", totalRouteExposure, " # ", fpm, " # ", routePointTime[j])
-		// totalRouteExposure += fpm * routePointTime[j] / 3600  // converting time to hours
 	}
 
-	// making the api call to get the entire
-	fpmVec, err := api.GetPredictedPm25(df)
-	checkErrNil(err)
-
-	// calculating the total exposure
-	for j := 0; j < len(routePoints); j++ {
-		// calculate the total exposure using the predicted fpm
-		// fmt.Println("\n\nPartial Exposure: ", totalRouteExposure, " # ", fpm, " # ", routePointTime[j])
-		totalRouteExposure += fpmVec[j] * routePointTime[j] / 3600 // converting time to hours
+	exposure, err := routeExposure(lookup, routePoints, routePointTime, delayCode)
+	if err != nil {
+		return route, err
 	}
 
-	return totalRouteExposure
+	route.TotalExposure = exposure.Total
+	route.ExposureInterval = exposure.Interval
+	route.ExposureSource = exposure.Source
+	route.DataQuality = exposure.Quality
+	return route, nil
 }
 
-func checkErrNil(err error) {
-	if err != nil {
-		log.Fatalf("Error encountered: %s", err)
+// mapboxDestination returns the last coordinate of a route's geometry, or of
+// its last step with a geometry
+func mapboxDestination(route mapbox.Route) []float64 {
+	if coordinates := route.Geometry.Coordinates; len(coordinates) > 0 {
+		return coordinates[len(coordinates)-1]
+	}
+	for i := len(route.Legs) - 1; i >= 0; i-- {
+		steps := route.Legs[i].Steps
+		for j := len(steps) - 1; j >= 0; j-- {
+			if coordinates := steps[j].Geometry.Coordinates; len(coordinates) > 0 {
+				return coordinates[len(coordinates)-1]
+			}
+		}
 	}
+	return nil
 }
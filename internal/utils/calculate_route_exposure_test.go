@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/airquality"
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/models"
+	graphhopper "github.com/clean-route/go-backend/internal/models/graphhopper"
+)
+
+func testConfig() {
+	config.AppConfig = &config.Config{
+		MinSampleCoverage:  0.5,
+		ExposureWorkers:    2,
+		StationLimitPolicy: "reject",
+	}
+}
+
+func TestRouteExposureWithoutSamples(t *testing.T) {
+	testConfig()
+
+	exposure, err := routeExposure(newSampleLookup(), nil, nil, 0)
+	if err != nil {
+		t.Fatalf("routeExposure: %v", err)
+	}
+	if exposure.Total != 0 || exposure.Quality.Coverage != 1 {
+		t.Errorf("exposure %g with coverage %g, want 0 with full coverage", exposure.Total, exposure.Quality.Coverage)
+	}
+}
+
+func TestShortGraphhopperPathSamplesDestination(t *testing.T) {
+	testConfig()
+
+	// Three steps under 1 km, 1.5 km and 450 s in total
+	path := graphhopper.Path{
+		Points: graphhopper.Waypoint{Coordinates: []graphhopper.Coordinates{
+			{77.59, 12.97, 0}, {77.595, 12.975, 0}, {77.60, 12.98, 0}, {77.605, 12.985, 0},
+		}},
+		Instructions: []graphhopper.Instruction{
+			{Distance: 500, Time: 150000, Interval: []int{0, 1}},
+			{Distance: 500, Time: 150000, Interval: []int{1, 2}},
+			{Distance: 500, Time: 150000, Interval: []int{2, 3}},
+		},
+	}
+
+	lookup := newSampleLookup()
+	destination := [2]float64{77.605, 12.985}
+	lookup.readings[destination] = readingResult{reading: airquality.Reading{
+		Pollutant:     airquality.PollutantPM25,
+		Concentration: 40,
+		Location:      destination,
+		Timestamp:     time.Now(),
+	}}
+
+	route, err := graphhopperRouteExposure(lookup, path, 0)
+	if err != nil {
+		t.Fatalf("graphhopperRouteExposure: %v", err)
+	}
+	// 40 µg/m³ for 450 s
+	if want := 40 * 450.0 / 3600; math.Abs(route.TotalExposure-want) > 1e-9 {
+		t.Errorf("exposure %g, want %g", route.TotalExposure, want)
+	}
+	if route.DataQuality.SamplesRequested != 1 {
+		t.Errorf("%d samples, want the destination only", route.DataQuality.SamplesRequested)
+	}
+}
+
+func TestCalculateConcurrently(t *testing.T) {
+	failure := errors.New("no coverage")
+
+	tests := []struct {
+		name          string
+		fails         []bool
+		wantSucceeded []bool
+		wantErr       bool
+	}{
+		{"all succeed", []bool{false, false}, []bool{true, true}, false},
+		{"one route fails", []bool{false, true, false}, []bool{true, false, true}, false},
+		{"every route fails", []bool{true, true}, []bool{false, false}, true},
+		{"no routes", nil, []bool{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			succeeded, err := calculateConcurrently(len(tt.fails), func(i int) error {
+				if tt.fails[i] {
+					return failure
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			for i := range tt.wantSucceeded {
+				if succeeded[i] != tt.wantSucceeded[i] {
+					t.Errorf("route %d succeeded %v, want %v", i, succeeded[i], tt.wantSucceeded[i])
+				}
+			}
+		})
+	}
+}
+
+func TestModelFeaturesUseAQIForIPM(t *testing.T) {
+	samples := []exposureSample{{pm25: 0}, {pm25: 12}, {pm25: 35.4}, {pm25: 103}}
+	// US EPA sub-indices of the concentrations above
+	want := []float64{0, 50, 100, 175.53}
+
+	df := modelFeatures(models.FeatureVector{ITEMP: 25, DelayCode: 2}, samples)
+	for j, features := range df {
+		if math.Abs(features.IPM-want[j]) > 0.01 {
+			t.Errorf("sample %d: IPM %g, want AQI %g", j, features.IPM, want[j])
+		}
+		if features.ITEMP != 25 || features.DelayCode != 2 {
+			t.Errorf("sample %d: weather features %+v not carried over", j, features)
+		}
+	}
+}
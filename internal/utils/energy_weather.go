@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/clean-route/go-backend/internal/weather"
+)
+
+const (
+	// Standard atmosphere at sea level
+	sea_level_pressure    = 101325.0 // Pa
+	standard_temperature  = 15.0     // °C
+	specific_gas_constant = 287.05   // J/(kg·K) for dry air
+
+	// Rolling resistance rises by up to this share on a wet road, reached at
+	// wet_road_full_precipitation mm/h of rain
+	wet_road_rolling_increase   = 0.2
+	wet_road_full_precipitation = 2.0
+)
+
+// EnergyWeather is the weather at the start of a trip that affects how much
+// energy a vehicle needs
+type EnergyWeather struct {
+	TempC         float64 // °C
+	WindSpeed     float64 // m/s
+	WindDeg       float64 // direction the wind blows from, degrees
+	Precipitation float64 // mm/h
+}
+
+// GetEnergyWeather returns the current conditions at a location from the
+// first weather provider that answers, or nil when none does. The lookup goes
+// through the weather cache shared with the exposure model.
+func GetEnergyWeather(location []float64) *EnergyWeather {
+	forecast, _, err := weather.Fetch([2]float64{location[0], location[1]})
+	if err != nil {
+		return nil
+	}
+
+	return &EnergyWeather{
+		TempC:         forecast.Current.TempC,
+		WindSpeed:     forecast.Current.WindSpeed,
+		WindDeg:       forecast.Current.WindDeg,
+		Precipitation: forecast.Current.Precipitation,
+	}
+}
+
+// getAirDensity returns the density of air (kg/m³) at an elevation, using the
+// trip temperature when known
+func getAirDensity(weather *EnergyWeather, elevation float64) float64 {
+	if weather == nil && elevation == 0 {
+		return air_density
+	}
+
+	temperature := standard_temperature
+	if weather != nil {
+		temperature = weather.TempC
+	}
+
+	// Barometric formula of the standard atmosphere
+	pressure := sea_level_pressure * math.Pow(1-2.25577e-5*math.Max(elevation, 0), 5.25588)
+	return pressure / (specific_gas_constant * (temperature + 273.15))
+}
+
+// getHeadwind returns the wind component against the direction of travel
+// (m/s); negative values are tailwind
+func getHeadwind(weather *EnergyWeather, segment EnergySegment) float64 {
+	if weather == nil || weather.WindSpeed == 0 || segment.Start == segment.End {
+		return 0
+	}
+	angle := (weather.WindDeg - getBearing(segment.Start, segment.End)) * math.Pi / 180
+	return weather.WindSpeed * math.Cos(angle)
+}
+
+// getRollingResistanceFactor returns how much rain raises rolling resistance
+func getRollingResistanceFactor(weather *EnergyWeather) float64 {
+	if weather == nil || weather.Precipitation <= 0 {
+		return 1
+	}
+	return 1 + wet_road_rolling_increase*math.Min(weather.Precipitation/wet_road_full_precipitation, 1)
+}
+
+// getBearing returns the initial compass bearing (degrees) from start to end,
+// both [longitude, latitude]
+func getBearing(start, end [2]float64) float64 {
+	lat1 := start[1] * math.Pi / 180
+	lat2 := end[1] * math.Pi / 180
+	deltaLon := (end[0] - start[0]) * math.Pi / 180
+
+	y := math.Sin(deltaLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(deltaLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
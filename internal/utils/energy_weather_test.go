@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGetHeadwind(t *testing.T) {
+	// Heading north
+	north := EnergySegment{Start: [2]float64{77.59, 12.97}, End: [2]float64{77.59, 12.98}}
+
+	tests := []struct {
+		name    string
+		weather *EnergyWeather
+		segment EnergySegment
+		want    float64
+	}{
+		{"headwind", &EnergyWeather{WindSpeed: 5, WindDeg: 0}, north, 5},
+		{"tailwind", &EnergyWeather{WindSpeed: 5, WindDeg: 180}, north, -5},
+		{"crosswind", &EnergyWeather{WindSpeed: 5, WindDeg: 90}, north, 0},
+		{"quartering headwind", &EnergyWeather{WindSpeed: 4, WindDeg: 60}, north, 2},
+		{"no weather", nil, north, 0},
+		{"no movement", &EnergyWeather{WindSpeed: 5}, EnergySegment{Start: north.Start, End: north.Start}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getHeadwind(tt.weather, tt.segment); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("getHeadwind = %g m/s, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAirDensity(t *testing.T) {
+	tests := []struct {
+		name      string
+		weather   *EnergyWeather
+		elevation float64
+		want      float64
+	}{
+		{"standard sea level", nil, 0, air_density},
+		{"standard temperature", &EnergyWeather{TempC: 15}, 0, 1.2250},
+		{"hot day", &EnergyWeather{TempC: 35}, 0, 1.1455},
+		{"cold day", &EnergyWeather{TempC: -10}, 0, 1.3414},
+		{"altitude", nil, 2000, 0.9611},
+		{"below sea level counts as sea level", &EnergyWeather{TempC: 15}, -50, 1.2250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getAirDensity(tt.weather, tt.elevation); math.Abs(got-tt.want) > 1e-4 {
+				t.Errorf("getAirDensity = %.4f kg/m³, want %.4f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRollingResistanceFactor(t *testing.T) {
+	tests := []struct {
+		name    string
+		weather *EnergyWeather
+		want    float64
+	}{
+		{"no weather", nil, 1},
+		{"dry road", &EnergyWeather{}, 1},
+		{"light rain", &EnergyWeather{Precipitation: 0.5}, 1.05},
+		{"full effect", &EnergyWeather{Precipitation: 2}, 1.2},
+		{"heavy rain capped", &EnergyWeather{Precipitation: 20}, 1.2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getRollingResistanceFactor(tt.weather); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("getRollingResistanceFactor = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteEnergyWithWeather(t *testing.T) {
+	segment := EnergySegment{Distance: 1000, Time: 50, Start: [2]float64{77.59, 12.97}, End: [2]float64{77.59, 12.979}}
+	calm := CalculateRouteEnergy([]EnergySegment{segment}, testCar(), "new", &EnergyWeather{TempC: 15})
+
+	tests := []struct {
+		name    string
+		weather *EnergyWeather
+		more    bool
+	}{
+		{"headwind", &EnergyWeather{TempC: 15, WindSpeed: 8, WindDeg: 0}, true},
+		{"tailwind", &EnergyWeather{TempC: 15, WindSpeed: 8, WindDeg: 180}, false},
+		{"rain", &EnergyWeather{TempC: 15, Precipitation: 3}, true},
+		{"cold dense air", &EnergyWeather{TempC: -10}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			energy := CalculateRouteEnergy([]EnergySegment{segment}, testCar(), "new", tt.weather)
+			if (energy > calm) != tt.more || energy == calm {
+				t.Errorf("energy %g kJ against %g kJ in calm weather, want more %v", energy, calm, tt.more)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/clean-route/go-backend/internal/airquality"
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/predictor"
+)
+
+// EstimatePM25FromForecast estimates the PM2.5 level at a station for the
+// given time from the daily forecast reported with the reading. The day's
+// average is shaped by the configured diurnal profile and kept within the
+// forecast min/max, which also bound the returned interval. When the station
+// has no forecast for that day, the current reading is carried forward with
+// the empirical error for the horizon.
+func EstimatePM25FromForecast(reading airquality.Reading, at time.Time, delayCode uint8) (float64, models.Interval) {
+	// Forecast days are in the station's time zone
+	local := at.In(reading.Timestamp.Location())
+	day := local.Format("2006-01-02")
+
+	for _, daily := range reading.Forecast {
+		if daily.Day != day {
+			continue
+		}
+
+		value := daily.Avg
+		if profile := config.AppConfig.FallbackDiurnalProfile; len(profile) == 24 {
+			value *= profile[local.Hour()]
+		}
+
+		lower, upper := daily.Min, daily.Max
+		if value < lower {
+			value = lower
+		}
+		if value > upper {
+			value = upper
+		}
+
+		// The forecast range bounds every hourly value of the day
+		return value, models.Interval{
+			Lower:      lower,
+			Upper:      upper,
+			Confidence: 1,
+			Source:     models.ExposureSourceWAQIForecast,
+		}
+	}
+
+	current := reading.Concentration
+	return current, predictor.EmpiricalInterval(current, delayCode)
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/airquality"
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/predictor"
+)
+
+func TestEstimatePM25FromForecast(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	reading := airquality.Reading{
+		Concentration: 40,
+		Timestamp:     time.Date(2024, 1, 1, 9, 0, 0, 0, ist),
+		Forecast: []airquality.DailyForecast{
+			{Day: "2024-01-01", Avg: 50, Min: 30, Max: 70},
+			{Day: "2024-01-02", Avg: 60, Min: 58, Max: 62},
+		},
+	}
+	// Doubled at 8, up a fifth at 10, halved at 22
+	profile := make([]float64, 24)
+	for hour := range profile {
+		profile[hour] = 1
+	}
+	profile[8], profile[10], profile[22] = 2, 1.2, 0.5
+
+	tests := []struct {
+		name      string
+		profile   []float64
+		at        time.Time
+		want      float64
+		wantRange [2]float64
+	}{
+		{"forecast day average", nil, time.Date(2024, 1, 1, 12, 0, 0, 0, ist), 50, [2]float64{30, 70}},
+		{"day in the station's time zone", nil, time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC), 60, [2]float64{58, 62}},
+		{"diurnal profile", profile, time.Date(2024, 1, 1, 10, 0, 0, 0, ist), 60, [2]float64{30, 70}},
+		{"clamped to the forecast max", profile, time.Date(2024, 1, 1, 8, 0, 0, 0, ist), 70, [2]float64{30, 70}},
+		{"clamped to the forecast min", profile, time.Date(2024, 1, 1, 22, 0, 0, 0, ist), 30, [2]float64{30, 70}},
+		{"profile without 24 hours ignored", []float64{2, 2}, time.Date(2024, 1, 1, 8, 0, 0, 0, ist), 50, [2]float64{30, 70}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.AppConfig = &config.Config{FallbackDiurnalProfile: tt.profile}
+			got, interval := EstimatePM25FromForecast(reading, tt.at, 3)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("estimate %g, want %g", got, tt.want)
+			}
+			if interval.Lower != tt.wantRange[0] || interval.Upper != tt.wantRange[1] || interval.Source != models.ExposureSourceWAQIForecast {
+				t.Errorf("interval %+v, want the forecast range %v", interval, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestEstimatePM25WithoutForecastDay(t *testing.T) {
+	config.AppConfig = &config.Config{PredictionErrorByHorizon: map[uint8]float64{0: 5, 3: 10}}
+	reading := airquality.Reading{
+		Concentration: 40,
+		Timestamp:     time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		Forecast:      []airquality.DailyForecast{{Day: "2024-01-01", Avg: 50, Min: 30, Max: 70}},
+	}
+
+	got, interval := EstimatePM25FromForecast(reading, time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC), 3)
+	if want := predictor.EmpiricalInterval(40, 3); got != 40 || interval != want {
+		t.Errorf("estimate %g with %+v, want the current reading with %+v", got, interval, want)
+	}
+}
+
+func TestForecastExposureDownweightsLikeReadings(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	day := time.Now().UTC()
+	forecast := []airquality.DailyForecast{{Day: day.Format("2006-01-02"), Avg: 80, Min: 40, Max: 120}}
+	station := func(concentration float64) airquality.Reading {
+		return airquality.Reading{Concentration: concentration, Timestamp: day, Forecast: forecast}
+	}
+
+	trusted := exposureSample{seconds: 3600, reading: station(80), pm25: 80, weight: 1, ok: true}
+	// Shrunk halfway from 120 toward the trusted 80
+	downweighted := exposureSample{seconds: 3600, reading: station(120), pm25: 100, weight: 0.5, ok: true}
+
+	exposure := getForecastExposure([]exposureSample{trusted, downweighted}, day, 0)
+	// 80 for the trusted sample, 80 × 100/120 for the down-weighted one
+	if want := 80 + 80*100.0/120; math.Abs(exposure.Total-want) > 1e-9 {
+		t.Errorf("exposure %g, want %g", exposure.Total, want)
+	}
+}
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/clean-route/go-backend/internal/elevation"
+	"github.com/clean-route/go-backend/internal/geo"
+	mapbox "github.com/clean-route/go-backend/internal/models/mapbox"
+)
+
+const (
+	// Below this speed (m/s) a segment counts as idling
+	idle_speed = 5 / 3.6
+)
+
+// Stop-and-go cycles per km and the share of time spent idling for each
+// Mapbox congestion level
+var (
+	stopGoCyclesPerKm = map[string]float64{"moderate": 1, "heavy": 3, "severe": 6}
+	idleShare         = map[string]float64{"moderate": 0.1, "heavy": 0.3, "severe": 0.5}
+)
+
+// annotatedSegment is the stretch between two consecutive coordinates of a
+// Mapbox leg together with its annotations
+type annotatedSegment struct {
+	start, end []float64
+	distance   float64
+	duration   float64
+	congestion string
+	leg, step  int
+}
+
+// annotateMapboxRoute pairs the route's annotations with the coordinates of
+// its steps. ok is false when the route has no annotations or they do not
+// line up with the step geometry.
+func annotateMapboxRoute(route mapbox.Route) (segments []annotatedSegment, ok bool) {
+	for legIndex, leg := range route.Legs {
+		annotation := leg.Annotation
+		if len(annotation.Duration) == 0 {
+			return nil, false
+		}
+
+		// Consecutive steps share their boundary coordinate
+		var coordinates [][]float64
+		var steps []int
+		for stepIndex, step := range leg.Steps {
+			for _, coordinate := range step.Geometry.Coordinates {
+				if len(coordinates) > 0 && sameCoordinate(coordinates[len(coordinates)-1], coordinate) {
+					continue
+				}
+				coordinates = append(coordinates, coordinate)
+				steps = append(steps, stepIndex)
+			}
+		}
+		if len(coordinates)-1 != len(annotation.Duration) {
+			return nil, false
+		}
+
+		for i := 0; i+1 < len(coordinates); i++ {
+			segment := annotatedSegment{
+				start:    coordinates[i],
+				end:      coordinates[i+1],
+				duration: annotation.Duration[i],
+				leg:      legIndex,
+				step:     steps[i],
+			}
+			if i < len(annotation.Distance) {
+				segment.distance = annotation.Distance[i]
+			} else {
+				segment.distance = geo.HaversineDistance(segment.start[1], segment.start[0], segment.end[1], segment.end[0])
+			}
+			if i < len(annotation.Congestion) {
+				segment.congestion = annotation.Congestion[i]
+			}
+			segments = append(segments, segment)
+		}
+	}
+	return segments, true
+}
+
+// idleSeconds estimates how long the vehicle stands still on the segment
+func (s annotatedSegment) idleSeconds() float64 {
+	if s.duration == 0 {
+		return 0
+	}
+	if s.distance/s.duration < idle_speed {
+		return s.duration
+	}
+	return s.duration * idleShare[s.congestion]
+}
+
+func sameCoordinate(a, b []float64) bool {
+	return len(a) >= 2 && len(b) >= 2 && a[0] == b[0] && a[1] == b[1]
+}
+
+// MapboxSegments splits a Mapbox route into energy segments with elevations
+// from the local elevation model. With annotations every segment between two
+// coordinates uses its own duration and congestion, accelerating whenever
+// speed increases; without them each step is one segment driven at its
+// average speed. complete is false when some segment ends had no elevation
+// data; those segments are treated as flat.
+func MapboxSegments(route mapbox.Route) (segments []EnergySegment, complete bool) {
+	annotated, ok := annotateMapboxRoute(route)
+	if !ok {
+		return mapboxStepSegments(route)
+	}
+
+	complete = true
+	var previousVelocity float64
+	for _, a := range annotated {
+		if a.duration == 0 {
+			continue
+		}
+
+		segment := EnergySegment{
+			Distance:     a.distance,
+			Time:         a.duration,
+			Start:        [2]float64{a.start[0], a.start[1]},
+			End:          [2]float64{a.end[0], a.end[1]},
+			StopGoCycles: a.distance / 1000 * stopGoCyclesPerKm[a.congestion],
+		}
+
+		// Only the part of the kinetic energy gained since the previous
+		// segment has to be supplied
+		velocity := a.distance / a.duration
+		if velocity > 0 {
+			segment.AccelerationCycles = math.Max(0, velocity*velocity-previousVelocity*previousVelocity) / (velocity * velocity)
+		}
+		previousVelocity = velocity
+
+		if !setHeightGain(&segment) {
+			complete = false
+		}
+		segments = append(segments, segment)
+	}
+	return segments, complete
+}
+
+// mapboxStepSegments splits a Mapbox route into its steps, using the step
+// durations from Mapbox
+func mapboxStepSegments(route mapbox.Route) (segments []EnergySegment, complete bool) {
+	complete = true
+	for _, leg := range route.Legs {
+		for _, step := range leg.Steps {
+			coordinates := step.Geometry.Coordinates
+			if len(coordinates) == 0 || step.Duration == 0 {
+				continue
+			}
+			start := coordinates[0]
+			end := coordinates[len(coordinates)-1]
+
+			segment := EnergySegment{
+				Distance: step.Distance,
+				Time:     step.Duration,
+				Start:    [2]float64{start[0], start[1]},
+				End:      [2]float64{end[0], end[1]},
+
+				AccelerationCycles: 1,
+			}
+			if !setHeightGain(&segment) {
+				complete = false
+			}
+			segments = append(segments, segment)
+		}
+	}
+	return segments, complete
+}
+
+// setHeightGain looks up the elevation of both segment ends, returning false
+// when either is unavailable
+func setHeightGain(segment *EnergySegment) bool {
+	startElevation, startOk := elevation.Lookup(segment.Start[0], segment.Start[1])
+	endElevation, endOk := elevation.Lookup(segment.End[0], segment.End[1])
+	if !startOk || !endOk {
+		return false
+	}
+	segment.HeightGain = endElevation - startElevation
+	segment.Elevation = (startElevation + endElevation) / 2
+	return true
+}
+
+// mapboxStepIdleTimes returns the idling seconds in each step of the route's
+// first leg and in the whole route, or nil without usable annotations
+func mapboxStepIdleTimes(route mapbox.Route) ([]float64, float64) {
+	annotated, ok := annotateMapboxRoute(route)
+	if !ok || len(route.Legs) == 0 {
+		return nil, 0
+	}
+
+	perStep := make([]float64, len(route.Legs[0].Steps))
+	var total float64
+	for _, a := range annotated {
+		idle := a.idleSeconds()
+		total += idle
+		if a.leg == 0 {
+			perStep[a.step] += idle
+		}
+	}
+	return perStep, total
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/clean-route/go-backend/internal/chargers"
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/geo"
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/vehicles"
+)
+
+// Share of the energy delivered by a charger that ends up in the battery
+const charging_efficiency = 0.9
+
+// chargerCandidate is the best station reachable from a point on the route
+type chargerCandidate struct {
+	station  chargers.Station
+	detourKm float64
+}
+
+// PlanChargingStops walks the route with the EV energy model and, wherever
+// the battery would drop below the reserve, inserts a stop at the last
+// charging station near the route that can still be reached. Stops charge to
+// EV_CHARGE_TARGET_SOC.
+func PlanChargingStops(segments []EnergySegment, vehicle vehicles.Profile, condition string, weather *EnergyWeather, startSoC float64, reserveSoC float64) *models.ChargingPlan {
+	plan := &models.ChargingPlan{
+		Stops:     []models.ChargingStop{},
+		ViaPoints: [][2]float64{},
+	}
+
+	// State of charge used and distance driven on each segment
+	socUsed := make([]float64, len(segments))
+	distanceKm := make([]float64, len(segments))
+	var totalKWh, totalKm float64
+	for i, segment := range segments {
+		if segment.Time == 0 {
+			continue
+		}
+		energy := CalculateEVRouteEnergy([]EnergySegment{segment}, vehicle, condition, weather, 0, 0)
+		socUsed[i] = energy.ConsumedKWh / vehicle.BatteryCapacityKWh * 100
+		distanceKm[i] = segment.Distance / 1000
+		totalKWh += energy.ConsumedKWh
+		totalKm += distanceKm[i]
+	}
+
+	// Detours to a station are costed at the route's average consumption
+	var socPerKm float64
+	if totalKm > 0 {
+		socPerKm = math.Max(totalKWh, 0) / totalKm / vehicle.BatteryCapacityKWh * 100
+	}
+
+	candidates := findChargerCandidates(segments, chargers.Near, config.AppConfig.EVChargerMaxDetourKm)
+
+	soc := startSoC
+	socAt := make([]float64, len(segments)) // state of charge at the end of each segment
+	routeKm := make([]float64, len(segments))
+	var drivenKm float64
+	lastStop := -1
+
+	for i := 0; i < len(segments); i++ {
+		soc -= socUsed[i]
+		drivenKm += distanceKm[i]
+		socAt[i] = soc
+		routeKm[i] = drivenKm
+
+		if soc >= reserveSoC {
+			continue
+		}
+
+		if len(plan.Stops) >= config.AppConfig.EVMaxChargingStops {
+			plan.Reason = fmt.Sprintf("more than %d charging stops needed", config.AppConfig.EVMaxChargingStops)
+			plan.ArrivalSoC = projectArrivalSoC(soc, socUsed[i+1:])
+			return plan
+		}
+
+		// Stop at the furthest station along the route that the battery reaches
+		stopIndex := -1
+		for j := i - 1; j > lastStop; j-- {
+			if candidates[j] != nil && socAt[j]-candidates[j].detourKm*socPerKm >= 0 {
+				stopIndex = j
+				break
+			}
+		}
+		if stopIndex < 0 {
+			plan.Reason = "no charging station within range"
+			plan.ArrivalSoC = projectArrivalSoC(soc, socUsed[i+1:])
+			return plan
+		}
+
+		candidate := candidates[stopIndex]
+		detourSoC := candidate.detourKm * socPerKm
+		stop := newChargingStop(candidate, vehicle, socAt[stopIndex]-detourSoC)
+		stop.DistanceKm = routeKm[stopIndex]
+
+		plan.Stops = append(plan.Stops, stop)
+		plan.ViaPoints = append(plan.ViaPoints, candidate.station.Location)
+		plan.TotalChargeMinutes += stop.ChargeMinutes
+
+		// Resume after the stop, driving back to the route first
+		soc = stop.DepartureSoC - detourSoC
+		drivenKm = routeKm[stopIndex] + 2*candidate.detourKm
+		lastStop = stopIndex
+		i = stopIndex
+	}
+
+	plan.Feasible = true
+	plan.ArrivalSoC = soc
+	return plan
+}
+
+// findChargerCandidates returns, for the end of every segment, the fastest
+// station within maxDetourKm, or nil if there is none. near returns the
+// stations that may lie within a radius of a point, such as chargers.Near.
+func findChargerCandidates(segments []EnergySegment, near func(lon, lat, radiusKm float64) []chargers.Station, maxDetourKm float64) []*chargerCandidate {
+	candidates := make([]*chargerCandidate, len(segments))
+
+	for i, segment := range segments {
+		for _, station := range near(segment.End[0], segment.End[1], maxDetourKm) {
+			detourKm := geo.HaversineDistance(segment.End[1], segment.End[0], station.Location[1], station.Location[0]) / 1000
+			if detourKm > maxDetourKm {
+				continue
+			}
+
+			best := candidates[i]
+			power := station.FastestConnector().PowerKW
+			if best == nil || power > best.station.FastestConnector().PowerKW ||
+				(power == best.station.FastestConnector().PowerKW && detourKm < best.detourKm) {
+				candidates[i] = &chargerCandidate{station: station, detourKm: detourKm}
+			}
+		}
+	}
+	return candidates
+}
+
+// newChargingStop charges from arrivalSoC to the target state of charge at
+// the station's fastest connector, limited by the vehicle's charge power
+func newChargingStop(candidate *chargerCandidate, vehicle vehicles.Profile, arrivalSoC float64) models.ChargingStop {
+	connector := candidate.station.FastestConnector()
+	power := connector.PowerKW
+	if vehicle.MaxChargePowerKW > 0 {
+		power = math.Min(power, vehicle.MaxChargePowerKW)
+	}
+
+	departureSoC := math.Max(config.AppConfig.EVChargeTargetSoC, arrivalSoC)
+	energy := (departureSoC - arrivalSoC) / 100 * vehicle.BatteryCapacityKWh
+
+	return models.ChargingStop{
+		StationID:     candidate.station.ID,
+		Name:          candidate.station.Name,
+		Location:      candidate.station.Location,
+		ConnectorType: connector.Type,
+		PowerKW:       connector.PowerKW,
+		DetourKm:      candidate.detourKm,
+		ArrivalSoC:    arrivalSoC,
+		DepartureSoC:  departureSoC,
+		EnergyKWh:     energy,
+		ChargeMinutes: energy / (power * charging_efficiency) * 60,
+	}
+}
+
+// projectArrivalSoC returns the state of charge at the destination if the
+// rest of the route is driven without charging
+func projectArrivalSoC(soc float64, remaining []float64) float64 {
+	for _, used := range remaining {
+		soc -= used
+	}
+	return soc
+}
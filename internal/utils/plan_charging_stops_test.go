@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/clean-route/go-backend/internal/chargers"
+)
+
+func TestFindChargerCandidates(t *testing.T) {
+	index := chargers.NewIndex([]chargers.Station{
+		{ID: "slow-near", Location: [2]float64{77.001, 12.0}, Connectors: []chargers.Connector{{Type: "Type 2", PowerKW: 22}}},
+		{ID: "fast-near", Location: [2]float64{77.02, 12.0}, Connectors: []chargers.Connector{{Type: "CCS2", PowerKW: 60}}},
+		{ID: "fast-far", Location: [2]float64{77.5, 12.0}, Connectors: []chargers.Connector{{Type: "CCS2", PowerKW: 150}}},
+	})
+	segments := []EnergySegment{
+		{End: [2]float64{77.0, 12.0}},
+		{End: [2]float64{78.0, 13.0}},
+	}
+
+	candidates := findChargerCandidates(segments, index.Near, 5)
+	if candidates[0] == nil || candidates[0].station.ID != "fast-near" {
+		t.Errorf("first segment candidate %+v, want the fastest station within 5 km", candidates[0])
+	}
+	if candidates[1] != nil {
+		t.Errorf("second segment candidate %+v, want none", candidates[1])
+	}
+}
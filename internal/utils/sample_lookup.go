@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/clean-route/go-backend/internal/airquality"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/weather"
+	"github.com/clean-route/go-backend/internal/workers"
+)
+
+// sampleLookup memoizes the air-quality and weather lookups of one request,
+// so that sample points shared by overlapping candidate routes are fetched
+// once even while the routes are computed concurrently
+type sampleLookup struct {
+	flight workers.Group
+
+	mu        sync.Mutex
+	readings  map[[2]float64]readingResult
+	forecasts map[[2]float64]forecastResult
+}
+
+type readingResult struct {
+	reading airquality.Reading
+	failed  []string
+	err     error
+}
+
+type forecastResult struct {
+	forecast weather.Forecast
+	failed   []string
+	err      error
+}
+
+func newSampleLookup() *sampleLookup {
+	return &sampleLookup{
+		readings:  map[[2]float64]readingResult{},
+		forecasts: map[[2]float64]forecastResult{},
+	}
+}
+
+// nearest returns the PM2.5 reading nearest to a point ([lon, lat])
+func (l *sampleLookup) nearest(point [2]float64) readingResult {
+	l.mu.Lock()
+	result, ok := l.readings[point]
+	l.mu.Unlock()
+	if ok {
+		return result
+	}
+
+	value, err, _ := l.flight.Do(fmt.Sprintf("aq|%v", point), func() (interface{}, error) {
+		reading, failed, err := airquality.Nearest(point, airquality.PollutantPM25)
+		result := readingResult{reading: reading, failed: failed, err: err}
+		l.mu.Lock()
+		l.readings[point] = result
+		l.mu.Unlock()
+		return result, nil
+	})
+	if err != nil {
+		return readingResult{err: err}
+	}
+	return value.(readingResult)
+}
+
+// forecast returns the weather forecast at a point ([lon, lat])
+func (l *sampleLookup) forecast(point [2]float64) forecastResult {
+	l.mu.Lock()
+	result, ok := l.forecasts[point]
+	l.mu.Unlock()
+	if ok {
+		return result
+	}
+
+	value, err, _ := l.flight.Do(fmt.Sprintf("weather|%v", point), func() (interface{}, error) {
+		forecast, failed, err := weather.Fetch(point)
+		result := forecastResult{forecast: forecast, failed: failed, err: err}
+		l.mu.Lock()
+		l.forecasts[point] = result
+		l.mu.Unlock()
+		return result, nil
+	})
+	if err != nil {
+		return forecastResult{err: err}
+	}
+	return value.(forecastResult)
+}
+
+// calculateConcurrently runs fn for every route at once and reports which
+// routes succeeded. Failed routes are logged; the error of the first route is
+// returned only when every route failed.
+func calculateConcurrently(n int, fn func(i int) error) ([]bool, error) {
+	errs := make([]error, n)
+	workers.ForEach(n, n, func(i int) {
+		errs[i] = fn(i)
+	})
+
+	succeeded := make([]bool, n)
+	var failed int
+	for i, err := range errs {
+		if err == nil {
+			succeeded[i] = true
+			continue
+		}
+		failed++
+		logger.Warn("Dropping candidate route without exposure",
+			"route_index", i,
+			"error", err.Error(),
+		)
+	}
+	if n > 0 && failed == n {
+		return succeeded, errs[0]
+	}
+	return succeeded, nil
+}
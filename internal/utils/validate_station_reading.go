@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"math"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/airquality"
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/geo"
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+// ValidateStationReading checks a station reading against the configured
+// staleness and station-distance limits for the sampled point ([lon, lat]).
+// It returns the sample diagnostic and a weight in (0, 1]; a weight below 1
+// means the reading exceeded a limit by the inverse of that factor.
+func ValidateStationReading(reading airquality.Reading, point []float64, now time.Time) (models.SampleDiagnostic, float64) {
+	diag := models.SampleDiagnostic{
+		Location: point,
+		Station:  reading.StationName,
+		Source:   reading.Source,
+		PM25:     reading.Concentration,
+		Status:   models.SampleStatusOK,
+	}
+	weight := 1.0
+
+	if !reading.Timestamp.IsZero() {
+		age := now.Sub(reading.Timestamp)
+		if age < 0 {
+			age = 0
+		}
+		minutes := age.Minutes()
+		diag.StationAgeMinutes = &minutes
+
+		if limit := config.AppConfig.MaxStationAge; limit > 0 && age > limit {
+			diag.Status = models.SampleStatusStale
+			weight = math.Min(weight, limit.Minutes()/minutes)
+		}
+	}
+
+	if reading.HasLocation() && len(point) >= 2 {
+		km := geo.HaversineDistance(point[1], point[0], reading.Location[1], reading.Location[0]) / 1000
+		diag.StationDistanceKm = &km
+
+		if limit := config.AppConfig.MaxStationDistanceKm; limit > 0 && km > limit {
+			if diag.Status == models.SampleStatusOK {
+				diag.Status = models.SampleStatusTooFar
+			}
+			weight = math.Min(weight, limit/km)
+		}
+	}
+
+	diag.Weight = weight
+	return diag, weight
+}
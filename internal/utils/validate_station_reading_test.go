@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/airquality"
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/geo"
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+func TestValidateStationReading(t *testing.T) {
+	config.AppConfig = &config.Config{MaxStationAge: 3 * time.Hour, MaxStationDistanceKm: 25}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	point := []float64{77.59, 12.97}
+	near := [2]float64{77.59, 13.07}
+	far := [2]float64{77.59, 13.47}
+	farKm := geo.HaversineDistance(point[1], point[0], far[1], far[0]) / 1000
+
+	tests := []struct {
+		name       string
+		age        time.Duration
+		location   [2]float64
+		wantStatus string
+		wantWeight float64
+	}{
+		{"fresh and near", 30 * time.Minute, near, models.SampleStatusOK, 1},
+		{"at the age limit", 3 * time.Hour, near, models.SampleStatusOK, 1},
+		{"stale", 6 * time.Hour, near, models.SampleStatusStale, 0.5},
+		{"far", time.Minute, far, models.SampleStatusTooFar, 25 / farKm},
+		// The larger excess decides the weight; staleness is reported first
+		{"stale and far", 4 * time.Hour, far, models.SampleStatusStale, math.Min(0.75, 25/farKm)},
+		{"a day old", 24 * time.Hour, near, models.SampleStatusStale, 0.125},
+		{"timestamp in the future", -time.Hour, near, models.SampleStatusOK, 1},
+		{"no location", time.Minute, [2]float64{}, models.SampleStatusOK, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reading := airquality.Reading{Concentration: 40, Location: tt.location, Timestamp: now.Add(-tt.age)}
+			diag, weight := ValidateStationReading(reading, point, now)
+			if diag.Status != tt.wantStatus || math.Abs(weight-tt.wantWeight) > 1e-9 || diag.Weight != weight {
+				t.Errorf("status %s with weight %g, want %s with %g", diag.Status, weight, tt.wantStatus, tt.wantWeight)
+			}
+			if diag.StationAgeMinutes == nil || (tt.location != [2]float64{}) != (diag.StationDistanceKm != nil) {
+				t.Errorf("diagnostic %+v lacks the station age or distance", diag)
+			}
+		})
+	}
+}
+
+func TestStationLimitPolicy(t *testing.T) {
+	now := time.Now()
+	point := [2]float64{77.59, 12.97}
+	stale := airquality.Reading{Concentration: 90, Location: point, Timestamp: now.Add(-6 * time.Hour)}
+
+	tests := []struct {
+		policy       string
+		wantRejected int
+		wantStatus   string
+	}{
+		{"reject", 1, models.SampleStatusStale},
+		{"downweight", 0, models.SampleStatusDownweighted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			config.AppConfig = &config.Config{ExposureWorkers: 1, MaxStationAge: 3 * time.Hour, StationLimitPolicy: tt.policy}
+			lookup := newSampleLookup()
+			lookup.readings[point] = readingResult{reading: stale}
+
+			samples, quality := fetchExposureSamples(lookup, [][]float64{point[:]}, []float64{60})
+			if quality.SamplesRejected != tt.wantRejected || samples[0].ok != (tt.wantRejected == 0) {
+				t.Errorf("%d rejected (sample usable %v), want %d", quality.SamplesRejected, samples[0].ok, tt.wantRejected)
+			}
+			if samples[0].diag.Status != tt.wantStatus || math.Abs(samples[0].weight-0.5) > 1e-6 {
+				t.Errorf("status %s with weight %g, want %s with 0.5", samples[0].diag.Status, samples[0].weight, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package vehicles
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/errors"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+//go:embed profiles.json
+var builtinCatalog []byte
+
+// catalogFile is the on-disk format of the built-in and custom catalogs
+type catalogFile struct {
+	Profiles     []Profile         `json:"profiles" yaml:"profiles"`
+	ModeDefaults map[string]string `json:"mode_defaults" yaml:"mode_defaults"`
+}
+
+var (
+	mu           sync.RWMutex
+	profiles     = map[string]Profile{}
+	modeDefaults = map[string]string{}
+)
+
+// Init loads the built-in vehicle profiles and, if VEHICLE_PROFILES_FILE is
+// set, merges the profiles from that JSON or YAML file on top of them
+func Init() error {
+	var builtin catalogFile
+	if err := json.Unmarshal(builtinCatalog, &builtin); err != nil {
+		return fmt.Errorf("error parsing built-in vehicle profiles: %w", err)
+	}
+
+	catalogs := []catalogFile{builtin}
+	if path := config.AppConfig.VehicleProfilesFile; path != "" {
+		custom, err := loadCatalogFile(path)
+		if err != nil {
+			return err
+		}
+		catalogs = append(catalogs, custom)
+	}
+
+	loaded := map[string]Profile{}
+	defaults := map[string]string{}
+	for _, catalog := range catalogs {
+		for _, profile := range catalog.Profiles {
+			if err := validate(profile); err != nil {
+				return err
+			}
+			loaded[profile.ID] = profile
+		}
+		for mode, id := range catalog.ModeDefaults {
+			defaults[mode] = id
+		}
+	}
+
+	for mode, id := range defaults {
+		if _, ok := loaded[id]; !ok {
+			return fmt.Errorf("default vehicle profile %q for mode %q does not exist", id, mode)
+		}
+	}
+
+	mu.Lock()
+	profiles = loaded
+	modeDefaults = defaults
+	mu.Unlock()
+
+	logger.Info("Vehicle profiles loaded", "profiles", len(loaded))
+	return nil
+}
+
+func loadCatalogFile(path string) (catalogFile, error) {
+	var catalog catalogFile
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return catalog, fmt.Errorf("error reading vehicle profiles %s: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &catalog)
+	default:
+		err = json.Unmarshal(data, &catalog)
+	}
+	if err != nil {
+		return catalog, fmt.Errorf("error parsing vehicle profiles %s: %w", path, err)
+	}
+	return catalog, nil
+}
+
+func validate(p Profile) error {
+	if p.ID == "" {
+		return fmt.Errorf("vehicle profile is missing an id")
+	}
+	if p.MassKg <= 0 || p.DragCoefficient < 0 || p.FrontalAreaM2 < 0 || p.RollingResistance < 0 {
+		return fmt.Errorf("vehicle profile %q has invalid physical parameters", p.ID)
+	}
+	for i := 1; i < len(p.EfficiencyCurve); i++ {
+		if p.EfficiencyCurve[i].SpeedKmh <= p.EfficiencyCurve[i-1].SpeedKmh {
+			return fmt.Errorf("vehicle profile %q efficiency curve must have increasing speeds", p.ID)
+		}
+	}
+	if p.BatteryCapacityKWh < 0 || p.RegenEfficiency < 0 || p.RegenEfficiency > 1 || p.AuxiliaryPowerKW < 0 || p.HVACPowerPerDegree < 0 || p.HVACMaxPowerKW < 0 || p.MaxChargePowerKW < 0 {
+		return fmt.Errorf("vehicle profile %q has invalid battery parameters", p.ID)
+	}
+	if p.BodyMassKg < 0 || p.BodyMassKg > p.MassKg || p.AssistRatio < 0 || p.AssistRatio >= 1 {
+		return fmt.Errorf("vehicle profile %q has invalid rider parameters", p.ID)
+	}
+	if p.IsHumanPowered() && p.BodyMassKg == 0 {
+		return fmt.Errorf("vehicle profile %q is human-powered but has no body_mass_kg", p.ID)
+	}
+	for _, point := range p.EfficiencyCurve {
+		if point.Efficiency <= 0 || point.Efficiency > 1 {
+			return fmt.Errorf("vehicle profile %q efficiency curve values must be in (0, 1]", p.ID)
+		}
+	}
+	return nil
+}
+
+// List returns every loaded profile ordered by id
+func List() []Profile {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]Profile, 0, len(profiles))
+	for _, profile := range profiles {
+		list = append(list, profile)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Resolve returns the vehicle profile for a route request. The request's
+// vehicle_profile is used when given, otherwise the default for its mode.
+// The legacy vehicle_mass and engine_type fields override the profile, and
+// vehicle_overrides override both.
+func Resolve(req models.RouteRequest) (Profile, error) {
+	mu.RLock()
+	id := req.VehicleProfile
+	if id == "" {
+		id = modeDefaults[req.Mode]
+	}
+	profile, ok := profiles[id]
+	mu.RUnlock()
+
+	if !ok {
+		if req.VehicleProfile == "" {
+			return Profile{}, errors.NewValidationError(fmt.Sprintf("no default vehicle profile for mode: %s", req.Mode), nil)
+		}
+		return Profile{}, errors.NewValidationError(fmt.Sprintf("unknown vehicle profile: %s", req.VehicleProfile), nil)
+	}
+
+	if req.VehicleMass > 0 {
+		profile.MassKg = float64(req.VehicleMass)
+	}
+	if req.EngineType != "" {
+		profile.FuelType = req.EngineType
+	}
+	profile = profile.WithOverrides(req.VehicleOverrides)
+
+	if err := validate(profile); err != nil {
+		return Profile{}, errors.NewValidationError("invalid vehicle overrides", err)
+	}
+	for _, soc := range []*float64{req.StartSoC, req.ReserveSoC} {
+		if soc != nil && (*soc < 0 || *soc > 100) {
+			return Profile{}, errors.NewValidationError("start_soc and reserve_soc must be between 0 and 100", nil)
+		}
+	}
+	return profile, nil
+}
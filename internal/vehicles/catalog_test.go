@@ -0,0 +1,56 @@
+package vehicles
+
+import (
+	"testing"
+
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/errors"
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+func TestResolve(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	mass := 2100.0
+	negative := -5.0
+	tests := []struct {
+		name     string
+		req      models.RouteRequest
+		wantID   string
+		wantMass float64
+		wantErr  bool
+	}{
+		{"mode default", models.RouteRequest{Mode: "driving-traffic"}, "car", 0, false},
+		{"explicit profile", models.RouteRequest{Mode: "driving-traffic", VehicleProfile: "suv"}, "suv", 0, false},
+		{"legacy mass", models.RouteRequest{Mode: "driving-traffic", VehicleMass: 1800}, "car", 1800, false},
+		{"override wins over legacy mass", models.RouteRequest{Mode: "driving-traffic", VehicleMass: 1800, VehicleOverrides: &models.VehicleOverrides{MassKg: &mass}}, "car", 2100, false},
+		{"unknown profile", models.RouteRequest{Mode: "driving-traffic", VehicleProfile: "hovercraft"}, "", 0, true},
+		{"mode without default", models.RouteRequest{Mode: "teleport"}, "", 0, true},
+		{"invalid override", models.RouteRequest{Mode: "driving-traffic", VehicleOverrides: &models.VehicleOverrides{MassKg: &negative}}, "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := Resolve(tt.req)
+			if tt.wantErr {
+				appErr := errors.GetAppError(err)
+				if appErr == nil || appErr.Type != errors.ErrorTypeValidation {
+					t.Fatalf("error %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if profile.ID != tt.wantID {
+				t.Errorf("profile %q, want %q", profile.ID, tt.wantID)
+			}
+			if tt.wantMass > 0 && profile.MassKg != tt.wantMass {
+				t.Errorf("mass %g, want %g", profile.MassKg, tt.wantMass)
+			}
+		})
+	}
+}
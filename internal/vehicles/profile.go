@@ -0,0 +1,117 @@
+package vehicles
+
+import "github.com/clean-route/go-backend/internal/models"
+
+// EfficiencyPoint is a point on a drivetrain efficiency curve
+type EfficiencyPoint struct {
+	SpeedKmh   float64 `json:"speed_kmh" yaml:"speed_kmh"`
+	Efficiency float64 `json:"efficiency" yaml:"efficiency"`
+}
+
+// Profile describes the physical properties of a vehicle class
+type Profile struct {
+	ID                string            `json:"id" yaml:"id"`
+	Name              string            `json:"name" yaml:"name"`
+	Class             string            `json:"class" yaml:"class"`
+	MassKg            float64           `json:"mass_kg" yaml:"mass_kg"`
+	DragCoefficient   float64           `json:"drag_coefficient" yaml:"drag_coefficient"`
+	FrontalAreaM2     float64           `json:"frontal_area_m2" yaml:"frontal_area_m2"`
+	RollingResistance float64           `json:"rolling_resistance" yaml:"rolling_resistance"`
+	FuelType          string            `json:"fuel_type" yaml:"fuel_type"`
+	EfficiencyCurve   []EfficiencyPoint `json:"efficiency_curve,omitempty" yaml:"efficiency_curve,omitempty"`
+
+	// Electric vehicles only
+	BatteryCapacityKWh float64 `json:"battery_capacity_kwh,omitempty" yaml:"battery_capacity_kwh,omitempty"`
+	RegenEfficiency    float64 `json:"regen_efficiency,omitempty" yaml:"regen_efficiency,omitempty"`
+	AuxiliaryPowerKW   float64 `json:"auxiliary_power_kw,omitempty" yaml:"auxiliary_power_kw,omitempty"`
+	HVACPowerPerDegree float64 `json:"hvac_kw_per_degree,omitempty" yaml:"hvac_kw_per_degree,omitempty"`
+	HVACMaxPowerKW     float64 `json:"hvac_max_kw,omitempty" yaml:"hvac_max_kw,omitempty"`
+	MaxChargePowerKW   float64 `json:"max_charge_power_kw,omitempty" yaml:"max_charge_power_kw,omitempty"`
+
+	// Walking and cycling profiles only. BodyMassKg is the mass of the walker
+	// or rider, included in MassKg. AssistRatio is the share of the mechanical
+	// work a pedal-assist motor supplies.
+	BodyMassKg  float64 `json:"body_mass_kg,omitempty" yaml:"body_mass_kg,omitempty"`
+	AssistRatio float64 `json:"assist_ratio,omitempty" yaml:"assist_ratio,omitempty"`
+}
+
+// IsElectric reports whether the profile has a battery the EV energy model
+// can be applied to
+func (p Profile) IsElectric() bool {
+	return p.FuelType == "ev" && p.BatteryCapacityKWh > 0
+}
+
+// IsHumanPowered reports whether the profile is moved, at least partly, by
+// its walker or rider
+func (p Profile) IsHumanPowered() bool {
+	return p.FuelType == "human" || p.AssistRatio > 0
+}
+
+// MotorShare returns the share of the mechanical work drawn from the engine
+// or battery
+func (p Profile) MotorShare() float64 {
+	if p.AssistRatio > 0 {
+		return p.AssistRatio
+	}
+	return 1
+}
+
+// DrivetrainEfficiency returns the drivetrain efficiency at the given speed by
+// interpolating the profile's curve. ok is false when the profile has no curve.
+func (p Profile) DrivetrainEfficiency(speedKmh float64) (float64, bool) {
+	curve := p.EfficiencyCurve
+	if len(curve) == 0 {
+		return 0, false
+	}
+	if speedKmh <= curve[0].SpeedKmh {
+		return curve[0].Efficiency, true
+	}
+	for i := 1; i < len(curve); i++ {
+		if speedKmh <= curve[i].SpeedKmh {
+			span := curve[i].SpeedKmh - curve[i-1].SpeedKmh
+			weight := (speedKmh - curve[i-1].SpeedKmh) / span
+			return curve[i-1].Efficiency + weight*(curve[i].Efficiency-curve[i-1].Efficiency), true
+		}
+	}
+	return curve[len(curve)-1].Efficiency, true
+}
+
+// WithOverrides returns a copy of the profile with the non-nil overrides applied
+func (p Profile) WithOverrides(overrides *models.VehicleOverrides) Profile {
+	if overrides == nil {
+		return p
+	}
+	if overrides.MassKg != nil {
+		p.MassKg = *overrides.MassKg
+	}
+	if overrides.BodyMassKg != nil {
+		// The walker or rider is part of the total mass
+		p.MassKg += *overrides.BodyMassKg - p.BodyMassKg
+		p.BodyMassKg = *overrides.BodyMassKg
+	}
+	if overrides.DragCoefficient != nil {
+		p.DragCoefficient = *overrides.DragCoefficient
+	}
+	if overrides.FrontalAreaM2 != nil {
+		p.FrontalAreaM2 = *overrides.FrontalAreaM2
+	}
+	if overrides.RollingResistance != nil {
+		p.RollingResistance = *overrides.RollingResistance
+	}
+	if overrides.FuelType != nil {
+		p.FuelType = *overrides.FuelType
+	}
+	if overrides.BatteryKWh != nil {
+		p.BatteryCapacityKWh = *overrides.BatteryKWh
+	}
+	if overrides.RegenEfficiency != nil {
+		p.RegenEfficiency = *overrides.RegenEfficiency
+	}
+	if len(overrides.EfficiencyCurve) > 0 {
+		p.EfficiencyCurve = make([]EfficiencyPoint, len(overrides.EfficiencyCurve))
+		for i, point := range overrides.EfficiencyCurve {
+			p.EfficiencyCurve[i] = EfficiencyPoint{SpeedKmh: point.SpeedKmh, Efficiency: point.Efficiency}
+		}
+	}
+	return p
+}
@@ -0,0 +1,155 @@
+package weather
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/cache"
+	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/history"
+	"github.com/clean-route/go-backend/internal/logger"
+	"github.com/clean-route/go-backend/internal/models"
+	"github.com/clean-route/go-backend/internal/workers"
+)
+
+var (
+	mu    sync.RWMutex
+	chain []Provider
+	// lookups caches provider forecasts by geohash cell and time bucket
+	lookups *cache.Cache
+	// inflight coalesces identical provider lookups
+	inflight workers.Group
+)
+
+// Init builds the provider failover chain from WEATHER_PROVIDERS
+func Init() error {
+	available := map[string]Provider{
+		models.ProviderOpenWeather: NewOpenWeatherProvider(config.AppConfig.OpenWeatherAPIKey),
+		models.ProviderOpenMeteo:   NewOpenMeteoProvider(config.AppConfig.OpenMeteoURL),
+	}
+
+	var providers []Provider
+	for _, name := range config.AppConfig.WeatherProviders {
+		provider, ok := available[strings.TrimSpace(name)]
+		if !ok {
+			return fmt.Errorf("weather provider %q is unknown", name)
+		}
+		providers = append(providers, provider)
+	}
+	if len(providers) == 0 {
+		return fmt.Errorf("weather provider chain is empty")
+	}
+
+	mu.Lock()
+	chain = providers
+	lookups = cache.New("weather", config.AppConfig.WeatherCachePrecision, config.AppConfig.WeatherCacheTTL, config.AppConfig.CacheMaxEntries)
+	mu.Unlock()
+
+	logger.Info("Weather providers configured",
+		"chain", config.AppConfig.WeatherProviders,
+	)
+	return nil
+}
+
+// Fetch returns the forecast at the location ([longitude, latitude]) from the
+// first provider in the chain that answers; forecasts fetched from a provider
+// are recorded in the reading history. failed lists the providers that
+// returned an error; providers without an API key are skipped silently. When
+// every provider fails the error joins theirs, so errors.Is matches any of
+// the error kinds.
+func Fetch(location [2]float64) (Forecast, []string, error) {
+	mu.RLock()
+	providers := chain
+	c := lookups
+	mu.RUnlock()
+
+	var failed []string
+	var errs []error
+	for _, provider := range providers {
+		forecast, err := cachedForecast(c, provider, location)
+		if err == nil {
+			return forecast, failed, nil
+		}
+		errs = append(errs, err)
+		if errors.Is(err, ErrNotConfigured) {
+			continue
+		}
+
+		logger.Warn("Weather provider failed, trying next",
+			"provider", provider.Name(),
+			"error", err.Error(),
+			"location", location,
+		)
+		failed = append(failed, provider.Name())
+	}
+
+	if len(errs) == 0 {
+		return Forecast{}, failed, fmt.Errorf("%w: no weather providers configured", ErrNotConfigured)
+	}
+	return Forecast{}, failed, errors.Join(errs...)
+}
+
+// cachedForecast asks a provider for the forecast at a location, answering
+// from the lookup cache, or the reading history, when a point in the same
+// geohash cell was looked up recently. Cached hourly forecasts are re-trimmed
+// so that Hourly[0] stays the current hour. Identical lookups in flight share
+// one call, and calls to each provider are capped by its worker limit.
+func cachedForecast(c *cache.Cache, provider Provider, location [2]float64) (Forecast, error) {
+	now := time.Now()
+	key := c.Key(provider.Name(), location, now)
+
+	var forecast Forecast
+	if c.Get(key, &forecast) {
+		forecast.Hourly = trimHourly(forecast.Hourly, now)
+		return forecast, nil
+	}
+	cell := c.Cell(provider.Name(), location)
+	if record, ok := history.Recent(history.KindWeather, cell, c.TTL()); ok && json.Unmarshal(record.Payload, &forecast) == nil {
+		c.Set(key, forecast)
+		forecast.Hourly = trimHourly(forecast.Hourly, now)
+		return forecast, nil
+	}
+	if key == "" {
+		key = fmt.Sprintf("%s|%f,%f", provider.Name(), location[0], location[1])
+	}
+
+	value, err, _ := inflight.Do(key, func() (interface{}, error) {
+		var forecast Forecast
+		var err error
+		workers.Provider(provider.Name()).Do(func() {
+			forecast, err = provider.Forecast(location)
+		})
+		if err == nil {
+			c.Set(key, forecast)
+			recordForecast(forecast, cell)
+		}
+		return forecast, err
+	})
+	if err != nil {
+		return Forecast{}, err
+	}
+	return value.(Forecast), nil
+}
+
+// recordForecast stores the current conditions of a forecast fetched from a
+// provider in the reading history, with the forecast as the payload
+// answering repeats of the lookup in cell
+func recordForecast(forecast Forecast, cell string) {
+	payload, err := json.Marshal(forecast)
+	if err != nil {
+		return
+	}
+	history.Add(history.Record{
+		Kind:      history.KindWeather,
+		Source:    forecast.Source,
+		Location:  forecast.Location,
+		Timestamp: forecast.Current.Time,
+		Values:    forecast.Current.values(),
+		LookupKey: cell,
+		Payload:   payload,
+	})
+}
@@ -0,0 +1,60 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kinds of provider failure. Errors returned by providers match one of these
+// with errors.Is.
+var (
+	ErrNotConfigured  = errors.New("weather provider is not configured")
+	ErrUnauthorized   = errors.New("weather provider rejected the API key")
+	ErrRateLimited    = errors.New("weather provider rate limit exceeded")
+	ErrUnavailable    = errors.New("weather provider is unavailable")
+	ErrBadResponse    = errors.New("weather provider returned an invalid response")
+	ErrNoForecastHour = errors.New("hourly forecast does not reach the requested hour")
+)
+
+// ProviderError is a failed call to a weather provider
+type ProviderError struct {
+	Provider   string
+	Kind       error
+	StatusCode int // 0 when no response was received
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	message := fmt.Sprintf("%s: %s", e.Provider, e.Kind)
+	if e.StatusCode != 0 {
+		message += fmt.Sprintf(" (status code %d)", e.StatusCode)
+	}
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+// Is matches the error against its kind
+func (e *ProviderError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// statusError classifies an unexpected HTTP status
+func statusError(provider string, statusCode int) *ProviderError {
+	kind := ErrUnavailable
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		kind = ErrUnauthorized
+	case statusCode == http.StatusTooManyRequests:
+		kind = ErrRateLimited
+	case statusCode >= 400 && statusCode < 500:
+		kind = ErrBadResponse
+	}
+	return &ProviderError{Provider: provider, Kind: kind, StatusCode: statusCode}
+}
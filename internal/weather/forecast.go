@@ -0,0 +1,65 @@
+package weather
+
+import (
+	"fmt"
+	"time"
+)
+
+// Conditions is the weather at a location for one point in time
+type Conditions struct {
+	Time             time.Time `json:"time"`
+	TempC            float64   `json:"temp_c"`
+	RelativeHumidity float64   `json:"relative_humidity"` // %
+	DewPointC        float64   `json:"dew_point_c"`
+	PressureHPa      float64   `json:"pressure_hpa"`
+	WindSpeed        float64   `json:"wind_speed"`    // m/s
+	WindDeg          float64   `json:"wind_deg"`      // direction the wind blows from
+	Precipitation    float64   `json:"precipitation"` // mm/h
+	CloudCover       float64   `json:"cloud_cover"`   // %
+}
+
+// Forecast is the current weather and hourly forecast at a location
+// ([longitude, latitude]). Hourly[0] is the hour the current conditions fall
+// in, Hourly[1] the next hour and so on.
+type Forecast struct {
+	Location [2]float64   `json:"location"`
+	Source   string       `json:"source"`
+	Current  Conditions   `json:"current"`
+	Hourly   []Conditions `json:"hourly"`
+}
+
+// HoursAhead returns the forecast for the given number of hours after the
+// current hour
+func (f Forecast) HoursAhead(hours int) (Conditions, error) {
+	if hours < 0 || hours >= len(f.Hourly) {
+		return Conditions{}, fmt.Errorf("%w: %d hours ahead from %s", ErrNoForecastHour, hours, f.Source)
+	}
+	return f.Hourly[hours], nil
+}
+
+// trimHourly drops hourly entries before the hour the current conditions
+// fall in, so that Hourly[0] is always the current hour
+func trimHourly(hourly []Conditions, current time.Time) []Conditions {
+	start := current.Truncate(time.Hour)
+	for i, conditions := range hourly {
+		if !conditions.Time.Before(start) {
+			return hourly[i:]
+		}
+	}
+	return nil
+}
+
+// values returns the conditions keyed by their JSON names, as recorded in
+// the reading history
+func (c Conditions) values() map[string]float64 {
+	return map[string]float64{
+		"temp_c":            c.TempC,
+		"relative_humidity": c.RelativeHumidity,
+		"dew_point_c":       c.DewPointC,
+		"pressure_hpa":      c.PressureHPa,
+		"wind_speed":        c.WindSpeed,
+		"wind_deg":          c.WindDeg,
+		"precipitation":     c.Precipitation,
+		"cloud_cover":       c.CloudCover,
+	}
+}
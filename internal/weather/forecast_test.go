@@ -0,0 +1,60 @@
+package weather
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func hours(start time.Time, n int) []Conditions {
+	hourly := make([]Conditions, n)
+	for i := range hourly {
+		hourly[i] = Conditions{Time: start.Add(time.Duration(i) * time.Hour), TempC: float64(i)}
+	}
+	return hourly
+}
+
+func TestTrimHourly(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	hourly := hours(start, 4)
+
+	tests := []struct {
+		name    string
+		current time.Time
+		want    []float64 // TempC of the kept hours
+	}{
+		{"current hour first", start, []float64{0, 1, 2, 3}},
+		{"within the first hour", start.Add(40 * time.Minute), []float64{0, 1, 2, 3}},
+		{"past hours dropped", start.Add(2*time.Hour + 5*time.Minute), []float64{2, 3}},
+		{"before the forecast", start.Add(-3 * time.Hour), []float64{0, 1, 2, 3}},
+		{"after the forecast", start.Add(4 * time.Hour), nil},
+		{"other time zone", start.Add(time.Hour).In(time.FixedZone("IST", 5*3600+1800)), []float64{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trimHourly(hourly, tt.current)
+			if len(got) != len(tt.want) {
+				t.Fatalf("kept %d hours, want %d", len(got), len(tt.want))
+			}
+			for i, conditions := range got {
+				if conditions.TempC != tt.want[i] {
+					t.Errorf("hour %d is forecast hour %g, want %g", i, conditions.TempC, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHoursAhead(t *testing.T) {
+	forecast := Forecast{Source: "test", Hourly: hours(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), 3)}
+
+	if conditions, err := forecast.HoursAhead(2); err != nil || conditions.TempC != 2 {
+		t.Errorf("HoursAhead(2) = %g, %v, want hour 2", conditions.TempC, err)
+	}
+	for _, ahead := range []int{-1, 3} {
+		if _, err := forecast.HoursAhead(ahead); !errors.Is(err, ErrNoForecastHour) {
+			t.Errorf("HoursAhead(%d) error %v, want ErrNoForecastHour", ahead, err)
+		}
+	}
+}
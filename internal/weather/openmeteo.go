@@ -0,0 +1,128 @@
+package weather
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/models"
+)
+
+// openMeteoVariables are requested for both the current and hourly blocks
+const openMeteoVariables = "temperature_2m,relative_humidity_2m,dew_point_2m,surface_pressure,wind_speed_10m,wind_direction_10m,precipitation,cloud_cover"
+
+// OpenMeteoProvider reads the Open-Meteo forecast API, which needs no API key
+type OpenMeteoProvider struct {
+	baseURL string
+}
+
+// NewOpenMeteoProvider creates a provider for the Open-Meteo API at baseURL,
+// or the public API when it is empty
+func NewOpenMeteoProvider(baseURL string) *OpenMeteoProvider {
+	if baseURL == "" {
+		baseURL = "https://api.open-meteo.com"
+	}
+	return &OpenMeteoProvider{baseURL: baseURL}
+}
+
+// Name returns the provider name
+func (p *OpenMeteoProvider) Name() string {
+	return models.ProviderOpenMeteo
+}
+
+type openMeteoCurrent struct {
+	Time             int64   `json:"time"`
+	Temperature      float64 `json:"temperature_2m"`
+	RelativeHumidity float64 `json:"relative_humidity_2m"`
+	DewPoint         float64 `json:"dew_point_2m"`
+	Pressure         float64 `json:"surface_pressure"`
+	WindSpeed        float64 `json:"wind_speed_10m"`
+	WindDirection    float64 `json:"wind_direction_10m"`
+	Precipitation    float64 `json:"precipitation"`
+	CloudCover       float64 `json:"cloud_cover"`
+}
+
+type openMeteoHourly struct {
+	Time             []int64   `json:"time"`
+	Temperature      []float64 `json:"temperature_2m"`
+	RelativeHumidity []float64 `json:"relative_humidity_2m"`
+	DewPoint         []float64 `json:"dew_point_2m"`
+	Pressure         []float64 `json:"surface_pressure"`
+	WindSpeed        []float64 `json:"wind_speed_10m"`
+	WindDirection    []float64 `json:"wind_direction_10m"`
+	Precipitation    []float64 `json:"precipitation"`
+	CloudCover       []float64 `json:"cloud_cover"`
+}
+
+type openMeteoResponse struct {
+	Current openMeteoCurrent `json:"current"`
+	Hourly  openMeteoHourly  `json:"hourly"`
+}
+
+// Forecast returns the current weather and 48-hour forecast
+func (p *OpenMeteoProvider) Forecast(location [2]float64) (Forecast, error) {
+	params := url.Values{}
+	params.Add("latitude", fmt.Sprintf("%f", location[1]))
+	params.Add("longitude", fmt.Sprintf("%f", location[0]))
+	params.Add("current", openMeteoVariables)
+	params.Add("hourly", openMeteoVariables)
+	params.Add("wind_speed_unit", "ms")
+	params.Add("timeformat", "unixtime")
+	params.Add("forecast_days", "3")
+
+	req, err := http.NewRequest(http.MethodGet, p.baseURL+"/v1/forecast?"+params.Encode(), nil)
+	if err != nil {
+		return Forecast{}, &ProviderError{Provider: p.Name(), Kind: ErrUnavailable, Err: err}
+	}
+
+	var data openMeteoResponse
+	if err := getJSON(p.Name(), req, &data); err != nil {
+		return Forecast{}, err
+	}
+	if data.Current.Time == 0 {
+		return Forecast{}, &ProviderError{Provider: p.Name(), Kind: ErrBadResponse, Err: fmt.Errorf("response has no current conditions")}
+	}
+
+	current := Conditions{
+		Time:             time.Unix(data.Current.Time, 0).UTC(),
+		TempC:            data.Current.Temperature,
+		RelativeHumidity: data.Current.RelativeHumidity,
+		DewPointC:        data.Current.DewPoint,
+		PressureHPa:      data.Current.Pressure,
+		WindSpeed:        data.Current.WindSpeed,
+		WindDeg:          data.Current.WindDirection,
+		// Current precipitation is the sum over the preceding 15 minutes
+		Precipitation: data.Current.Precipitation * 4,
+		CloudCover:    data.Current.CloudCover,
+	}
+
+	series := data.Hourly
+	for _, values := range [][]float64{series.Temperature, series.RelativeHumidity, series.DewPoint, series.Pressure, series.WindSpeed, series.WindDirection, series.Precipitation, series.CloudCover} {
+		if len(values) != len(series.Time) {
+			return Forecast{}, &ProviderError{Provider: p.Name(), Kind: ErrBadResponse, Err: fmt.Errorf("hourly series have different lengths")}
+		}
+	}
+
+	hourly := make([]Conditions, len(series.Time))
+	for i, at := range series.Time {
+		hourly[i] = Conditions{
+			Time:             time.Unix(at, 0).UTC(),
+			TempC:            series.Temperature[i],
+			RelativeHumidity: series.RelativeHumidity[i],
+			DewPointC:        series.DewPoint[i],
+			PressureHPa:      series.Pressure[i],
+			WindSpeed:        series.WindSpeed[i],
+			WindDeg:          series.WindDirection[i],
+			Precipitation:    series.Precipitation[i],
+			CloudCover:       series.CloudCover[i],
+		}
+	}
+
+	return Forecast{
+		Location: location,
+		Source:   p.Name(),
+		Current:  current,
+		Hourly:   trimHourly(hourly, current.Time),
+	}, nil
+}
@@ -0,0 +1,96 @@
+package weather
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/models"
+	openweather "github.com/clean-route/go-backend/internal/models/openweather"
+)
+
+// OpenWeatherProvider reads the OpenWeather One Call 3.0 API, which returns
+// the current, minutely and hourly blocks in one response
+type OpenWeatherProvider struct {
+	apiKey string
+}
+
+// NewOpenWeatherProvider creates a provider using the OpenWeather API key
+func NewOpenWeatherProvider(apiKey string) *OpenWeatherProvider {
+	return &OpenWeatherProvider{apiKey: apiKey}
+}
+
+// Name returns the provider name
+func (p *OpenWeatherProvider) Name() string {
+	return models.ProviderOpenWeather
+}
+
+// Forecast returns the current weather and 48-hour forecast
+func (p *OpenWeatherProvider) Forecast(location [2]float64) (Forecast, error) {
+	if p.apiKey == "" {
+		return Forecast{}, &ProviderError{Provider: p.Name(), Kind: ErrNotConfigured}
+	}
+
+	params := url.Values{}
+	params.Add("lat", fmt.Sprintf("%f", location[1]))
+	params.Add("lon", fmt.Sprintf("%f", location[0]))
+	params.Add("exclude", "alerts,daily")
+	params.Add("units", "metric")
+	params.Add("appid", p.apiKey)
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.openweathermap.org/data/3.0/onecall?"+params.Encode(), nil)
+	if err != nil {
+		return Forecast{}, &ProviderError{Provider: p.Name(), Kind: ErrUnavailable, Err: err}
+	}
+
+	var data openweather.WeatherData
+	if err := getJSON(p.Name(), req, &data); err != nil {
+		return Forecast{}, err
+	}
+	if data.Current.Dt == 0 {
+		return Forecast{}, &ProviderError{Provider: p.Name(), Kind: ErrBadResponse, Err: fmt.Errorf("response has no current conditions")}
+	}
+
+	current := Conditions{
+		Time:             time.Unix(data.Current.Dt, 0).UTC(),
+		TempC:            data.Current.Temp,
+		RelativeHumidity: data.Current.Humidity,
+		DewPointC:        data.Current.DewPoint,
+		PressureHPa:      data.Current.Pressure,
+		WindSpeed:        data.Current.WindSpeed,
+		WindDeg:          data.Current.WindDeg,
+		CloudCover:       data.Current.Clouds,
+	}
+	// The minutely block is the nowcast; rain.1h covers the past hour
+	if len(data.Minutely) > 0 {
+		current.Precipitation = data.Minutely[0].Precipitation
+	} else if data.Current.Rain != nil {
+		current.Precipitation = data.Current.Rain.OneHour
+	}
+
+	hourly := make([]Conditions, 0, len(data.Hourly))
+	for _, hour := range data.Hourly {
+		conditions := Conditions{
+			Time:             time.Unix(int64(hour.Dt), 0).UTC(),
+			TempC:            hour.Temp,
+			RelativeHumidity: hour.Humidity,
+			DewPointC:        hour.DewPoint,
+			PressureHPa:      hour.Pressure,
+			WindSpeed:        hour.WindSpeed,
+			WindDeg:          hour.WindDeg,
+			CloudCover:       hour.Clouds,
+		}
+		if hour.Rain != nil {
+			conditions.Precipitation = hour.Rain.OneHour
+		}
+		hourly = append(hourly, conditions)
+	}
+
+	return Forecast{
+		Location: location,
+		Source:   p.Name(),
+		Current:  current,
+		Hourly:   trimHourly(hourly, current.Time),
+	}, nil
+}
@@ -0,0 +1,45 @@
+package weather
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/clean-route/go-backend/internal/outbound"
+)
+
+// Provider is a source of current weather and hourly forecasts
+type Provider interface {
+	// Name identifies the provider in configuration and data quality reports
+	Name() string
+	// Forecast returns the current weather and hourly forecast at the
+	// location ([longitude, latitude]). Errors are *ProviderError.
+	Forecast(location [2]float64) (Forecast, error)
+}
+
+// getJSON fetches a URL through the provider's outbound client and decodes
+// its JSON body into target
+func getJSON(provider string, req *http.Request, target interface{}) error {
+	resp, err := outbound.For(provider).Do(req)
+	if errors.Is(err, outbound.ErrRateLimited) || errors.Is(err, outbound.ErrQuotaExceeded) || errors.Is(err, outbound.ErrQuotaSoftLimit) {
+		return &ProviderError{Provider: provider, Kind: ErrRateLimited, Err: err}
+	}
+	if err != nil {
+		return &ProviderError{Provider: provider, Kind: ErrUnavailable, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(provider, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &ProviderError{Provider: provider, Kind: ErrUnavailable, Err: err}
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return &ProviderError{Provider: provider, Kind: ErrBadResponse, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package workers
+
+import (
+	"sync"
+
+	"github.com/clean-route/go-backend/internal/config"
+)
+
+// Limiter caps the number of concurrent calls
+type Limiter struct {
+	slots chan struct{}
+}
+
+// NewLimiter returns a limiter allowing n concurrent calls (at least one)
+func NewLimiter(n int) *Limiter {
+	if n < 1 {
+		n = 1
+	}
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+// Do runs fn once a slot is free
+func (l *Limiter) Do(fn func()) {
+	l.slots <- struct{}{}
+	defer func() { <-l.slots }()
+	fn()
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*Limiter{}
+)
+
+// Provider returns the limiter of an upstream provider, sized by its
+// PROVIDER_WORKERS entry or PROVIDER_WORKERS_DEFAULT
+func Provider(name string) *Limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	if l, ok := limiters[name]; ok {
+		return l
+	}
+	n, ok := config.AppConfig.ProviderWorkers[name]
+	if !ok {
+		n = config.AppConfig.ProviderWorkersDefault
+	}
+	l := NewLimiter(n)
+	limiters[name] = l
+	return l
+}
+
+// ForEach calls fn for every index below n on at most workers goroutines
+// and returns when all calls have finished
+func ForEach(n, workers int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,68 @@
+// Package workers bounds and coalesces concurrent upstream work: a
+// singleflight Group shares one call among identical in-flight requests,
+// per-provider limiters cap concurrent calls to each upstream API, and
+// ForEach runs a loop on a bounded pool of goroutines.
+package workers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPanicked is returned to the waiters of a call whose fn panicked
+var ErrPanicked = errors.New("coalesced call panicked")
+
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+	dups  int
+}
+
+// Group coalesces calls with the same key: while one is in flight, later
+// callers wait for and share its result instead of starting their own. The
+// zero Group is ready to use.
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// Do runs fn once for all concurrent callers with the same key. shared
+// reports whether the result was given to more than one caller. If fn
+// panics, the panic is raised again in the caller that ran it and the
+// waiters get an error wrapping ErrPanicked.
+func (g *Group) Do(key string, fn func() (interface{}, error)) (value interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*call{}
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		<-c.done
+		return c.value, c.err, true
+	}
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// A panicking fn must not leave waiters blocked forever
+	defer func() {
+		if r := recover(); r != nil {
+			c.value, c.err = nil, fmt.Errorf("%w: %v", ErrPanicked, r)
+			defer panic(r)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.value, c.err = fn()
+
+	g.mu.Lock()
+	shared = c.dups > 0
+	g.mu.Unlock()
+	return c.value, c.err, shared
+}
@@ -0,0 +1,116 @@
+package workers
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startWaiters calls g.Do with key n times once fn is in flight, returning
+// their results once all have returned
+func startWaiters(g *Group, key string, n int, started <-chan struct{}) func() []error {
+	<-started
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	values := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], errs[i], _ = g.Do(key, func() (interface{}, error) { return "second call", nil })
+		}(i)
+	}
+	return func() []error {
+		wg.Wait()
+		for i, value := range values {
+			if value == "second call" {
+				errs[i] = errors.New("waiter ran its own call")
+			}
+		}
+		return errs
+	}
+}
+
+// waitForDups blocks until n callers are waiting on key
+func waitForDups(g *Group, key string, n int) {
+	for {
+		g.mu.Lock()
+		c := g.calls[key]
+		dups := c != nil && c.dups >= n
+		g.mu.Unlock()
+		if dups {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDoSharesOneCall(t *testing.T) {
+	var g Group
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+
+	go func() {
+		value, err, shared := g.Do("key", func() (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			close(started)
+			<-release
+			return 42, nil
+		})
+		if value != 42 || err != nil || !shared {
+			t.Errorf("caller got (%v, %v, %v), want (42, nil, true)", value, err, shared)
+		}
+	}()
+	wait := startWaiters(&g, "key", 3, started)
+	waitForDups(&g, "key", 3)
+	close(release)
+
+	for _, err := range wait() {
+		if err != nil {
+			t.Errorf("waiter: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fn ran %d times, want once", calls)
+	}
+
+	// Finished calls are not reused
+	value, _, shared := g.Do("key", func() (interface{}, error) { return 43, nil })
+	if value != 43 || shared {
+		t.Errorf("later call got (%v, shared %v), want its own result", value, shared)
+	}
+}
+
+func TestDoPanicFailsWaiters(t *testing.T) {
+	var g Group
+	started, release := make(chan struct{}), make(chan struct{})
+
+	recovered := make(chan interface{})
+	go func() {
+		defer func() { recovered <- recover() }()
+		g.Do("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			panic("provider bug")
+		})
+	}()
+	wait := startWaiters(&g, "key", 2, started)
+	waitForDups(&g, "key", 2)
+	close(release)
+
+	if r := <-recovered; r != "provider bug" {
+		t.Errorf("caller recovered %v, want the panic of fn", r)
+	}
+	for _, err := range wait() {
+		if !errors.Is(err, ErrPanicked) {
+			t.Errorf("waiter error %v, want ErrPanicked", err)
+		}
+	}
+
+	// The key is usable again after the panic
+	if value, err, _ := g.Do("key", func() (interface{}, error) { return 1, nil }); value != 1 || err != nil {
+		t.Errorf("call after the panic got (%v, %v)", value, err)
+	}
+}
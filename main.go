@@ -3,12 +3,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
-
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/clean-route/go-backend/internal/airquality"
+	"github.com/clean-route/go-backend/internal/background"
+	"github.com/clean-route/go-backend/internal/cache"
+	"github.com/clean-route/go-backend/internal/chargers"
 	"github.com/clean-route/go-backend/internal/config"
+	"github.com/clean-route/go-backend/internal/elevation"
 	"github.com/clean-route/go-backend/internal/handlers"
+	"github.com/clean-route/go-backend/internal/history"
 	"github.com/clean-route/go-backend/internal/logger"
 	"github.com/clean-route/go-backend/internal/middleware"
+	"github.com/clean-route/go-backend/internal/observations"
+	"github.com/clean-route/go-backend/internal/outbound"
+	"github.com/clean-route/go-backend/internal/predictor"
+	"github.com/clean-route/go-backend/internal/pricing"
+	"github.com/clean-route/go-backend/internal/vehicles"
+	"github.com/clean-route/go-backend/internal/weather"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -17,6 +34,9 @@ import (
 const (
 	port        = "9000"
 	serviceName = "clean-route-service"
+	// shutdownTimeout bounds how long in-flight requests may finish after
+	// SIGINT or SIGTERM
+	shutdownTimeout = 15 * time.Second
 )
 
 func main() {
@@ -36,6 +56,69 @@ func main() {
 		logger.Fatal("Failed to initialize configuration", "error", err.Error())
 	}
 
+	// Initialize provider rate limits and quota accounting
+	if err := outbound.Init(); err != nil {
+		logger.Fatal("Failed to initialize provider quotas", "error", err.Error())
+	}
+
+	// Initialize PM2.5 model registry
+	if err := predictor.Init(); err != nil {
+		logger.Fatal("Failed to initialize model registry", "error", err.Error())
+	}
+
+	// Initialize vehicle profile catalog
+	if err := vehicles.Init(); err != nil {
+		logger.Fatal("Failed to initialize vehicle profiles", "error", err.Error())
+	}
+
+	// Initialize charging station dataset
+	if err := chargers.Init(); err != nil {
+		logger.Fatal("Failed to initialize charging stations", "error", err.Error())
+	}
+
+	// Initialize elevation model
+	if err := elevation.Init(); err != nil {
+		logger.Fatal("Failed to initialize elevation model", "error", err.Error())
+	}
+
+	// Initialize regional prices and toll zones
+	if err := pricing.Init(); err != nil {
+		logger.Fatal("Failed to initialize prices", "error", err.Error())
+	}
+
+	// Initialize the reading history store, which also persists observations
+	if err := history.Init(); err != nil {
+		logger.Fatal("Failed to initialize reading history", "error", err.Error())
+	}
+
+	// Initialize the community sensor observation store
+	if err := observations.Init(); err != nil {
+		logger.Fatal("Failed to initialize observation store", "error", err.Error())
+	}
+	if err := observations.StartSubscriber(); err != nil {
+		logger.Fatal("Failed to start MQTT subscriber", "error", err.Error())
+	}
+
+	// Initialize air-quality providers
+	if err := airquality.Init(); err != nil {
+		logger.Fatal("Failed to initialize air-quality providers", "error", err.Error())
+	}
+
+	// Initialize weather providers
+	if err := weather.Init(); err != nil {
+		logger.Fatal("Failed to initialize weather providers", "error", err.Error())
+	}
+
+	// Restore the provider lookup caches created above
+	if err := cache.Init(); err != nil {
+		logger.Fatal("Failed to initialize lookup caches", "error", err.Error())
+	}
+
+	// Initialize gridded background concentrations
+	if err := background.Init(); err != nil {
+		logger.Fatal("Failed to initialize background grids", "error", err.Error())
+	}
+
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
 
@@ -50,11 +133,17 @@ func main() {
 	router.Use(cors.Default())
 	router.Use(middleware.SetReferrerPolicy())
 
-	// Health check endpoint
+	// Health check endpoint; the service is degraded while an upstream
+	// provider's circuit breaker is open or its quota is exhausted
 	router.GET("/health", func(c *gin.Context) {
+		status := "healthy"
+		if !outbound.Healthy() {
+			status = "degraded"
+		}
 		c.JSON(http.StatusOK, gin.H{
-			"status":  "healthy",
-			"service": serviceName,
+			"status":    status,
+			"service":   serviceName,
+			"upstreams": outbound.Statuses(),
 		})
 	})
 
@@ -68,16 +157,75 @@ func main() {
 		// Route planning endpoints
 		api.POST("/route", handlers.FindRoute)
 		api.POST("/routes", handlers.FindAllRoutes)
+		api.POST("/compare-modes", handlers.CompareModes)
 
 		// Weather and air quality endpoints
 		api.GET("/weather", handlers.GetWeatherData)
 		api.GET("/aqi", handlers.GetAQIData)
 		api.POST("/predict/pm25", handlers.GetPredictedPM25)
+		api.GET("/history", handlers.GetHistory)
+		api.GET("/cache/stats", handlers.GetCacheStats)
+		api.GET("/upstreams", handlers.GetUpstreams)
+
+		// Model registry endpoints
+		api.GET("/models", handlers.GetModelMetrics)
+
+		// Vehicle profile endpoints
+		api.GET("/vehicles", handlers.GetVehicleProfiles)
+
+		// Trip cost endpoints
+		api.GET("/prices", handlers.GetPrices)
+
+		// Community sensor endpoints. Observations are authenticated by the
+		// API key registered with their sensor, the sensor list by
+		// OBSERVATION_API_KEYS.
+		api.POST("/observations", handlers.IngestObservations)
+		sensors := api.Group("", middleware.APIKeyAuth(config.AppConfig.ObservationAPIKeys))
+		sensors.GET("/sensors", handlers.GetSensors)
+
+		// Administrative endpoints, authenticated by ADMIN_API_KEYS
+		admin := api.Group("", middleware.APIKeyAuth(config.AppConfig.AdminAPIKeys))
+		admin.POST("/models/reload", handlers.ReloadModels)
+		admin.PUT("/prices", handlers.UpdatePrices)
+		admin.POST("/sensors", handlers.RegisterSensor)
 	}
 
 	// Start server
-	logger.Info("Server starting", "port", port)
-	if err := router.Run(":" + port); err != nil {
-		logger.Fatal("Failed to start server", "error", err.Error())
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	go func() {
+		logger.Info("Server starting", "port", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Failed to start server", "error", err.Error())
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	logger.Info("Shutting down server", "timeout_seconds", shutdownTimeout.Seconds())
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Failed to finish in-flight requests", "error", err.Error())
+	}
+	shutdown()
+	logger.Info("Server stopped")
+}
+
+// shutdown stops the background workers and saves the state that is
+// otherwise only persisted periodically
+func shutdown() {
+	observations.StopSubscriber()
+	if err := outbound.Flush(); err != nil {
+		logger.Error("Failed to persist quota usage", "error", err.Error())
+	}
+	if err := cache.Flush(); err != nil {
+		logger.Error("Failed to persist lookup caches", "error", err.Error())
+	}
+	if err := history.Close(); err != nil {
+		logger.Error("Failed to close reading history", "error", err.Error())
 	}
 }